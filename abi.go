@@ -0,0 +1,558 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// ABIType is the type of a contract function parameter or return value
+type ABIType string
+
+const (
+	ABITypeAddress     ABIType = "address"   // Account or contract address
+	ABITypeString      ABIType = "string"    // Arbitrary text
+	ABITypeAmount      ABIType = "amount"    // Non-negative decimal coin or token amount
+	ABITypeUint        ABIType = "uint64"    // Non-negative integer
+	ABITypeBool        ABIType = "bool"      // Boolean
+	ABITypeUintList    ABIType = "uint64[]"  // List of non-negative integers (results only)
+	ABITypeAddressList ABIType = "address[]" // List of addresses (as a final input, collects the remaining arguments)
+	ABITypeMap         ABIType = "map"       // JSON object (results only)
+	ABITypeAny         ABIType = "any"       // Untyped value (results only)
+)
+
+// ABIParam describes a named, typed function parameter or return value
+type ABIParam struct {
+	Name string  `json:"name"`
+	Type ABIType `json:"type"`
+}
+
+// ABIFunction describes a callable contract function
+type ABIFunction struct {
+	Name    string     `json:"name"`
+	Inputs  []ABIParam `json:"inputs"`
+	Outputs []ABIParam `json:"outputs"`
+	Payable bool       `json:"payable"` // Accepts native value with the call
+	View    bool       `json:"view"`    // Does not modify contract state
+}
+
+// ContractABI describes the functions and events of a contract
+type ContractABI struct {
+	Type      ContractType  `json:"type"`
+	Functions []ABIFunction `json:"functions"`
+	Events    []string      `json:"events"` // Event signatures emitted by the contract
+}
+
+// EncodedCall is the JSON encoding of contract call data: {"function": "transfer", "args": ["0xabc...", 20]}
+type EncodedCall struct {
+	Function string        `json:"function"`
+	Args     []interface{} `json:"args"`
+}
+
+// builtinABIs are the ABIs of the built-in contract types
+var builtinABIs = map[ContractType]*ContractABI{
+	ContractTypeSimple: {
+		Type: ContractTypeSimple,
+		Functions: []ABIFunction{
+			{Name: "set", Inputs: []ABIParam{{"key", ABITypeString}, {"value", ABITypeString}}, Outputs: []ABIParam{{"message", ABITypeString}}},
+			{Name: "get", Inputs: []ABIParam{{"key", ABITypeString}}, Outputs: []ABIParam{{"value", ABITypeAny}}, View: true},
+			{Name: "delete", Inputs: []ABIParam{{"key", ABITypeString}}, Outputs: []ABIParam{{"message", ABITypeString}}},
+			{Name: "exists", Inputs: []ABIParam{{"key", ABITypeString}}, Outputs: []ABIParam{{"exists", ABITypeBool}}, View: true},
+		},
+	},
+	ContractTypeToken: {
+		Type: ContractTypeToken,
+		Functions: []ABIFunction{
+			{Name: "transfer", Inputs: []ABIParam{{"to", ABITypeAddress}, {"amount", ABITypeAmount}}, Outputs: []ABIParam{{"message", ABITypeString}}},
+			{Name: "balanceOf", Inputs: []ABIParam{{"owner", ABITypeAddress}}, Outputs: []ABIParam{{"balance", ABITypeAmount}}, View: true},
+			{Name: "totalSupply", Outputs: []ABIParam{{"supply", ABITypeAmount}}, View: true},
+			{Name: "mint", Inputs: []ABIParam{{"to", ABITypeAddress}, {"amount", ABITypeAmount}}, Outputs: []ABIParam{{"message", ABITypeString}}},
+			{Name: "burn", Inputs: []ABIParam{{"amount", ABITypeAmount}}, Outputs: []ABIParam{{"message", ABITypeString}}},
+			{Name: "approve", Inputs: []ABIParam{{"spender", ABITypeAddress}, {"amount", ABITypeAmount}}, Outputs: []ABIParam{{"message", ABITypeString}}},
+			{Name: "allowance", Inputs: []ABIParam{{"owner", ABITypeAddress}, {"spender", ABITypeAddress}}, Outputs: []ABIParam{{"remaining", ABITypeAmount}}, View: true},
+			{Name: "transferFrom", Inputs: []ABIParam{{"from", ABITypeAddress}, {"to", ABITypeAddress}, {"amount", ABITypeAmount}}, Outputs: []ABIParam{{"message", ABITypeString}}},
+		},
+		Events: []string{EventTransfer, EventApproval},
+	},
+	ContractTypeEscrow: {
+		Type: ContractTypeEscrow,
+		Functions: []ABIFunction{
+			{Name: "deposit", Outputs: []ABIParam{{"message", ABITypeString}}, Payable: true},
+			{Name: "release", Outputs: []ABIParam{{"message", ABITypeString}}},
+			{Name: "refund", Outputs: []ABIParam{{"message", ABITypeString}}},
+			{Name: "getBalance", Outputs: []ABIParam{{"deposited", ABITypeAmount}}, View: true},
+			{Name: "getStatus", Outputs: []ABIParam{{"status", ABITypeMap}}, View: true},
+		},
+		Events: []string{EventDeposit, EventRelease, EventRefund},
+	},
+	ContractTypeVoting: {
+		Type: ContractTypeVoting,
+		Functions: []ABIFunction{
+			{Name: "propose", Inputs: []ABIParam{{"proposal", ABITypeString}}, Outputs: []ABIParam{{"message", ABITypeString}}},
+			{Name: "vote", Inputs: []ABIParam{{"proposal", ABITypeString}}, Outputs: []ABIParam{{"message", ABITypeString}}},
+			{Name: "getResults", Outputs: []ABIParam{{"results", ABITypeMap}}, View: true},
+			{Name: "getWinner", Outputs: []ABIParam{{"winner", ABITypeMap}}, View: true},
+			{Name: "endVoting", Outputs: []ABIParam{{"message", ABITypeString}}},
+		},
+		Events: []string{EventProposal, EventVote, EventVotingEnded},
+	},
+	ContractTypeNFT: {
+		Type: ContractTypeNFT,
+		Functions: []ABIFunction{
+			{Name: "mint", Inputs: []ABIParam{{"to", ABITypeAddress}, {"tokenURI", ABITypeString}}, Outputs: []ABIParam{{"tokenId", ABITypeUint}}},
+			{Name: "transfer", Inputs: []ABIParam{{"to", ABITypeAddress}, {"tokenId", ABITypeUint}}, Outputs: []ABIParam{{"message", ABITypeString}}},
+			{Name: "approve", Inputs: []ABIParam{{"spender", ABITypeAddress}, {"tokenId", ABITypeUint}}, Outputs: []ABIParam{{"message", ABITypeString}}},
+			{Name: "getApproved", Inputs: []ABIParam{{"tokenId", ABITypeUint}}, Outputs: []ABIParam{{"approved", ABITypeString}}, View: true},
+			{Name: "ownerOf", Inputs: []ABIParam{{"tokenId", ABITypeUint}}, Outputs: []ABIParam{{"owner", ABITypeAddress}}, View: true},
+			{Name: "tokenURI", Inputs: []ABIParam{{"tokenId", ABITypeUint}}, Outputs: []ABIParam{{"uri", ABITypeString}}, View: true},
+			{Name: "balanceOf", Inputs: []ABIParam{{"owner", ABITypeAddress}}, Outputs: []ABIParam{{"count", ABITypeUint}}, View: true},
+			{Name: "tokensOfOwner", Inputs: []ABIParam{{"owner", ABITypeAddress}}, Outputs: []ABIParam{{"tokenIds", ABITypeUintList}}, View: true},
+			{Name: "tokenOfOwnerByIndex", Inputs: []ABIParam{{"owner", ABITypeAddress}, {"index", ABITypeUint}}, Outputs: []ABIParam{{"tokenId", ABITypeUint}}, View: true},
+			{Name: "totalSupply", Outputs: []ABIParam{{"supply", ABITypeUint}}, View: true},
+		},
+		Events: []string{EventTransfer, EventApproval},
+	},
+	ContractTypeMultisig: {
+		Type: ContractTypeMultisig,
+		Functions: []ABIFunction{
+			{Name: ContractConstructor, Inputs: []ABIParam{{"threshold", ABITypeUint}, {"owners", ABITypeAddressList}}, Outputs: []ABIParam{{"message", ABITypeString}}, Payable: true},
+			{Name: "deposit", Outputs: []ABIParam{{"message", ABITypeString}}, Payable: true},
+			{Name: "submit", Inputs: []ABIParam{{"to", ABITypeAddress}, {"value", ABITypeAmount}}, Outputs: []ABIParam{{"txId", ABITypeUint}}},
+			{Name: "confirm", Inputs: []ABIParam{{"txId", ABITypeUint}}, Outputs: []ABIParam{{"message", ABITypeString}}},
+			{Name: "revoke", Inputs: []ABIParam{{"txId", ABITypeUint}}, Outputs: []ABIParam{{"message", ABITypeString}}},
+			{Name: "execute", Inputs: []ABIParam{{"txId", ABITypeUint}}, Outputs: []ABIParam{{"result", ABITypeAny}}},
+			{Name: "getTransaction", Inputs: []ABIParam{{"txId", ABITypeUint}}, Outputs: []ABIParam{{"transaction", ABITypeMap}}, View: true},
+			{Name: "getOwners", Outputs: []ABIParam{{"owners", ABITypeMap}}, View: true},
+		},
+		Events: []string{EventDeposit, EventSubmission, EventConfirmation, EventRevocation, EventExecution},
+	},
+	ContractTypeAMM: {
+		Type: ContractTypeAMM,
+		Functions: []ABIFunction{
+			{Name: "addLiquidity", Inputs: []ABIParam{{"token", ABITypeAddress}, {"maxTokens", ABITypeAmount}, {"minShares", ABITypeAmount}}, Outputs: []ABIParam{{"shares", ABITypeAmount}}, Payable: true},
+			{Name: "removeLiquidity", Inputs: []ABIParam{{"token", ABITypeAddress}, {"shares", ABITypeAmount}, {"minCoins", ABITypeAmount}, {"minTokens", ABITypeAmount}}, Outputs: []ABIParam{{"amounts", ABITypeMap}}},
+			{Name: "swapCoinForToken", Inputs: []ABIParam{{"token", ABITypeAddress}, {"minTokens", ABITypeAmount}}, Outputs: []ABIParam{{"tokensOut", ABITypeAmount}}, Payable: true},
+			{Name: "swapTokenForCoin", Inputs: []ABIParam{{"token", ABITypeAddress}, {"tokens", ABITypeAmount}, {"minCoins", ABITypeAmount}}, Outputs: []ABIParam{{"coinsOut", ABITypeAmount}}},
+			{Name: "quoteCoinForToken", Inputs: []ABIParam{{"token", ABITypeAddress}, {"coins", ABITypeAmount}}, Outputs: []ABIParam{{"tokensOut", ABITypeAmount}}, View: true},
+			{Name: "quoteTokenForCoin", Inputs: []ABIParam{{"token", ABITypeAddress}, {"tokens", ABITypeAmount}}, Outputs: []ABIParam{{"coinsOut", ABITypeAmount}}, View: true},
+			{Name: "getPrice", Inputs: []ABIParam{{"token", ABITypeAddress}}, Outputs: []ABIParam{{"coinsPerToken", ABITypeAmount}}, View: true},
+			{Name: "getPool", Inputs: []ABIParam{{"token", ABITypeAddress}}, Outputs: []ABIParam{{"pool", ABITypeMap}}, View: true},
+			{Name: "sharesOf", Inputs: []ABIParam{{"token", ABITypeAddress}, {"provider", ABITypeAddress}}, Outputs: []ABIParam{{"shares", ABITypeAmount}}, View: true},
+		},
+		Events: []string{EventLiquidityAdded, EventLiquidityRemoved, EventSwap},
+	},
+	ContractTypeVesting: {
+		Type: ContractTypeVesting,
+		Functions: []ABIFunction{
+			{Name: ContractConstructor, Inputs: []ABIParam{{"beneficiary", ABITypeAddress}, {"clock", ABITypeString}, {"start", ABITypeUint}, {"cliff", ABITypeUint}, {"duration", ABITypeUint}, {"revocable", ABITypeBool}}, Outputs: []ABIParam{{"message", ABITypeString}}, Payable: true},
+			{Name: "deposit", Outputs: []ABIParam{{"message", ABITypeString}}, Payable: true},
+			{Name: "release", Outputs: []ABIParam{{"message", ABITypeString}}},
+			{Name: "revoke", Outputs: []ABIParam{{"message", ABITypeString}}},
+			{Name: "vestedAmount", Outputs: []ABIParam{{"vested", ABITypeAmount}}, View: true},
+			{Name: "releasable", Outputs: []ABIParam{{"amount", ABITypeAmount}}, View: true},
+			{Name: "getSchedule", Outputs: []ABIParam{{"schedule", ABITypeMap}}, View: true},
+		},
+		Events: []string{EventDeposit, EventRelease, EventVestingRevoked},
+	},
+	ContractTypeGovernance: {
+		Type: ContractTypeGovernance,
+		Functions: []ABIFunction{
+			{Name: ContractConstructor, Inputs: []ABIParam{{"weightSource", ABITypeString}, {"votingDelay", ABITypeUint}, {"votingPeriod", ABITypeUint}, {"quorum", ABITypeAmount}, {"threshold", ABITypeUint}}, Outputs: []ABIParam{{"message", ABITypeString}}, Payable: true},
+			{Name: "deposit", Outputs: []ABIParam{{"message", ABITypeString}}, Payable: true},
+			{Name: "propose", Inputs: []ABIParam{{"description", ABITypeString}, {"target", ABITypeString}, {"value", ABITypeAmount}}, Outputs: []ABIParam{{"proposalId", ABITypeUint}}},
+			{Name: "vote", Inputs: []ABIParam{{"proposalId", ABITypeUint}, {"support", ABITypeString}}, Outputs: []ABIParam{{"message", ABITypeString}}},
+			{Name: "delegate", Inputs: []ABIParam{{"delegatee", ABITypeAddress}}, Outputs: []ABIParam{{"message", ABITypeString}}},
+			{Name: "execute", Inputs: []ABIParam{{"proposalId", ABITypeUint}}, Outputs: []ABIParam{{"result", ABITypeAny}}},
+			{Name: "getProposal", Inputs: []ABIParam{{"proposalId", ABITypeUint}}, Outputs: []ABIParam{{"proposal", ABITypeMap}}, View: true},
+			{Name: "state", Inputs: []ABIParam{{"proposalId", ABITypeUint}}, Outputs: []ABIParam{{"state", ABITypeString}}, View: true},
+			{Name: "getVotingPower", Inputs: []ABIParam{{"account", ABITypeAddress}}, Outputs: []ABIParam{{"power", ABITypeAmount}}, View: true},
+			{Name: "getDelegate", Inputs: []ABIParam{{"account", ABITypeAddress}}, Outputs: []ABIParam{{"delegatee", ABITypeAddress}}, View: true},
+		},
+		Events: []string{EventDeposit, EventGovernanceProposal, EventGovernanceVote, EventDelegateChanged, EventProposalExecuted},
+	},
+	ContractTypeProxy: {
+		Type: ContractTypeProxy,
+		Functions: []ABIFunction{
+			{Name: ContractConstructor, Inputs: []ABIParam{{"implementation", ABITypeAddress}, {"timelock", ABITypeUint}}, Outputs: []ABIParam{{"result", ABITypeAny}}, Payable: true},
+			{Name: "upgradeTo", Inputs: []ABIParam{{"implementation", ABITypeAddress}}, Outputs: []ABIParam{{"message", ABITypeString}}},
+			{Name: "applyUpgrade", Outputs: []ABIParam{{"message", ABITypeString}}},
+			{Name: "cancelUpgrade", Outputs: []ABIParam{{"message", ABITypeString}}},
+			{Name: "changeAdmin", Inputs: []ABIParam{{"admin", ABITypeAddress}}, Outputs: []ABIParam{{"message", ABITypeString}}},
+			{Name: "implementation", Outputs: []ABIParam{{"implementation", ABITypeAddress}}, View: true},
+			{Name: "admin", Outputs: []ABIParam{{"admin", ABITypeAddress}}, View: true},
+			{Name: "pendingUpgrade", Outputs: []ABIParam{{"upgrade", ABITypeMap}}, View: true},
+			{Name: "implementationHistory", Outputs: []ABIParam{{"history", ABITypeAny}}, View: true},
+		},
+		Events: []string{EventUpgraded, EventUpgradeScheduled, EventUpgradeCanceled, EventAdminChanged},
+	},
+}
+
+// GetContractTypeABI returns the ABI of a built-in contract type
+func GetContractTypeABI(contractType ContractType) (*ContractABI, error) {
+	abi, exists := builtinABIs[contractType]
+	if !exists {
+		return nil, fmt.Errorf("no ABI for contract type: %s", contractType)
+	}
+	return abi, nil
+}
+
+// Function looks up a function by name
+func (abi *ContractABI) Function(name string) (*ABIFunction, error) {
+	for i := range abi.Functions {
+		if abi.Functions[i].Name == name {
+			return &abi.Functions[i], nil
+		}
+	}
+	return nil, fmt.Errorf("unknown function: %s", name)
+}
+
+// EncodeCall validates arguments against a function's inputs and returns JSON call data
+func (abi *ContractABI) EncodeCall(function string, args ...interface{}) (string, error) {
+	fn, err := abi.Function(function)
+	if err != nil {
+		return "", err
+	}
+	values, err := fn.DecodeArgs(args)
+	if err != nil {
+		return "", err
+	}
+	return EncodeContractCall(function, values...)
+}
+
+// EncodeContractCall returns JSON call data for a function without type checking
+func EncodeContractCall(function string, args ...interface{}) (string, error) {
+	if args == nil {
+		args = []interface{}{}
+	}
+	data, err := json.Marshal(EncodedCall{Function: function, Args: args})
+	if err != nil {
+		return "", fmt.Errorf("failed to encode contract call: %v", err)
+	}
+	return string(data), nil
+}
+
+// DecodeArgs converts raw arguments (JSON values or legacy strings) to the function's input types.
+// Arguments beyond the declared inputs are passed through unchanged.
+func (fn *ABIFunction) DecodeArgs(args []interface{}) ([]interface{}, error) {
+	if len(args) < len(fn.Inputs) {
+		return nil, fmt.Errorf("%s requires %d argument(s)", fn.Name, len(fn.Inputs))
+	}
+	// A trailing list input collects the remaining legacy string arguments
+	if n := len(fn.Inputs); n > 0 && fn.Inputs[n-1].Type == ABITypeAddressList {
+		if _, isList := args[n-1].([]interface{}); !isList {
+			rest := make([]interface{}, len(args)-(n-1))
+			copy(rest, args[n-1:])
+			args = append(append([]interface{}(nil), args[:n-1]...), rest)
+		}
+	}
+
+	values := make([]interface{}, len(args))
+	copy(values, args)
+	for i, param := range fn.Inputs {
+		value, err := convertABIValue(param.Type, args[i])
+		if err != nil {
+			return nil, fmt.Errorf("%s: argument %q: %v", fn.Name, param.Name, err)
+		}
+		values[i] = value
+	}
+	return values, nil
+}
+
+// EncodeResult converts a function's result to its declared output type and returns it as JSON
+func (fn *ABIFunction) EncodeResult(result interface{}) ([]byte, error) {
+	if len(fn.Outputs) == 1 {
+		value, err := convertABIValue(fn.Outputs[0].Type, result)
+		if err != nil {
+			return nil, fmt.Errorf("%s: result %q: %v", fn.Name, fn.Outputs[0].Name, err)
+		}
+		result = value
+	}
+	data, err := json.Marshal(result)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode result: %v", err)
+	}
+	return data, nil
+}
+
+// convertABIValue converts a JSON-decoded value or legacy string argument to an ABI type
+func convertABIValue(t ABIType, value interface{}) (interface{}, error) {
+	switch t {
+	case ABITypeAddress:
+		s, ok := value.(string)
+		if !ok || strings.TrimSpace(s) == "" {
+			return nil, fmt.Errorf("expected address")
+		}
+		return strings.TrimSpace(s), nil
+
+	case ABITypeString:
+		s, ok := value.(string)
+		if !ok {
+			return nil, fmt.Errorf("expected string")
+		}
+		return s, nil
+
+	case ABITypeAmount:
+		var amount float64
+		switch v := value.(type) {
+		case float64:
+			amount = v
+		case int:
+			amount = float64(v)
+		case string:
+			parsed, err := parseAmount(strings.TrimSpace(v))
+			if err != nil {
+				return nil, err
+			}
+			amount = parsed
+		default:
+			return nil, fmt.Errorf("expected amount")
+		}
+		if amount < 0 || math.IsNaN(amount) || math.IsInf(amount, 0) {
+			return nil, fmt.Errorf("invalid amount: %v", value)
+		}
+		return amount, nil
+
+	case ABITypeUint:
+		switch v := value.(type) {
+		case float64:
+			if v < 0 || v != math.Trunc(v) {
+				return nil, fmt.Errorf("expected non-negative integer")
+			}
+			return uint64(v), nil
+		case int:
+			if v < 0 {
+				return nil, fmt.Errorf("expected non-negative integer")
+			}
+			return uint64(v), nil
+		case uint64:
+			return v, nil
+		case string:
+			n, err := strconv.ParseUint(strings.TrimSpace(v), 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("expected non-negative integer")
+			}
+			return n, nil
+		default:
+			return nil, fmt.Errorf("expected non-negative integer")
+		}
+
+	case ABITypeBool:
+		switch v := value.(type) {
+		case bool:
+			return v, nil
+		case string:
+			b, err := strconv.ParseBool(strings.TrimSpace(v))
+			if err != nil {
+				return nil, fmt.Errorf("expected bool")
+			}
+			return b, nil
+		default:
+			return nil, fmt.Errorf("expected bool")
+		}
+
+	case ABITypeUintList:
+		if _, ok := value.([]uint64); !ok {
+			return nil, fmt.Errorf("expected list of integers")
+		}
+		return value, nil
+
+	case ABITypeAddressList:
+		var items []interface{}
+		switch v := value.(type) {
+		case []interface{}:
+			items = v
+		case []string:
+			for _, item := range v {
+				items = append(items, item)
+			}
+		default:
+			return nil, fmt.Errorf("expected list of addresses")
+		}
+		addresses := make([]string, len(items))
+		for i, item := range items {
+			address, err := convertABIValue(ABITypeAddress, item)
+			if err != nil {
+				return nil, err
+			}
+			addresses[i] = address.(string)
+		}
+		return addresses, nil
+
+	case ABITypeMap:
+		return value, nil
+
+	case ABITypeAny:
+		return value, nil
+
+	default:
+		return nil, fmt.Errorf("unknown ABI type: %s", t)
+	}
+}
+
+// abiValueString formats a typed argument as a legacy string argument
+func abiValueString(value interface{}) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case float64:
+		return formatAmount(v)
+	case bool:
+		return strconv.FormatBool(v)
+	case uint64:
+		return strconv.FormatUint(v, 10)
+	case []string:
+		return strings.Join(v, ",")
+	case nil:
+		return ""
+	default:
+		data, _ := json.Marshal(v)
+		return string(data)
+	}
+}
+
+// bindArgs checks a call against the contract's ABI and stores typed arguments on the context.
+// Contracts without an ABI (such as WASM contracts) receive their arguments as plain strings.
+func (sc *SmartContract) bindArgs(function string, ctx *ContractContext) error {
+	abi, err := GetContractTypeABI(sc.Type)
+	if err != nil {
+		return nil
+	}
+	fn, err := abi.Function(function)
+	if err != nil {
+		if sc.Type == ContractTypeProxy {
+			return nil // Bound against the implementation's ABI when delegated
+		}
+		return err
+	}
+	if ctx.Value > 0 && !fn.Payable {
+		return fmt.Errorf("function %s is not payable", function)
+	}
+
+	raw := ctx.Values
+	if raw == nil {
+		raw = make([]interface{}, len(ctx.Args))
+		for i, arg := range ctx.Args {
+			raw[i] = arg
+		}
+	}
+	values, err := fn.DecodeArgs(raw)
+	if err != nil {
+		return err
+	}
+	ctx.Values = values
+	ctx.Args = make([]string, len(values))
+	for i, value := range values {
+		ctx.Args[i] = abiValueString(value)
+	}
+	return nil
+}
+
+// StringArg returns argument i as a string (addresses and text)
+func (ctx *ContractContext) StringArg(i int) string {
+	if i < len(ctx.Values) {
+		if s, ok := ctx.Values[i].(string); ok {
+			return s
+		}
+	}
+	if i < len(ctx.Args) {
+		return ctx.Args[i]
+	}
+	return ""
+}
+
+// AmountArg returns argument i as an amount
+func (ctx *ContractContext) AmountArg(i int) (float64, error) {
+	if i < len(ctx.Values) {
+		if amount, ok := ctx.Values[i].(float64); ok {
+			return amount, nil
+		}
+	}
+	if i >= len(ctx.Args) {
+		return 0, fmt.Errorf("missing amount argument")
+	}
+	return parseAmount(ctx.Args[i])
+}
+
+// StringListArg returns argument i as a list of strings (a bound list, or the remaining untyped arguments)
+func (ctx *ContractContext) StringListArg(i int) []string {
+	if i < len(ctx.Values) {
+		if list, ok := ctx.Values[i].([]string); ok {
+			return list
+		}
+	}
+	if i >= len(ctx.Args) {
+		return nil
+	}
+	return append([]string(nil), ctx.Args[i:]...)
+}
+
+// CallDataArg returns the optional call data from argument i on, rejoining
+// untyped arguments that the legacy comma-separated format split apart
+func (ctx *ContractContext) CallDataArg(i int) string {
+	if i < len(ctx.Values) {
+		if data, ok := ctx.Values[i].(string); ok {
+			return data
+		}
+	}
+	if i >= len(ctx.Args) {
+		return ""
+	}
+	return strings.Join(ctx.Args[i:], ",")
+}
+
+// UintArg returns argument i as a non-negative integer
+func (ctx *ContractContext) UintArg(i int) (uint64, error) {
+	if i < len(ctx.Values) {
+		if n, ok := ctx.Values[i].(uint64); ok {
+			return n, nil
+		}
+	}
+	if i >= len(ctx.Args) {
+		return 0, fmt.Errorf("missing integer argument")
+	}
+	n, err := convertABIValue(ABITypeUint, ctx.Args[i])
+	if err != nil {
+		return 0, err
+	}
+	return n.(uint64), nil
+}
+
+// BoolArg returns argument i as a boolean
+func (ctx *ContractContext) BoolArg(i int) (bool, error) {
+	if i < len(ctx.Values) {
+		if b, ok := ctx.Values[i].(bool); ok {
+			return b, nil
+		}
+	}
+	if i >= len(ctx.Args) {
+		return false, fmt.Errorf("missing bool argument")
+	}
+	b, err := convertABIValue(ABITypeBool, ctx.Args[i])
+	if err != nil {
+		return false, err
+	}
+	return b.(bool), nil
+}
+
+// GetABI returns the ABI of a deployed contract
+func (cr *ContractRegistry) GetABI(address string) (*ContractABI, error) {
+	contract, err := cr.GetContract(address)
+	if err != nil {
+		return nil, err
+	}
+	if contract.Type == ContractTypeProxy {
+		return cr.proxyABI(contract)
+	}
+	return GetContractTypeABI(contract.Type)
+}
+
+// GetABIs returns the ABIs of all built-in contract types
+func (cr *ContractRegistry) GetABIs() map[ContractType]*ContractABI {
+	abis := make(map[ContractType]*ContractABI, len(builtinABIs))
+	for contractType, abi := range builtinABIs {
+		abis[contractType] = abi
+	}
+	return abis
+}
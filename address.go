@@ -0,0 +1,256 @@
+package main
+
+import (
+	"encoding/hex"
+	"fmt"
+	"strings"
+)
+
+// AddressHRP is the human-readable part that starts every encoded address
+const AddressHRP = "lbc"
+
+// AddressType is the version of an encoded address, telling what kind of account it names
+type AddressType byte
+
+const (
+	AddressTypeAccount  AddressType = 0 // Key-controlled account: 40 hex characters
+	AddressTypeContract AddressType = 1 // Smart contract: "0x" + 40 hex characters
+	AddressTypeMultisig AddressType = 2 // Native multisig account: MultisigAddressPrefix + 40 hex characters
+)
+
+// addressPrefixes are the prefixes of each type in the internal hex form
+var addressPrefixes = map[AddressType]string{
+	AddressTypeAccount:  "",
+	AddressTypeContract: "0x",
+	AddressTypeMultisig: MultisigAddressPrefix,
+}
+
+// String returns the name of the address type
+func (t AddressType) String() string {
+	switch t {
+	case AddressTypeAccount:
+		return "account"
+	case AddressTypeContract:
+		return "contract"
+	case AddressTypeMultisig:
+		return "multisig"
+	default:
+		return fmt.Sprintf("unknown(%d)", byte(t))
+	}
+}
+
+// bech32m alphabet and checksum constant (BIP-350)
+const (
+	bech32Charset   = "qpzry9x8gf2tvdw0s3jn54khce6mua7l"
+	bech32mConstant = 0x2bc830a3
+)
+
+// ValidateAddress checks that an address is in the internal form of one of the address types.
+// The hex must be lowercase: addresses are compared as strings, so "AB" and "ab" are different accounts.
+func ValidateAddress(address string) error {
+	if IsEncodedAddress(address) {
+		if _, err := DecodeAddress(address); err != nil {
+			return fmt.Errorf("invalid address %q: %v", address, err)
+		}
+		return fmt.Errorf("address %q is encoded; decode it with ParseAddress", address)
+	}
+	_, _, err := splitAddress(address)
+	return err
+}
+
+// AddressTypeOf returns the type of an address in internal form
+func AddressTypeOf(address string) (AddressType, error) {
+	addressType, _, err := splitAddress(address)
+	return addressType, err
+}
+
+// EncodeAddress converts an address to its checksummed form: AddressHRP, "1", the address type
+// and 20 bytes in bech32 characters, then a six-character bech32m checksum. A typo of up to four
+// characters is always detected, and the type cannot be changed without breaking the checksum.
+func EncodeAddress(address string) (string, error) {
+	addressType, payload, err := splitAddress(address)
+	if err != nil {
+		return "", err
+	}
+	data := append([]byte{byte(addressType)}, convertBits(payload, 8, 5, true)...)
+	checksum := bech32mChecksum(AddressHRP, data)
+
+	var encoded strings.Builder
+	encoded.WriteString(AddressHRP + "1")
+	for _, value := range append(data, checksum...) {
+		encoded.WriteByte(bech32Charset[value])
+	}
+	return encoded.String(), nil
+}
+
+// DecodeAddress checks the checksum of an encoded address and returns its internal form
+func DecodeAddress(encoded string) (string, error) {
+	if strings.ToLower(encoded) != encoded && strings.ToUpper(encoded) != encoded {
+		return "", fmt.Errorf("encoded address mixes upper and lower case")
+	}
+	encoded = strings.ToLower(encoded)
+	separator := strings.LastIndexByte(encoded, '1')
+	if separator < 0 || encoded[:separator] != AddressHRP {
+		return "", fmt.Errorf("encoded address must start with %s1", AddressHRP)
+	}
+
+	data := make([]byte, 0, len(encoded)-separator-1)
+	for _, c := range encoded[separator+1:] {
+		value := strings.IndexRune(bech32Charset, c)
+		if value < 0 {
+			return "", fmt.Errorf("invalid character %q in encoded address", c)
+		}
+		data = append(data, byte(value))
+	}
+	if len(data) < 7 {
+		return "", fmt.Errorf("encoded address is too short")
+	}
+	if bech32Polymod(append(bech32HRPExpand(AddressHRP), data...)) != bech32mConstant {
+		return "", fmt.Errorf("invalid address checksum")
+	}
+
+	data = data[:len(data)-6]
+	addressType := AddressType(data[0])
+	prefix, known := addressPrefixes[addressType]
+	if !known {
+		return "", fmt.Errorf("unknown address type %d", data[0])
+	}
+	payload, err := convertBitsStrict(data[1:])
+	if err != nil {
+		return "", err
+	}
+	if len(payload) != 20 {
+		return "", fmt.Errorf("encoded address must hold 20 bytes, got %d", len(payload))
+	}
+	return prefix + hex.EncodeToString(payload), nil
+}
+
+// ParseAddress accepts an address typed by a user or client, either encoded (checked against
+// its checksum) or in internal form, and returns the internal form
+func ParseAddress(input string) (string, error) {
+	if IsEncodedAddress(input) {
+		return DecodeAddress(input)
+	}
+	if err := ValidateAddress(input); err != nil {
+		return "", err
+	}
+	return input, nil
+}
+
+// IsEncodedAddress reports whether input looks like an encoded address (its checksum is not checked)
+func IsEncodedAddress(input string) bool {
+	return strings.HasPrefix(strings.ToLower(input), AddressHRP+"1")
+}
+
+// FormatAddress returns the encoded form of an address for display, or the address unchanged if
+// it is not a valid one (such as the empty sender of a coinbase)
+func FormatAddress(address string) string {
+	if encoded, err := EncodeAddress(address); err == nil {
+		return encoded
+	}
+	return address
+}
+
+// canonicalAddress decodes an encoded address, leaving anything else unchanged for
+// ValidateAddress to judge later
+func canonicalAddress(address string) string {
+	if !IsEncodedAddress(address) {
+		return address
+	}
+	if decoded, err := DecodeAddress(address); err == nil {
+		return decoded
+	}
+	return address
+}
+
+// splitAddress returns the type and 20-byte payload of an address in internal form
+func splitAddress(address string) (AddressType, []byte, error) {
+	addressType := AddressTypeAccount
+	switch {
+	case len(address) == 42 && strings.HasPrefix(address, "0x"):
+		addressType = AddressTypeContract
+	case len(address) == len(MultisigAddressPrefix)+40 && strings.HasPrefix(address, MultisigAddressPrefix):
+		addressType = AddressTypeMultisig
+	case len(address) != 40:
+		return 0, nil, fmt.Errorf("invalid address %q: expected 40 hex characters, optionally prefixed with 0x or %s", address, MultisigAddressPrefix)
+	}
+	digits := address[len(addressPrefixes[addressType]):]
+	if strings.ToLower(digits) != digits {
+		return 0, nil, fmt.Errorf("invalid address %q: hex must be lowercase", address)
+	}
+	payload, err := hex.DecodeString(digits)
+	if err != nil {
+		return 0, nil, fmt.Errorf("invalid address %q: not hex", address)
+	}
+	return addressType, payload, nil
+}
+
+// bech32Polymod computes the BCH checksum over 5-bit values
+func bech32Polymod(values []byte) uint32 {
+	generator := [5]uint32{0x3b6a57b2, 0x26508e6d, 0x1ea119fa, 0x3d4233dd, 0x2a1462b3}
+	checksum := uint32(1)
+	for _, value := range values {
+		top := checksum >> 25
+		checksum = (checksum&0x1ffffff)<<5 ^ uint32(value)
+		for i := 0; i < 5; i++ {
+			if (top>>i)&1 == 1 {
+				checksum ^= generator[i]
+			}
+		}
+	}
+	return checksum
+}
+
+// bech32HRPExpand spreads the human-readable part into 5-bit values so the checksum covers it
+func bech32HRPExpand(hrp string) []byte {
+	expanded := make([]byte, 0, 2*len(hrp)+1)
+	for i := 0; i < len(hrp); i++ {
+		expanded = append(expanded, hrp[i]>>5)
+	}
+	expanded = append(expanded, 0)
+	for i := 0; i < len(hrp); i++ {
+		expanded = append(expanded, hrp[i]&31)
+	}
+	return expanded
+}
+
+// bech32mChecksum returns the six 5-bit checksum values for data
+func bech32mChecksum(hrp string, data []byte) []byte {
+	values := append(bech32HRPExpand(hrp), data...)
+	polymod := bech32Polymod(append(values, 0, 0, 0, 0, 0, 0)) ^ bech32mConstant
+	checksum := make([]byte, 6)
+	for i := range checksum {
+		checksum[i] = byte(polymod>>(5*(5-i))) & 31
+	}
+	return checksum
+}
+
+// convertBits regroups a sequence of fromBits-bit values into toBits-bit values
+func convertBits(data []byte, fromBits, toBits uint, pad bool) []byte {
+	var converted []byte
+	accumulator, bits := uint32(0), uint(0)
+	maxValue := uint32(1)<<toBits - 1
+	for _, value := range data {
+		accumulator = accumulator<<fromBits | uint32(value)
+		bits += fromBits
+		for bits >= toBits {
+			bits -= toBits
+			converted = append(converted, byte(accumulator>>bits&maxValue))
+		}
+	}
+	if pad && bits > 0 {
+		converted = append(converted, byte(accumulator<<(toBits-bits)&maxValue))
+	}
+	return converted
+}
+
+// convertBitsStrict turns 5-bit values back into bytes, rejecting leftover bits that are not zero padding
+func convertBitsStrict(data []byte) ([]byte, error) {
+	if len(data)*5%8 >= 5 {
+		return nil, fmt.Errorf("invalid encoded address length")
+	}
+	if padding := len(data) * 5 % 8; padding > 0 && data[len(data)-1]&(1<<padding-1) != 0 {
+		return nil, fmt.Errorf("invalid encoded address padding")
+	}
+	return convertBits(data, 5, 8, false), nil
+}
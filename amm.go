@@ -0,0 +1,297 @@
+package main
+
+import (
+	"fmt"
+	"math"
+)
+
+// AMMFeeRate is the fraction of every swap input kept by the pool for liquidity providers
+const AMMFeeRate = 0.003
+
+// Event signatures emitted by AMM contracts
+const (
+	EventLiquidityAdded   = "LiquidityAdded(address,address,uint256)"
+	EventLiquidityRemoved = "LiquidityRemoved(address,address,uint256)"
+	EventSwap             = "Swap(address,address,uint256,uint256)"
+)
+
+// executeAMM executes a constant-product automated market maker (Uniswap v1 like).
+// Each pool pairs the native coin with a token contract and is keyed by the token's address;
+// swaps keep reserveCoin * reserveToken constant apart from the fee, which accrues to the pool.
+// Tokens are pulled from callers with transferFrom, so callers must approve the AMM first.
+func (sc *SmartContract) executeAMM(function string, ctx *ContractContext) (interface{}, error) {
+	reserveCoin := sc.getFloatMap("reserveCoin")   // token -> native coin reserve
+	reserveToken := sc.getFloatMap("reserveToken") // token -> token reserve
+	totalShares := sc.getFloatMap("totalShares")   // token -> outstanding LP shares
+	shares := sc.getFloatMap("shares")             // token:provider -> LP shares
+
+	// NaN passes every comparison below and would poison the reserves
+	if math.IsNaN(ctx.Value) || math.IsInf(ctx.Value, 0) {
+		return nil, fmt.Errorf("invalid value: %v", ctx.Value)
+	}
+
+	switch function {
+	case "addLiquidity":
+		if err := validateArgsCount(ctx.Args, 3, "addLiquidity"); err != nil {
+			return nil, err
+		}
+		token := ctx.StringArg(0)
+		maxTokens, err := ctx.AmountArg(1)
+		if err != nil {
+			return nil, err
+		}
+		minShares, err := ctx.AmountArg(2)
+		if err != nil {
+			return nil, err
+		}
+		if err := checkAMMToken(ctx, token); err != nil {
+			return nil, err
+		}
+		if ctx.Value <= 0 || maxTokens <= 0 {
+			return nil, fmt.Errorf("liquidity requires both coins and tokens")
+		}
+
+		sc.mu.RLock()
+		coins, tokens, total := reserveCoin[token], reserveToken[token], totalShares[token]
+		sc.mu.RUnlock()
+
+		// The first provider sets the price; later ones deposit at the current ratio
+		tokenAmount, minted := maxTokens, math.Sqrt(ctx.Value*maxTokens)
+		if total > 0 {
+			tokenAmount = ctx.Value * tokens / coins
+			if tokenAmount > maxTokens {
+				return nil, fmt.Errorf("insufficient token amount: pool requires %.8f tokens", tokenAmount)
+			}
+			minted = ctx.Value * total / coins
+		}
+		if minted < minShares {
+			return nil, fmt.Errorf("slippage limit exceeded: %.8f shares < %.8f", minted, minShares)
+		}
+
+		if _, err := ctx.Call(token, "transferFrom", []string{ctx.Caller, ctx.Address, formatAmount(tokenAmount)}, 0); err != nil {
+			return nil, err
+		}
+
+		sc.mu.Lock()
+		reserveCoin[token] += ctx.Value
+		reserveToken[token] += tokenAmount
+		totalShares[token] += minted
+		shares[poolShareKey(token, ctx.Caller)] += minted
+		sc.mu.Unlock()
+
+		sc.emitLog(ctx, EventLiquidityAdded, []string{ctx.Caller, token}, formatAmount(minted))
+		return minted, nil
+
+	case "removeLiquidity":
+		if err := validateArgsCount(ctx.Args, 4, "removeLiquidity"); err != nil {
+			return nil, err
+		}
+		token := ctx.StringArg(0)
+		amount, err := ctx.AmountArg(1)
+		if err != nil {
+			return nil, err
+		}
+		minCoins, err := ctx.AmountArg(2)
+		if err != nil {
+			return nil, err
+		}
+		minTokens, err := ctx.AmountArg(3)
+		if err != nil {
+			return nil, err
+		}
+		if amount == 0 {
+			return nil, fmt.Errorf("share amount must be greater than zero")
+		}
+		key := poolShareKey(token, ctx.Caller)
+
+		sc.mu.Lock()
+		if shares[key] < amount {
+			held := shares[key]
+			sc.mu.Unlock()
+			return nil, fmt.Errorf("insufficient shares: %.8f < %.8f", held, amount)
+		}
+		total := totalShares[token]
+		coinsOut := amount * reserveCoin[token] / total
+		tokensOut := amount * reserveToken[token] / total
+		if amount == total {
+			// The last provider takes the whole pool, free of rounding error
+			coinsOut, tokensOut = reserveCoin[token], reserveToken[token]
+		}
+		if coinsOut < minCoins || tokensOut < minTokens {
+			sc.mu.Unlock()
+			return nil, fmt.Errorf("slippage limit exceeded: %.8f coins and %.8f tokens out", coinsOut, tokensOut)
+		}
+		shares[key] -= amount
+		totalShares[token] -= amount
+		reserveCoin[token] -= coinsOut
+		reserveToken[token] -= tokensOut
+		sc.mu.Unlock()
+
+		if err := ctx.Transfer(ctx.Caller, coinsOut); err != nil {
+			return nil, err
+		}
+		if _, err := ctx.Call(token, "transfer", []string{ctx.Caller, formatAmount(tokensOut)}, 0); err != nil {
+			return nil, err
+		}
+
+		sc.emitLog(ctx, EventLiquidityRemoved, []string{ctx.Caller, token}, formatAmount(amount))
+		return map[string]interface{}{
+			"coins":  coinsOut,
+			"tokens": tokensOut,
+		}, nil
+
+	case "swapCoinForToken":
+		if err := validateArgsCount(ctx.Args, 2, "swapCoinForToken"); err != nil {
+			return nil, err
+		}
+		token := ctx.StringArg(0)
+		minOut, err := ctx.AmountArg(1)
+		if err != nil {
+			return nil, err
+		}
+		if ctx.Value <= 0 {
+			return nil, fmt.Errorf("swap value must be greater than zero")
+		}
+
+		sc.mu.Lock()
+		out, err := ammAmountOut(ctx.Value, reserveCoin[token], reserveToken[token])
+		if err == nil && out < minOut {
+			err = fmt.Errorf("slippage limit exceeded: %.8f tokens < %.8f", out, minOut)
+		}
+		if err != nil {
+			sc.mu.Unlock()
+			return nil, err
+		}
+		reserveCoin[token] += ctx.Value
+		reserveToken[token] -= out
+		sc.mu.Unlock()
+
+		if _, err := ctx.Call(token, "transfer", []string{ctx.Caller, formatAmount(out)}, 0); err != nil {
+			return nil, err
+		}
+
+		sc.emitLog(ctx, EventSwap, []string{ctx.Caller, token}, formatAmount(ctx.Value)+","+formatAmount(out))
+		return out, nil
+
+	case "swapTokenForCoin":
+		if err := validateArgsCount(ctx.Args, 3, "swapTokenForCoin"); err != nil {
+			return nil, err
+		}
+		token := ctx.StringArg(0)
+		amountIn, err := ctx.AmountArg(1)
+		if err != nil {
+			return nil, err
+		}
+		minOut, err := ctx.AmountArg(2)
+		if err != nil {
+			return nil, err
+		}
+
+		sc.mu.Lock()
+		out, err := ammAmountOut(amountIn, reserveToken[token], reserveCoin[token])
+		if err == nil && out < minOut {
+			err = fmt.Errorf("slippage limit exceeded: %.8f coins < %.8f", out, minOut)
+		}
+		if err != nil {
+			sc.mu.Unlock()
+			return nil, err
+		}
+		reserveToken[token] += amountIn
+		reserveCoin[token] -= out
+		sc.mu.Unlock()
+
+		if _, err := ctx.Call(token, "transferFrom", []string{ctx.Caller, ctx.Address, formatAmount(amountIn)}, 0); err != nil {
+			return nil, err
+		}
+		if err := ctx.Transfer(ctx.Caller, out); err != nil {
+			return nil, err
+		}
+
+		sc.emitLog(ctx, EventSwap, []string{ctx.Caller, token}, formatAmount(amountIn)+","+formatAmount(out))
+		return out, nil
+
+	case "quoteCoinForToken", "quoteTokenForCoin":
+		if err := validateArgsCount(ctx.Args, 2, function); err != nil {
+			return nil, err
+		}
+		token := ctx.StringArg(0)
+		amountIn, err := ctx.AmountArg(1)
+		if err != nil {
+			return nil, err
+		}
+		sc.mu.RLock()
+		defer sc.mu.RUnlock()
+		if function == "quoteCoinForToken" {
+			return ammAmountOut(amountIn, reserveCoin[token], reserveToken[token])
+		}
+		return ammAmountOut(amountIn, reserveToken[token], reserveCoin[token])
+
+	case "getPrice":
+		if err := validateArgsCount(ctx.Args, 1, "getPrice"); err != nil {
+			return nil, err
+		}
+		token := ctx.StringArg(0)
+		sc.mu.RLock()
+		defer sc.mu.RUnlock()
+		if reserveToken[token] == 0 {
+			return nil, fmt.Errorf("no liquidity for token %s", truncateAddress(token))
+		}
+		return reserveCoin[token] / reserveToken[token], nil
+
+	case "getPool":
+		if err := validateArgsCount(ctx.Args, 1, "getPool"); err != nil {
+			return nil, err
+		}
+		token := ctx.StringArg(0)
+		sc.mu.RLock()
+		defer sc.mu.RUnlock()
+		return map[string]interface{}{
+			"reserveCoin":  reserveCoin[token],
+			"reserveToken": reserveToken[token],
+			"totalShares":  totalShares[token],
+		}, nil
+
+	case "sharesOf":
+		if err := validateArgsCount(ctx.Args, 2, "sharesOf"); err != nil {
+			return nil, err
+		}
+		sc.mu.RLock()
+		defer sc.mu.RUnlock()
+		return shares[poolShareKey(ctx.StringArg(0), ctx.StringArg(1))], nil
+
+	default:
+		return nil, fmt.Errorf("unknown function: %s", function)
+	}
+}
+
+// ammAmountOut returns the output of a swap against the given reserves after the pool fee
+func ammAmountOut(amountIn, reserveIn, reserveOut float64) (float64, error) {
+	if amountIn <= 0 {
+		return 0, fmt.Errorf("swap amount must be greater than zero")
+	}
+	if reserveIn == 0 || reserveOut == 0 {
+		return 0, fmt.Errorf("pool has no liquidity")
+	}
+	inWithFee := amountIn * (1 - AMMFeeRate)
+	return inWithFee * reserveOut / (reserveIn + inWithFee), nil
+}
+
+// checkAMMToken checks that a pool's token is a deployed token contract
+func checkAMMToken(ctx *ContractContext, token string) error {
+	if ctx.registry == nil {
+		return fmt.Errorf("contract calls are not available in this context")
+	}
+	contract, err := ctx.registry.GetContract(token)
+	if err != nil {
+		return err
+	}
+	if contract.Type != ContractTypeToken {
+		return fmt.Errorf("%s is not a token contract", truncateAddress(token))
+	}
+	return nil
+}
+
+// poolShareKey is the state key of a provider's LP shares in a token's pool
+func poolShareKey(token, provider string) string {
+	return token + ":" + provider
+}
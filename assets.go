@@ -0,0 +1,213 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+)
+
+// NativeAsset is the asset ID of the chain's native coin
+const NativeAsset = ""
+
+// AssetBurnAddress receives burned assets; nothing can spend from it and its balance is not part of an asset's supply
+const AssetBurnAddress = "0x0000000000000000000000000000000000000000"
+
+// MaxAssetIDLength is the maximum length of a named asset's ID
+const MaxAssetIDLength = 32
+
+// AssetInfo describes a named asset issued on the chain
+type AssetInfo struct {
+	ID        string  `json:"id"`
+	Issuer    string  `json:"issuer"`    // Address that first issued the asset; only it can issue more
+	CreatedAt int     `json:"createdAt"` // Block of the first issuance
+	Issued    float64 `json:"issued"`    // Total amount ever issued
+	Burned    float64 `json:"burned"`    // Total amount sent to AssetBurnAddress
+	Supply    float64 `json:"supply"`    // Issued minus burned
+}
+
+// NewAssetIssueTransaction creates a transaction issuing amount of a named asset to the issuer.
+// The first issuance of an asset ID makes the sender its issuer.
+func NewAssetIssueTransaction(issuer, asset string, amount, fee float64) *Transaction {
+	return &Transaction{
+		From:   issuer,
+		To:     "",
+		Amount: amount,
+		Fee:    fee,
+		Asset:  asset,
+	}
+}
+
+// NewAssetTransferTransaction creates a transaction moving amount of a named asset; the fee is paid in native coin
+func NewAssetTransferTransaction(from, to, asset string, amount, fee float64) *Transaction {
+	return &Transaction{
+		From:   from,
+		To:     to,
+		Amount: amount,
+		Fee:    fee,
+		Asset:  asset,
+	}
+}
+
+// NewAssetBurnTransaction creates a transaction destroying amount of a named asset held by the sender
+func NewAssetBurnTransaction(from, asset string, amount, fee float64) *Transaction {
+	return NewAssetTransferTransaction(from, AssetBurnAddress, asset, amount, fee)
+}
+
+// IsAssetIssue reports whether the transaction issues new units of a named asset
+func (tx *Transaction) IsAssetIssue() bool {
+	return tx.Asset != NativeAsset && tx.To == ""
+}
+
+// assetSymbol returns a display name for an asset ID
+func assetSymbol(asset string) string {
+	if asset == NativeAsset {
+		return "coins"
+	}
+	return asset
+}
+
+// validateAssetID checks that an asset ID is short and made of letters, digits, '-', '_' or '.'
+func validateAssetID(asset string) error {
+	if len(asset) > MaxAssetIDLength {
+		return fmt.Errorf("asset ID too long: %d > %d characters", len(asset), MaxAssetIDLength)
+	}
+	for _, c := range asset {
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9', c == '-', c == '_', c == '.':
+		default:
+			return fmt.Errorf("invalid character %q in asset ID %q", c, asset)
+		}
+	}
+	return nil
+}
+
+// GetAssetBalance returns the balance of an asset held by an address (NativeAsset for the native coin)
+func (bc *Blockchain) GetAssetBalance(address, asset string) float64 {
+	return bc.GetAssetBalanceAt(address, asset, len(bc.Blocks)-1)
+}
+
+// GetAssetBalanceAt returns the balance of an asset held by an address as of a block (-1 or beyond the tip means latest)
+func (bc *Blockchain) GetAssetBalanceAt(address, asset string, blockNumber int) float64 {
+	if asset == NativeAsset {
+		return bc.GetBalanceAt(address, blockNumber)
+	}
+	if blockNumber < 0 || blockNumber >= len(bc.Blocks) {
+		blockNumber = len(bc.Blocks) - 1
+	}
+
+	balance := 0.0
+	for _, block := range bc.Blocks[:blockNumber+1] {
+		for _, tx := range block.Transactions {
+			if tx.Asset != asset {
+				continue
+			}
+			if tx.IsAssetIssue() {
+				if tx.From == address {
+					balance += tx.Amount
+				}
+				continue
+			}
+			if tx.From == address {
+				balance -= tx.Amount
+			}
+			if tx.To == address {
+				balance += tx.Amount
+			}
+		}
+	}
+	return balance
+}
+
+// GetAssetBalances returns every named asset an address holds, by asset ID
+func (bc *Blockchain) GetAssetBalances(address string) map[string]float64 {
+	balances := make(map[string]float64)
+	for _, info := range bc.GetAssets() {
+		if balance := bc.GetAssetBalance(address, info.ID); balance != 0 {
+			balances[info.ID] = balance
+		}
+	}
+	return balances
+}
+
+// GetAsset returns the issuer and supply of a named asset
+func (bc *Blockchain) GetAsset(asset string) (*AssetInfo, error) {
+	if info, exists := bc.assets()[asset]; exists {
+		return info, nil
+	}
+	return nil, fmt.Errorf("asset not found: %s", asset)
+}
+
+// GetAssets returns every named asset issued on the chain, sorted by ID
+func (bc *Blockchain) GetAssets() []*AssetInfo {
+	assets := bc.assets()
+	list := make([]*AssetInfo, 0, len(assets))
+	for _, info := range assets {
+		list = append(list, info)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].ID < list[j].ID })
+	return list
+}
+
+// assets scans the chain for named assets and their issuance and burns
+func (bc *Blockchain) assets() map[string]*AssetInfo {
+	assets := make(map[string]*AssetInfo)
+	for _, block := range bc.Blocks {
+		for _, tx := range block.Transactions {
+			if tx.Asset == NativeAsset {
+				continue
+			}
+			info, exists := assets[tx.Asset]
+			if tx.IsAssetIssue() {
+				if !exists {
+					info = &AssetInfo{ID: tx.Asset, Issuer: tx.From, CreatedAt: block.Index}
+					assets[tx.Asset] = info
+				}
+				info.Issued += tx.Amount
+			} else if exists && tx.To == AssetBurnAddress {
+				info.Burned += tx.Amount
+			}
+		}
+	}
+	for _, info := range assets {
+		info.Supply = info.Issued - info.Burned
+	}
+	return assets
+}
+
+// validateAssetTransaction checks a named asset transaction: the sender must hold the asset
+// (or be its issuer, to issue more) and pay the fee in native coin
+func (bc *Blockchain) validateAssetTransaction(tx *Transaction) error {
+	if err := validateAssetID(tx.Asset); err != nil {
+		return err
+	}
+	if tx.From == "" {
+		return fmt.Errorf("asset transactions require a sender")
+	}
+	if tx.ContractData != "" {
+		return fmt.Errorf("asset transactions cannot call contracts")
+	}
+	if tx.Amount <= 0 {
+		return fmt.Errorf("asset amount must be greater than zero")
+	}
+	if tx.From == AssetBurnAddress {
+		return fmt.Errorf("burned assets cannot be spent")
+	}
+
+	if balance := bc.GetBalance(tx.From); balance < tx.Fee {
+		return fmt.Errorf("insufficient balance for fee: address %s has %.2f, fee is %.2f", tx.From, balance, tx.Fee)
+	}
+
+	info, exists := bc.assets()[tx.Asset]
+	if tx.IsAssetIssue() {
+		if exists && info.Issuer != tx.From {
+			return fmt.Errorf("only the issuer %s can issue more %s", info.Issuer, tx.Asset)
+		}
+		return nil
+	}
+	if !exists {
+		return fmt.Errorf("asset not found: %s", tx.Asset)
+	}
+	if balance := bc.GetAssetBalance(tx.From, tx.Asset); balance < tx.Amount {
+		return fmt.Errorf("insufficient %s balance: address %s has %.2f, trying to send %.2f", tx.Asset, tx.From, balance, tx.Amount)
+	}
+	return nil
+}
@@ -4,10 +4,18 @@ import "fmt"
 
 // GetBalance calculates the balance of an address by scanning all transactions
 func (bc *Blockchain) GetBalance(address string) float64 {
+	return bc.GetBalanceAt(address, len(bc.Blocks)-1)
+}
+
+// GetBalanceAt calculates the balance of an address as of a block (-1 or beyond the tip means latest)
+func (bc *Blockchain) GetBalanceAt(address string, blockNumber int) float64 {
 	balance := 0.0
+	if blockNumber < 0 || blockNumber >= len(bc.Blocks) {
+		blockNumber = len(bc.Blocks) - 1
+	}
 
-	// Scan all blocks
-	for _, block := range bc.Blocks {
+	// Scan all blocks up to the requested one
+	for _, block := range bc.Blocks[:blockNumber+1] {
 		// Scan all transactions in the block
 		for _, tx := range block.Transactions {
 			// Skip genesis transaction
@@ -15,6 +23,14 @@ func (bc *Blockchain) GetBalance(address string) float64 {
 				continue
 			}
 
+			// Named asset transactions only cost native coin for their fee
+			if tx.Asset != NativeAsset {
+				if tx.From == address {
+					balance -= tx.Fee
+				}
+				continue
+			}
+
 			// Subtract if address is sender (amount + fee)
 			if tx.From == address {
 				balance -= tx.Amount
@@ -31,11 +47,38 @@ func (bc *Blockchain) GetBalance(address string) float64 {
 		}
 	}
 
+	// Apply native coins moved by contracts
+	for _, transfer := range bc.InternalTransfers {
+		if transfer.BlockNumber > blockNumber {
+			continue
+		}
+		if transfer.From == address {
+			balance -= transfer.Amount
+		}
+		if transfer.To == address {
+			balance += transfer.Amount
+		}
+	}
+
 	return balance
 }
 
-// ValidateTransaction checks if a transaction is valid (sufficient balance including fee)
+// ValidateTransaction checks if a transaction is valid (well-formed addresses and type payload,
+// sufficient balance including fee)
 func (bc *Blockchain) ValidateTransaction(tx *Transaction) error {
+	if err := tx.ValidateAddresses(); err != nil {
+		return err
+	}
+	if err := tx.ValidateEnvelope(); err != nil {
+		return err
+	}
+	if err := tx.checkTypeAccepted(); err != nil {
+		return err
+	}
+	if tx.Asset != NativeAsset {
+		return bc.validateAssetTransaction(tx)
+	}
+
 	// Skip validation for genesis-like transactions
 	if tx.From == "" {
 		return nil
@@ -15,6 +15,8 @@ type Block struct {
 	PreviousHash string
 	Hash         string
 	Nonce        int
+	LogsBloom    string             // Bloom filter over contract logs (set after execution, not part of the hash)
+	QuorumCert   *QuorumCertificate // Aggregated commit votes of PBFT validators (set after consensus, not part of the hash)
 }
 
 // CalculateHash calculates the hash of the block
@@ -28,6 +30,22 @@ func (b *Block) CalculateHash() string {
 	return CalculateHash(record)
 }
 
+// VerifyQuorumCertificate checks that 2f+1 of the validators (64-byte secp256k1 keys, in PBFT
+// node order) committed to this block, without replaying the consensus messages
+func (b *Block) VerifyQuorumCertificate(validatorKeys [][]byte) error {
+	if b.QuorumCert == nil {
+		return fmt.Errorf("block #%d has no quorum certificate", b.Index)
+	}
+	if b.Hash != b.CalculateHash() {
+		return fmt.Errorf("block #%d hash mismatch", b.Index)
+	}
+	qc := b.QuorumCert
+	if qc.Domain != QuorumDomainPBFTCommit || qc.Subject != b.Hash || qc.Round != int64(b.Index) {
+		return fmt.Errorf("quorum certificate is not a commit for block #%d", b.Index)
+	}
+	return qc.Verify(validatorKeys, QuorumSize(len(validatorKeys)))
+}
+
 // String returns a string representation of the block
 func (b *Block) String() string {
 	result := fmt.Sprintf("Block #%d\nTimestamp: %s\nMerkle Root: %s\nPrevious Hash: %s\nHash: %s\nNonce: %d\n",
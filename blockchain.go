@@ -13,6 +13,13 @@ type Blockchain struct {
 	ContractRegistry *ContractRegistry
 	ChannelManager   *ChannelManager
 	BridgeManager    *BridgeManager
+	Receipts         map[string]*Receipt // Contract transaction receipts by transaction hash
+	// StateHistory holds copies of contract state after recent blocks, for calls against past blocks
+	StateHistory map[int]*ContractRegistry
+	// InternalTransfers are native coin movements made by contracts
+	InternalTransfers []*InternalTransfer
+
+	tracer *Tracer // Traces contract execution on replay copies of the chain (see tracer.go)
 }
 
 // NewBlockchain creates a new blockchain with genesis block
@@ -23,8 +30,12 @@ func NewBlockchain() *Blockchain {
 		ContractRegistry: NewContractRegistry(),
 		ChannelManager:   nil, // Will be initialized after blockchain creation
 		BridgeManager:    nil, // Will be initialized after blockchain creation
+		Receipts:         make(map[string]*Receipt),
+		StateHistory:     make(map[int]*ContractRegistry),
 	}
+	bc.ContractRegistry.onChain = true
 	bc.CreateGenesisBlock()
+	bc.recordStateHistory(0)
 	bc.ChannelManager = NewChannelManager(bc)
 	bc.BridgeManager = NewBridgeManager(bc)
 	return bc
@@ -66,9 +77,14 @@ func (bc *Blockchain) AddTransactionToMempool(tx *Transaction) error {
 		return err
 	}
 
-	// Verify signature
-	if tx.Signature != "" && !tx.Verify() {
-		return fmt.Errorf("transaction signature is invalid")
+	// Coinbases are created by block producers, never relayed
+	if tx.IsCoinbase() {
+		return fmt.Errorf("coinbase transactions cannot be added to the mempool")
+	}
+
+	// Verify signature; the result is cached so block validation does not repeat it
+	if err := signatureVerifier.Verify(tx); err != nil {
+		return err
 	}
 
 	// Add to mempool
@@ -87,10 +103,6 @@ func (bc *Blockchain) AddBlockWithReward(transactions []*Transaction, minerAddre
 		if err := bc.ValidateTransaction(tx); err != nil {
 			return err
 		}
-		// Verify signature
-		if tx.Signature != "" && !tx.Verify() {
-			return fmt.Errorf("transaction has invalid signature")
-		}
 	}
 
 	prevBlock := bc.Blocks[len(bc.Blocks)-1]
@@ -104,6 +116,11 @@ func (bc *Blockchain) AddBlockWithReward(transactions []*Transaction, minerAddre
 		allTransactions = append([]*Transaction{blockRewardTx}, allTransactions...)
 	}
 
+	// Verify signatures and coinbase rules (including the reward)
+	if err := ValidateBlockTransactions(allTransactions); err != nil {
+		return err
+	}
+
 	// Create Merkle tree from all transactions (including reward)
 	merkleTree := NewMerkleTree(allTransactions)
 	merkleRoot := merkleTree.GetRootHash()
@@ -123,24 +140,7 @@ func (bc *Blockchain) AddBlockWithReward(transactions []*Transaction, minerAddre
 	newBlock.Nonce = nonce
 	newBlock.Hash = hash
 
-	bc.Blocks = append(bc.Blocks, newBlock)
-
-	// Process contract calls
-	for _, tx := range transactions {
-		if tx.ContractData != "" && IsContractAddress(tx.To) {
-			// Parse contract call
-			call, err := ParseContractCall(tx.ContractData)
-			if err == nil {
-				// Execute contract call
-				result, err := bc.CallContract(tx.To, call.Function, call.Args, tx.From, tx.Amount)
-				if err != nil {
-					fmt.Printf("Contract call failed: %v\n", err)
-				} else {
-					fmt.Printf("Contract call result: %v\n", result)
-				}
-			}
-		}
-	}
+	bc.appendBlock(newBlock)
 
 	// Remove transactions from mempool (excluding reward transaction)
 	txHashes := make([]string, len(transactions))
@@ -161,6 +161,131 @@ func (bc *Blockchain) AddBlockWithReward(transactions []*Transaction, minerAddre
 	return nil
 }
 
+// appendBlock adds an accepted block to the chain and applies its contract transactions and storage
+// rent. Every consensus path goes through it, so contract state does not depend on how a block was produced.
+func (bc *Blockchain) appendBlock(block *Block) {
+	bc.Blocks = append(bc.Blocks, block)
+	bc.applyContractTransactions(block)
+}
+
+// applyContractTransactions executes the contract calls in a block, storing receipts and the block's log bloom
+func (bc *Blockchain) applyContractTransactions(block *Block) {
+	// Direct calls since the previous block executed at its height; include them in its recorded state
+	bc.recordStateHistory(block.Index - 1)
+
+	var blockBloom Bloom
+	logIndex := 0
+
+	for txIndex, tx := range block.Transactions {
+		call := contractCallOf(tx)
+		if call == nil {
+			continue
+		}
+
+		receipt := bc.executeContractTransaction(tx, call, block.Index, txIndex)
+		if receipt.Status {
+			for _, log := range receipt.Logs {
+				log.LogIndex = logIndex
+				logIndex++
+			}
+			fmt.Printf("Contract call result: %v\n", receipt.Result)
+		} else {
+			fmt.Printf("Contract call failed: %s\n", receipt.Error)
+		}
+
+		bloom := LogsBloom(receipt.Logs)
+		receipt.LogsBloom = bloom.Hex()
+		blockBloom.Or(bloom)
+		bc.Receipts[receipt.TxHash] = receipt
+	}
+
+	if logIndex > 0 {
+		block.LogsBloom = blockBloom.Hex()
+	}
+	bc.collectStorageRent(block.Index)
+	bc.recordStateHistory(block.Index)
+}
+
+// contractCallOf returns the contract call or deployment carried by a transaction (nil if there is none)
+func contractCallOf(tx *Transaction) *ContractCall {
+	if tx.Type != TxTypeLegacy {
+		return tx.typedContractCall()
+	}
+	if tx.ContractData == "" {
+		return nil
+	}
+	call, err := ParseContractCall(tx.ContractData)
+	if err != nil {
+		return nil
+	}
+	isDeploy := tx.To == "" && call.Function == ContractDeployFunction
+	if !isDeploy && !IsContractAddress(tx.To) {
+		return nil
+	}
+	return call
+}
+
+// executeContractTransaction executes the contract call or deployment in a block's transaction,
+// commits its native transfers and returns its receipt (without bloom or log indexes)
+func (bc *Blockchain) executeContractTransaction(tx *Transaction, call *ContractCall, blockIndex, txIndex int) *Receipt {
+	txHash := hex.EncodeToString(tx.Hash())
+	receipt := &Receipt{
+		TxHash:      txHash,
+		BlockNumber: blockIndex,
+		TxIndex:     txIndex,
+		From:        tx.From,
+		To:          tx.To,
+		Logs:        make([]*Log, 0),
+	}
+
+	// Execute contract call
+	// (the transaction itself already moved tx.Amount to the contract)
+	var ctx *ContractContext
+	var result interface{}
+	var err error
+	if tx.To == "" && call.Function == ContractDeployFunction {
+		ctx, result, err = bc.deployFromTransaction(tx, call, blockIndex)
+		if ctx != nil {
+			receipt.ContractAddress = ctx.Address
+		}
+	} else if call.Function == StorageDepositFunction {
+		ctx, result, err = bc.depositFromTransaction(tx)
+	} else {
+		ctx = newCallContext(bc, bc.ContractRegistry, tx.To, tx.From, call.Args, tx.Amount)
+		ctx.Values = call.Values
+		result, err = bc.ContractRegistry.CallContractWithContext(tx.To, call.Function, ctx)
+		if err == nil {
+			err = ctx.settleStorage()
+		}
+	}
+	if ctx != nil {
+		receipt.GasUsed = ctx.GasUsed
+	}
+	if err != nil {
+		receipt.Error = err.Error()
+		receipt.ContractAddress = ""
+		// Return the value attached to the failed call to the sender
+		if tx.Amount > 0 {
+			refund := &InternalTransfer{From: tx.To, To: tx.From, Amount: tx.Amount}
+			receipt.Transfers = []*InternalTransfer{refund}
+			bc.commitTransfers(receipt.Transfers, txHash, blockIndex)
+		}
+		return receipt
+	}
+
+	receipt.Status = true
+	receipt.Result = result
+	for _, log := range ctx.Logs {
+		log.BlockNumber = blockIndex
+		log.TxHash = txHash
+		log.TxIndex = txIndex
+	}
+	receipt.Logs = ctx.Logs
+	receipt.Transfers = ctx.Transfers
+	bc.commitTransfers(ctx.Transfers, txHash, blockIndex)
+	return receipt
+}
+
 // AddBlockFromMempool creates a block from transactions in mempool
 func (bc *Blockchain) AddBlockFromMempool(maxTransactions int) error {
 	transactions := bc.Mempool.GetTransactionsForBlock(maxTransactions)
@@ -181,6 +306,11 @@ func (bc *Blockchain) AddBlockFromMempoolWithReward(maxTransactions int, minerAd
 
 // IsValid validates the integrity of the blockchain
 func (bc *Blockchain) IsValid() bool {
+	// Verify all signatures up front across the worker pool; the per-block checks then hit the cache
+	if len(bc.Blocks) > 1 {
+		signatureVerifier.VerifyBlocks(bc.Blocks[1:])
+	}
+
 	for i := 0; i < len(bc.Blocks); i++ {
 		currentBlock := bc.Blocks[i]
 
@@ -194,16 +324,9 @@ func (bc *Blockchain) IsValid() bool {
 
 		// Validate transaction signatures (skip genesis block)
 		if i > 0 {
-			for j, tx := range currentBlock.Transactions {
-				// Skip unsigned transactions (like genesis/coinbase)
-				if tx.Signature == "" {
-					continue
-				}
-				// Full signature verification using stored public key
-				if !tx.Verify() {
-					fmt.Printf("Block #%d: Transaction #%d has invalid signature\n", currentBlock.Index, j+1)
-					return false
-				}
+			if err := ValidateBlockTransactions(currentBlock.Transactions); err != nil {
+				fmt.Printf("Block #%d: %v\n", currentBlock.Index, err)
+				return false
 			}
 		}
 
@@ -251,9 +374,63 @@ func (bc *Blockchain) DeployContract(deployer string, contractType ContractType,
 	return contract, nil
 }
 
-// CallContract calls a function on a smart contract
+// deployFromTransaction deploys the contract described by a deployment transaction
+func (bc *Blockchain) deployFromTransaction(tx *Transaction, call *ContractCall, blockIndex int) (*ContractContext, interface{}, error) {
+	if len(call.Args) < 2 {
+		return nil, nil, fmt.Errorf("deploy requires a contract type and bytecode")
+	}
+	contract, err := bc.ContractRegistry.DeployContract(tx.From, ContractType(call.Args[0]), call.Args[1], int64(blockIndex))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	ctx := newCallContext(bc, bc.ContractRegistry, contract.Address, tx.From, call.Args[2:], tx.Amount)
+	if call.Values != nil {
+		ctx.Values = call.Values[2:]
+	}
+	// The transaction sent its value to the empty address; forward it to the new contract
+	if tx.Amount > 0 {
+		ctx.Transfers = append(ctx.Transfers, &InternalTransfer{From: "", To: contract.Address, Amount: tx.Amount})
+	}
+
+	if err := bc.runConstructor(contract, ctx); err != nil {
+		return ctx, nil, err
+	}
+	if err := ctx.settleStorage(); err != nil {
+		bc.ContractRegistry.removeContract(contract.Address)
+		return ctx, nil, err
+	}
+	fmt.Printf("Contract deployed at %s\n", contract.Address)
+	return ctx, contract.Address, nil
+}
+
+// runConstructor calls the contract's constructor (if it has one), removing the contract again if it fails
+func (bc *Blockchain) runConstructor(contract *SmartContract, ctx *ContractContext) error {
+	if !contract.hasConstructor() {
+		if contract.Type == ContractTypeWASM {
+			// Still surface bytecode errors at deployment
+			if _, err := contract.wasmModule(); err != nil {
+				bc.ContractRegistry.removeContract(contract.Address)
+				return err
+			}
+		}
+		return nil
+	}
+	if _, err := contract.ExecuteWithContext(ContractConstructor, ctx); err != nil {
+		bc.ContractRegistry.removeContract(contract.Address)
+		return fmt.Errorf("constructor failed: %v", err)
+	}
+	return nil
+}
+
+// CallContract calls a function on a smart contract directly (outside of a block). The caller is
+// not authenticated, so the call is read-only: its state changes and transfers are discarded, and
+// value can only be sent with a signed contract-call transaction.
 func (bc *Blockchain) CallContract(contractAddress string, function string, args []string, caller string, value float64) (interface{}, error) {
-	return bc.ContractRegistry.CallContract(contractAddress, function, args, caller, value)
+	if value != 0 {
+		return nil, fmt.Errorf("direct contract calls cannot send value; use a signed contract-call transaction")
+	}
+	return bc.ViewContract(contractAddress, function, args, caller)
 }
 
 // GetContract retrieves a contract by address
@@ -263,16 +440,45 @@ func (bc *Blockchain) GetContract(address string) (*SmartContract, error) {
 
 // CreateBlockWithRaft creates a block using Raft consensus
 func (bc *Blockchain) CreateBlockWithRaft(transactions []*Transaction, nodeID string, nodes []string) error {
+	return bc.createBlockWithRaft(transactions, nodeID, nodes, nil)
+}
+
+// CreateBlockWithRaftValidators creates a block using Raft consensus among validator wallets,
+// which sign their messages
+func (bc *Blockchain) CreateBlockWithRaftValidators(transactions []*Transaction, nodeID string, validators []*Wallet) error {
+	nodes := make([]string, len(validators))
+	for i, validator := range validators {
+		nodes[i] = validator.Address
+	}
+	return bc.createBlockWithRaft(transactions, nodeID, nodes, validators)
+}
+
+// createBlockWithRaft runs Raft consensus on a new block; with validator wallets the nodes sign their messages
+func (bc *Blockchain) createBlockWithRaft(transactions []*Transaction, nodeID string, nodes []string, validators []*Wallet) error {
 	// Validate all transactions before adding
 	for _, tx := range transactions {
 		if err := bc.ValidateTransaction(tx); err != nil {
 			return err
 		}
 	}
+	if err := ValidateBlockTransactions(transactions); err != nil {
+		return err
+	}
 
 	// Create Raft node
 	raftNode := NewRaftNode(nodeID, nodes, bc)
 
+	// Each validator signs its own messages, including the simulated ones from the other nodes
+	raftNode.peerSigners = make(map[string]Signer)
+	for _, validator := range validators {
+		raftNode.peerSigners[validator.Address] = validator.Signer
+	}
+	if signer := raftNode.peerSigners[nodeID]; signer != nil {
+		if err := raftNode.SetSigner(signer); err != nil {
+			return err
+		}
+	}
+
 	fmt.Printf("\n=== Raft Consensus for Block #%d ===\n", len(bc.Blocks))
 	fmt.Printf("Node ID: %s\n", nodeID[:16]+"...")
 	fmt.Printf("Total nodes: %d\n", len(nodes))
@@ -291,6 +497,22 @@ func (bc *Blockchain) CreateBlockWithRaft(transactions []*Transaction, nodeID st
 		return fmt.Errorf("this node is not the leader")
 	}
 
+	// Validators with secp256k1 keys aggregate their votes, so followers can check the leader with one signature
+	if keys, keyErr := ValidatorKeys(validators); validators != nil && keyErr != nil {
+		fmt.Printf("No leader certificate: %v\n", keyErr)
+	} else if validators != nil {
+		qc, err := raftNode.voteCertificate(validators)
+		if err != nil {
+			return fmt.Errorf("leader certificate failed: %v", err)
+		}
+		if err := VerifyLeaderCertificate(qc, nodeID, raftNode.CurrentTerm, keys); err != nil {
+			return fmt.Errorf("leader certificate rejected: %v", err)
+		}
+		raftNode.LeaderCert = qc
+		fmt.Printf("Leader certificate: %d/%d votes, %d bytes (instead of %d signatures)\n",
+			qc.SignerCount(), len(validators), qc.Size(), qc.SignerCount())
+	}
+
 	// Step 2: Create the block
 	prevBlock := bc.Blocks[len(bc.Blocks)-1]
 
@@ -320,8 +542,10 @@ func (bc *Blockchain) CreateBlockWithRaft(transactions []*Transaction, nodeID st
 		return fmt.Errorf("log replication failed: %v", err)
 	}
 
-	// Step 4: Apply committed block to blockchain
-	bc.Blocks = append(bc.Blocks, newBlock)
+	// Step 4: Apply committed block to blockchain (unless committing it on the followers already did)
+	if bc.Blocks[len(bc.Blocks)-1] != newBlock {
+		bc.appendBlock(newBlock)
+	}
 
 	fmt.Printf("\nBlock #%d added to blockchain using Raft consensus!\n", newBlock.Index)
 	fmt.Printf("  Leader: %s\n", nodeID[:16]+"...")
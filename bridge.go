@@ -1,9 +1,11 @@
 package main
 
 import (
+	"crypto/ecdsa"
 	"crypto/sha256"
 	"encoding/hex"
 	"fmt"
+	"math"
 	"sync"
 	"time"
 )
@@ -28,21 +30,23 @@ const (
 
 // BridgeTransaction represents a cross-chain transfer
 type BridgeTransaction struct {
-	TxID           string          `json:"tx_id"`
-	FromChain      string          `json:"from_chain"`
-	ToChain        string          `json:"to_chain"`
-	FromAddress    string          `json:"from_address"`
-	ToAddress      string          `json:"to_address"`
-	Amount         float64         `json:"amount"`
-	Token          string          `json:"token"`
-	Status         BridgeStatus    `json:"status"`
-	Direction      BridgeDirection `json:"direction"`
-	Timestamp      time.Time       `json:"timestamp"`
-	Approvals      int             `json:"approvals"`
-	RequiredSigs   int             `json:"required_sigs"`
-	Signatures     []string        `json:"signatures"`
-	LockTxHash     string          `json:"lock_tx_hash"`     // Tx hash on source chain
-	UnlockTxHash   string          `json:"unlock_tx_hash"`   // Tx hash on destination chain
+	TxID         string                    `json:"tx_id"`
+	FromChain    string                    `json:"from_chain"`
+	ToChain      string                    `json:"to_chain"`
+	FromAddress  string                    `json:"from_address"`
+	ToAddress    string                    `json:"to_address"`
+	Amount       float64                   `json:"amount"`
+	Token        string                    `json:"token"` // Asset ID (NativeAsset for the native coin)
+	Status       BridgeStatus              `json:"status"`
+	Direction    BridgeDirection           `json:"direction"`
+	Timestamp    time.Time                 `json:"timestamp"`
+	Approvals    int                       `json:"approvals"`
+	RequiredSigs int                       `json:"required_sigs"`
+	Approvers    []string                  `json:"approvers"`             // Addresses of the validators that approved
+	QuorumCert   *QuorumCertificate        `json:"quorum_cert,omitempty"` // Approvals aggregated into one signature once enough arrive
+	LockTxHash   string                    `json:"lock_tx_hash"`          // Tx hash on source chain
+	UnlockTxHash string                    `json:"unlock_tx_hash"`        // Tx hash on destination chain
+	approvals    map[int]*ecdsa.PrivateKey // Validator index -> key, for aggregating the approvals in-process
 }
 
 // BridgeEvent represents an event emitted by the bridge
@@ -58,6 +62,7 @@ type BridgeEvent struct {
 type Validator struct {
 	ID          string  `json:"id"`
 	Address     string  `json:"address"`
+	PublicKey   string  `json:"public_key"` // Hex 64-byte secp256k1 key that verifies its approvals
 	Stake       float64 `json:"stake"`
 	IsActive    bool    `json:"is_active"`
 	VotingPower int     `json:"voting_power"`
@@ -80,6 +85,7 @@ type Bridge struct {
 	MaxAmount      float64
 	Fee            float64
 	RelayerAddress string
+	relayer        *Wallet // Key of the escrow address, which signs the bridge's release and mint transactions
 }
 
 // BridgeManager manages multiple bridges
@@ -98,7 +104,12 @@ func NewBridgeManager(bc *Blockchain) *BridgeManager {
 }
 
 // NewBridge creates a new cross-chain bridge
-func NewBridge(bridgeID string, chainA, chainB *Blockchain, chainAName, chainBName string, requiredSigs int) *Bridge {
+func NewBridge(bridgeID string, chainA, chainB *Blockchain, chainAName, chainBName string, requiredSigs int) (*Bridge, error) {
+	relayer, err := NewWallet()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create relayer key: %v", err)
+	}
+
 	bridge := &Bridge{
 		BridgeID:     bridgeID,
 		ChainA:       chainA,
@@ -113,20 +124,32 @@ func NewBridge(bridgeID string, chainA, chainB *Blockchain, chainAName, chainBNa
 		MinAmount:    0.1,
 		MaxAmount:    10000.0,
 		Fee:          0.01, // 1% bridge fee
-		RelayerAddress: "relayer_" + bridgeID,
+		RelayerAddress: relayer.Address,
+		relayer:        relayer,
 	}
 
-	return bridge
+	return bridge, nil
 }
 
-// AddValidator adds a validator to the bridge
-func (b *Bridge) AddValidator(id, address string, stake float64, votingPower int) {
+// AddValidator adds a validator to the bridge; its secp256k1 key signs its approvals
+func (b *Bridge) AddValidator(id string, wallet *Wallet, stake float64, votingPower int) error {
+	keys, err := ValidatorKeys([]*Wallet{wallet})
+	if err != nil {
+		return err
+	}
+
 	b.mu.Lock()
 	defer b.mu.Unlock()
 
+	for _, existing := range b.Validators {
+		if existing.Address == wallet.Address {
+			return fmt.Errorf("validator %s already added", truncateAddress(wallet.Address))
+		}
+	}
 	validator := &Validator{
 		ID:          id,
-		Address:     address,
+		Address:     wallet.Address,
+		PublicKey:   hex.EncodeToString(keys[0]),
 		Stake:       stake,
 		IsActive:    true,
 		VotingPower: votingPower,
@@ -135,17 +158,19 @@ func (b *Bridge) AddValidator(id, address string, stake float64, votingPower int
 	b.Validators = append(b.Validators, validator)
 
 	fmt.Printf("\n[Bridge Validator Added]\n")
-	fmt.Printf("  Bridge: %s\n", b.BridgeID[:16]+"...")
-	fmt.Printf("  Validator ID: %s\n", validator.ID[:16]+"...")
-	fmt.Printf("  Address: %s\n", validator.Address[:16]+"...")
+	fmt.Printf("  Bridge: %s\n", truncateAddress(b.BridgeID))
+	fmt.Printf("  Validator ID: %s\n", truncateAddress(validator.ID))
+	fmt.Printf("  Address: %s\n", truncateAddress(validator.Address))
 	fmt.Printf("  Stake: %.2f\n", validator.Stake)
 	fmt.Printf("  Voting Power: %d\n", validator.VotingPower)
+	return nil
 }
 
-// LockFunds locks funds on the source chain (Chain A -> Chain B)
-func (b *Bridge) LockFunds(fromAddress, toAddress string, amount float64, token string) (*BridgeTransaction, error) {
+// LockFunds locks funds on the source chain (Chain A -> Chain B); the sender's wallet signs the lock transaction
+func (b *Bridge) LockFunds(from *Wallet, toAddress string, amount float64, token string) (*BridgeTransaction, error) {
 	b.mu.Lock()
 	defer b.mu.Unlock()
+	fromAddress := from.Address
 
 	// Validate amount
 	if amount < b.MinAmount {
@@ -160,14 +185,17 @@ func (b *Bridge) LockFunds(fromAddress, toAddress string, amount float64, token
 	totalAmount := amount + fee
 
 	// Check balance on Chain A
-	balance := b.ChainA.GetBalance(fromAddress)
+	balance := b.ChainA.GetAssetBalance(fromAddress, token)
 	if balance < totalAmount {
-		return nil, fmt.Errorf("insufficient balance on %s: %.2f < %.2f", b.ChainAName, balance, totalAmount)
+		return nil, fmt.Errorf("insufficient %s balance on %s: %.2f < %.2f", assetSymbol(token), b.ChainAName, balance, totalAmount)
 	}
 
-	// Lock funds on Chain A (create lock transaction)
+	// Lock funds (and the bridge fee) on Chain A
 	// In a real implementation, this would call a bridge smart contract
-	lockTxHash := generateLockTxHash(fromAddress, toAddress, amount, time.Now())
+	lockTxHash, err := b.lockFunds(b.ChainA, from, totalAmount, token, b.ChainBName, toAddress)
+	if err != nil {
+		return nil, fmt.Errorf("failed to lock funds on %s: %v", b.ChainAName, err)
+	}
 
 	// Create bridge transaction
 	txID := generateBridgeTxID(lockTxHash, b.ChainAName, b.ChainBName)
@@ -185,22 +213,22 @@ func (b *Bridge) LockFunds(fromAddress, toAddress string, amount float64, token
 		Timestamp:    time.Now(),
 		Approvals:    0,
 		RequiredSigs: b.RequiredSigs,
-		Signatures:   make([]string, 0),
+		Approvers:    make([]string, 0),
 		LockTxHash:   lockTxHash,
 	}
 
 	b.PendingTxs[txID] = bridgeTx
 
 	// Emit lock event
-	b.emitEvent("lock", b.ChainAName, lockTxHash, fmt.Sprintf("%s->%s: %.4f %s", b.ChainAName, b.ChainBName, amount, token))
+	b.emitEvent("lock", b.ChainAName, lockTxHash, fmt.Sprintf("%s->%s: %.4f %s", b.ChainAName, b.ChainBName, amount, assetSymbol(token)))
 
 	fmt.Printf("\n=== Cross-Chain Bridge: Lock Funds ===\n")
-	fmt.Printf("Bridge: %s\n", b.BridgeID[:16]+"...")
+	fmt.Printf("Bridge: %s\n", truncateAddress(b.BridgeID))
 	fmt.Printf("Direction: %s → %s\n", b.ChainAName, b.ChainBName)
 	fmt.Printf("From: %s\n", fromAddress[:16]+"...")
 	fmt.Printf("To: %s\n", toAddress[:16]+"...")
-	fmt.Printf("Amount: %.4f %s\n", amount, token)
-	fmt.Printf("Fee: %.4f %s (%.1f%%)\n", fee, token, b.Fee*100)
+	fmt.Printf("Amount: %.4f %s\n", amount, assetSymbol(token))
+	fmt.Printf("Fee: %.4f %s (%.1f%%)\n", fee, assetSymbol(token), b.Fee*100)
 	fmt.Printf("Lock Tx Hash: %s\n", lockTxHash[:16]+"...")
 	fmt.Printf("Status: %s\n", bridgeTx.Status)
 	fmt.Printf("Required Signatures: %d/%d\n", 0, b.RequiredSigs)
@@ -217,15 +245,22 @@ func (b *Bridge) UnlockFunds(bridgeTx *BridgeTransaction) error {
 	if bridgeTx.Status != BridgeStatusApproved {
 		return fmt.Errorf("transaction not approved: %s", bridgeTx.Status)
 	}
+	if err := b.verifyApprovals(bridgeTx); err != nil {
+		return err
+	}
 
-	// Unlock/mint funds on Chain B
-	// In a real implementation, this would call a bridge smart contract on Chain B
-	unlockTxHash := generateUnlockTxHash(bridgeTx.ToAddress, bridgeTx.Amount, time.Now())
-	bridgeTx.UnlockTxHash = unlockTxHash
+	destination := b.ChainB
+	if bridgeTx.Direction == BridgeDirectionBToA {
+		destination = b.ChainA
+	}
 
-	// Add coinbase transaction to Chain B
-	coinbaseTx := NewTransaction(b.RelayerAddress, bridgeTx.ToAddress, bridgeTx.Amount)
-	b.ChainB.AddBlock([]*Transaction{coinbaseTx})
+	// Unlock/mint funds on the destination chain
+	// In a real implementation, this would call a bridge smart contract on the destination chain
+	unlockTxHash, err := b.releaseFunds(destination, bridgeTx.ToAddress, bridgeTx.Amount, bridgeTx.Token)
+	if err != nil {
+		return fmt.Errorf("failed to unlock funds on %s: %v", bridgeTx.ToChain, err)
+	}
+	bridgeTx.UnlockTxHash = unlockTxHash
 
 	// Move to completed
 	delete(b.PendingTxs, bridgeTx.TxID)
@@ -233,21 +268,22 @@ func (b *Bridge) UnlockFunds(bridgeTx *BridgeTransaction) error {
 	b.CompletedTxs[bridgeTx.TxID] = bridgeTx
 
 	// Emit unlock event
-	b.emitEvent("unlock", b.ChainBName, unlockTxHash, fmt.Sprintf("Minted %.4f %s to %s", bridgeTx.Amount, bridgeTx.Token, bridgeTx.ToAddress[:16]+"..."))
+	b.emitEvent("unlock", bridgeTx.ToChain, unlockTxHash, fmt.Sprintf("Released %.4f %s to %s", bridgeTx.Amount, assetSymbol(bridgeTx.Token), bridgeTx.ToAddress[:16]+"..."))
 
 	fmt.Printf("\n=== Cross-Chain Bridge: Unlock Funds ===\n")
-	fmt.Printf("Bridge: %s\n", b.BridgeID[:16]+"...")
-	fmt.Printf("Direction: %s → %s\n", b.ChainAName, b.ChainBName)
+	fmt.Printf("Bridge: %s\n", truncateAddress(b.BridgeID))
+	fmt.Printf("Direction: %s → %s\n", bridgeTx.FromChain, bridgeTx.ToChain)
 	fmt.Printf("To: %s\n", bridgeTx.ToAddress[:16]+"...")
-	fmt.Printf("Amount: %.4f %s\n", bridgeTx.Amount, bridgeTx.Token)
+	fmt.Printf("Amount: %.4f %s\n", bridgeTx.Amount, assetSymbol(bridgeTx.Token))
 	fmt.Printf("Unlock Tx Hash: %s\n", unlockTxHash[:16]+"...")
-	fmt.Printf("✓ Funds successfully transferred to %s\n", b.ChainBName)
+	fmt.Printf("✓ Funds successfully transferred to %s\n", bridgeTx.ToChain)
 
 	return nil
 }
 
-// ApproveTransaction approves a bridge transaction (by validator)
-func (b *Bridge) ApproveTransaction(txID, validatorID string, signature string) error {
+// ApproveTransaction approves a bridge transaction with a validator's key. Once enough validators
+// approve, their approvals are aggregated into one quorum certificate that UnlockFunds checks.
+func (b *Bridge) ApproveTransaction(txID string, validator *Wallet) error {
 	b.mu.Lock()
 	defer b.mu.Unlock()
 
@@ -255,49 +291,110 @@ func (b *Bridge) ApproveTransaction(txID, validatorID string, signature string)
 	if !exists {
 		return fmt.Errorf("transaction not found: %s", txID)
 	}
+	if bridgeTx.Status != BridgeStatusPending {
+		return fmt.Errorf("transaction is %s", bridgeTx.Status)
+	}
 
-	// Check if already approved by this validator
-	for _, sig := range bridgeTx.Signatures {
-		if sig == signature {
-			return fmt.Errorf("already approved by validator")
+	index := -1
+	for i, v := range b.Validators {
+		if v.Address == validator.Address && v.IsActive {
+			index = i
 		}
 	}
+	if index < 0 {
+		return fmt.Errorf("%s is not an active bridge validator", truncateAddress(validator.Address))
+	}
+
+	// Check if already approved by this validator
+	if bridgeTx.approvals == nil {
+		bridgeTx.approvals = make(map[int]*ecdsa.PrivateKey)
+	}
+	if _, approved := bridgeTx.approvals[index]; approved {
+		return fmt.Errorf("already approved by validator")
+	}
 
-	// Add signature
-	bridgeTx.Signatures = append(bridgeTx.Signatures, signature)
+	// Record the approval
+	bridgeTx.approvals[index] = validator.PrivateKey
+	bridgeTx.Approvers = append(bridgeTx.Approvers, validator.Address)
 	bridgeTx.Approvals++
 
 	fmt.Printf("\n[Bridge Transaction Approved]\n")
 	fmt.Printf("  Tx ID: %s\n", txID[:16]+"...")
-	fmt.Printf("  Validator: %s\n", validatorID[:16]+"...")
+	fmt.Printf("  Validator: %s\n", truncateAddress(b.Validators[index].ID))
 	fmt.Printf("  Approvals: %d/%d\n", bridgeTx.Approvals, bridgeTx.RequiredSigs)
 
 	// Check if we have enough approvals
 	if bridgeTx.Approvals >= bridgeTx.RequiredSigs {
+		keys, err := b.validatorKeys()
+		if err != nil {
+			return err
+		}
+		qc, err := BuildQuorumCertificate(QuorumDomainBridgeApproval, txID, 0, keys, bridgeTx.approvals)
+		if err != nil {
+			return fmt.Errorf("quorum certificate failed: %v", err)
+		}
+		bridgeTx.QuorumCert = qc
 		bridgeTx.Status = BridgeStatusApproved
 		fmt.Printf("  ✓ Transaction approved by validators!\n")
+		fmt.Printf("  Quorum certificate: %d signers, %d bytes\n", qc.SignerCount(), qc.Size())
 	}
 
 	// Emit approval event
-	b.emitEvent("approval", b.ChainAName, txID, fmt.Sprintf("Validator %s approved", validatorID[:16]+"..."))
+	b.emitEvent("approval", b.ChainAName, txID, fmt.Sprintf("Validator %s approved", truncateAddress(b.Validators[index].ID)))
+
+	return nil
+}
+
+// validatorKeys decodes the public keys of the bridge validators, in the order certificates use
+func (b *Bridge) validatorKeys() ([][]byte, error) {
+	keys := make([][]byte, len(b.Validators))
+	for i, validator := range b.Validators {
+		key, err := hex.DecodeString(validator.PublicKey)
+		if err != nil {
+			return nil, fmt.Errorf("validator %s has an invalid public key", truncateAddress(validator.ID))
+		}
+		keys[i] = key
+	}
+	return keys, nil
+}
 
+// verifyApprovals checks that the transaction's quorum certificate holds the required approvals
+func (b *Bridge) verifyApprovals(bridgeTx *BridgeTransaction) error {
+	qc := bridgeTx.QuorumCert
+	if qc == nil {
+		return fmt.Errorf("transaction has no quorum certificate")
+	}
+	if qc.Domain != QuorumDomainBridgeApproval || qc.Subject != bridgeTx.TxID {
+		return fmt.Errorf("quorum certificate does not approve transaction %s", truncateAddress(bridgeTx.TxID))
+	}
+	keys, err := b.validatorKeys()
+	if err != nil {
+		return err
+	}
+	if err := qc.Verify(keys, b.RequiredSigs); err != nil {
+		return fmt.Errorf("invalid approvals: %v", err)
+	}
 	return nil
 }
 
-// ReverseTransfer reverses direction (Chain B -> Chain A)
-func (b *Bridge) ReverseTransfer(fromAddress, toAddress string, amount float64, token string) (*BridgeTransaction, error) {
+// ReverseTransfer reverses direction (Chain B -> Chain A); the sender's wallet signs the lock transaction
+func (b *Bridge) ReverseTransfer(from *Wallet, toAddress string, amount float64, token string) (*BridgeTransaction, error) {
 	b.mu.Lock()
 	defer b.mu.Unlock()
+	fromAddress := from.Address
 
 	// Lock funds on Chain B
-	balance := b.ChainB.GetBalance(fromAddress)
+	balance := b.ChainB.GetAssetBalance(fromAddress, token)
 	totalAmount := amount + (amount * b.Fee)
 
 	if balance < totalAmount {
-		return nil, fmt.Errorf("insufficient balance on %s: %.2f < %.2f", b.ChainBName, balance, totalAmount)
+		return nil, fmt.Errorf("insufficient %s balance on %s: %.2f < %.2f", assetSymbol(token), b.ChainBName, balance, totalAmount)
 	}
 
-	lockTxHash := generateLockTxHash(fromAddress, toAddress, amount, time.Now())
+	lockTxHash, err := b.lockFunds(b.ChainB, from, totalAmount, token, b.ChainAName, toAddress)
+	if err != nil {
+		return nil, fmt.Errorf("failed to lock funds on %s: %v", b.ChainBName, err)
+	}
 	txID := generateBridgeTxID(lockTxHash, b.ChainBName, b.ChainAName)
 
 	bridgeTx := &BridgeTransaction{
@@ -313,20 +410,20 @@ func (b *Bridge) ReverseTransfer(fromAddress, toAddress string, amount float64,
 		Timestamp:    time.Now(),
 		Approvals:    0,
 		RequiredSigs: b.RequiredSigs,
-		Signatures:   make([]string, 0),
+		Approvers:    make([]string, 0),
 		LockTxHash:   lockTxHash,
 	}
 
 	b.PendingTxs[txID] = bridgeTx
 
-	b.emitEvent("lock", b.ChainBName, lockTxHash, fmt.Sprintf("%s->%s: %.4f %s", b.ChainBName, b.ChainAName, amount, token))
+	b.emitEvent("lock", b.ChainBName, lockTxHash, fmt.Sprintf("%s->%s: %.4f %s", b.ChainBName, b.ChainAName, amount, assetSymbol(token)))
 
 	fmt.Printf("\n=== Cross-Chain Bridge: Reverse Transfer ===\n")
-	fmt.Printf("Bridge: %s\n", b.BridgeID[:16]+"...")
+	fmt.Printf("Bridge: %s\n", truncateAddress(b.BridgeID))
 	fmt.Printf("Direction: %s → %s (REVERSE)\n", b.ChainBName, b.ChainAName)
 	fmt.Printf("From: %s\n", fromAddress[:16]+"...")
 	fmt.Printf("To: %s\n", toAddress[:16]+"...")
-	fmt.Printf("Amount: %.4f %s\n", amount, token)
+	fmt.Printf("Amount: %.4f %s\n", amount, assetSymbol(token))
 
 	return bridgeTx, nil
 }
@@ -348,7 +445,7 @@ func (b *Bridge) GetBridgeStatistics() map[string]interface{} {
 	}
 
 	return map[string]interface{}{
-		"bridge_id":         b.BridgeID[:16] + "...",
+		"bridge_id":         truncateAddress(b.BridgeID),
 		"chain_a":           b.ChainAName,
 		"chain_b":           b.ChainBName,
 		"validators":        len(b.Validators),
@@ -380,6 +477,64 @@ func (b *Bridge) GetTransaction(txID string) (*BridgeTransaction, error) {
 	return nil, fmt.Errorf("transaction not found: %s", txID)
 }
 
+// lockFunds moves an amount of an asset from a sender into the bridge's escrow (its relayer address) on a chain,
+// with a bridge-lock transaction recording the target chain and recipient
+func (b *Bridge) lockFunds(chain *Blockchain, from *Wallet, amount float64, asset, targetChain, recipient string) (string, error) {
+	lockTx, err := NewTypedTransaction(from.Address, b.RelayerAddress, amount, 0, &BridgeLockPayload{TargetChain: targetChain, Recipient: canonicalAddress(recipient)})
+	if err != nil {
+		return "", err
+	}
+	lockTx.Asset = asset
+	if err := from.SignTransaction(lockTx); err != nil {
+		return "", err
+	}
+	if err := chain.AddBlock([]*Transaction{lockTx}); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(lockTx.Hash()), nil
+}
+
+// releaseFunds pays an amount of an asset out of the bridge's escrow on a chain. Funds locked there by
+// transfers in the other direction are released first; any shortfall is minted into the escrow
+// (new native coins, or an issuance of the named asset by the relayer).
+func (b *Bridge) releaseFunds(chain *Blockchain, to string, amount float64, asset string) (string, error) {
+	if shortfall := amount - chain.GetAssetBalance(b.RelayerAddress, asset); shortfall > 0 {
+		if err := b.mint(chain, shortfall, asset); err != nil {
+			return "", err
+		}
+	}
+
+	releaseTx := NewAssetTransferTransaction(b.RelayerAddress, to, asset, amount, 0)
+	if err := b.relayer.SignTransaction(releaseTx); err != nil {
+		return "", err
+	}
+	if err := chain.AddBlock([]*Transaction{releaseTx}); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(releaseTx.Hash()), nil
+}
+
+// mint creates an amount of an asset in the bridge's escrow. Named assets are issued by the relayer;
+// native coins are created by coinbases, which are capped at the block reward, so large amounts
+// are minted over several blocks.
+func (b *Bridge) mint(chain *Blockchain, amount float64, asset string) error {
+	if asset != NativeAsset {
+		issueTx := NewAssetIssueTransaction(b.RelayerAddress, asset, amount, 0)
+		if err := b.relayer.SignTransaction(issueTx); err != nil {
+			return err
+		}
+		return chain.AddBlock([]*Transaction{issueTx})
+	}
+	for amount > 0 {
+		minted := math.Min(amount, BlockReward)
+		if err := chain.AddBlock([]*Transaction{chain.AddCoinbaseTransaction(b.RelayerAddress, minted)}); err != nil {
+			return err
+		}
+		amount -= minted
+	}
+	return nil
+}
+
 // emitEvent emits a bridge event
 func (b *Bridge) emitEvent(eventType, chain, txHash, data string) {
 	event := &BridgeEvent{
@@ -400,15 +555,3 @@ func generateBridgeTxID(lockTxHash, fromChain, toChain string) string {
 	hash := sha256.Sum256([]byte(data))
 	return hex.EncodeToString(hash[:])
 }
-
-func generateLockTxHash(from, to string, amount float64, timestamp time.Time) string {
-	data := fmt.Sprintf("lock:%s:%s:%.4f:%d", from, to, amount, timestamp.UnixNano())
-	hash := sha256.Sum256([]byte(data))
-	return "L" + hex.EncodeToString(hash[:])[:40]
-}
-
-func generateUnlockTxHash(to string, amount float64, timestamp time.Time) string {
-	data := fmt.Sprintf("unlock:%s:%.4f:%d", to, amount, timestamp.UnixNano())
-	hash := sha256.Sum256([]byte(data))
-	return "U" + hex.EncodeToString(hash[:])[:40]
-}
@@ -0,0 +1,281 @@
+package main
+
+import (
+	"fmt"
+)
+
+// MaxCallDepth is the maximum nesting of contract-to-contract calls
+const MaxCallDepth = 64
+
+// InternalTransfer records native coins moved by a contract during execution
+type InternalTransfer struct {
+	From        string  `json:"from"`
+	To          string  `json:"to"`
+	Amount      float64 `json:"amount"`
+	TxHash      string  `json:"transactionHash,omitempty"` // Transaction that triggered the transfer (empty for direct calls)
+	BlockNumber int     `json:"blockNumber"`
+}
+
+// contractSnapshot is a copy of a contract's state taken before a call frame modified it
+type contractSnapshot struct {
+	contract *SmartContract
+	state    map[string]interface{}
+}
+
+// newCallContext creates the context for a top-level contract call
+func newCallContext(chain *Blockchain, registry *ContractRegistry, contractAddress, caller string, args []string, value float64) *ContractContext {
+	ctx := &ContractContext{
+		Caller:      caller,
+		Value:       value,
+		Args:        args,
+		Address:     contractAddress,
+		Origin:      caller,
+		BlockNumber: -1,
+		chain:       chain,
+		registry:    registry,
+	}
+	if chain != nil {
+		ctx.setBlock(len(chain.Blocks) - 1)
+		ctx.tracer = chain.tracer
+	}
+	return ctx
+}
+
+// setBlock sets the block height and time the call executes at
+func (ctx *ContractContext) setBlock(blockNumber int) {
+	ctx.BlockNumber = blockNumber
+	ctx.Timestamp = 0
+	if ctx.chain != nil && blockNumber >= 0 && blockNumber < len(ctx.chain.Blocks) {
+		ctx.Timestamp = ctx.chain.Blocks[blockNumber].Timestamp.Unix()
+	}
+}
+
+// Call invokes a function on another contract from the executing contract.
+// The call runs in its own revert scope: if it fails, its state changes, logs
+// and transfers are discarded and the error is returned to the caller.
+func (ctx *ContractContext) Call(to, function string, args []string, value float64) (interface{}, error) {
+	if ctx.registry == nil {
+		return nil, fmt.Errorf("contract calls are not available in this context")
+	}
+	if ctx.Depth+1 > MaxCallDepth {
+		return nil, fmt.Errorf("max call depth exceeded (%d)", MaxCallDepth)
+	}
+
+	callee, err := ctx.registry.GetContract(to)
+	if err != nil {
+		return nil, err
+	}
+
+	child := &ContractContext{
+		Caller:      ctx.Address,
+		Value:       value,
+		Args:        args,
+		Address:     to,
+		Origin:      ctx.Origin,
+		Depth:       ctx.Depth + 1,
+		GasLimit:    ctx.GasLimit,
+		BlockNumber: ctx.BlockNumber,
+		Timestamp:   ctx.Timestamp,
+		chain:       ctx.chain,
+		registry:    ctx.registry,
+		parent:      ctx,
+		tracer:      ctx.tracer,
+	}
+
+	// Value moves with the call and is reverted with it
+	if value > 0 {
+		if err := child.transfer(ctx.Address, to, value); err != nil {
+			return nil, err
+		}
+	}
+
+	result, err := callee.ExecuteWithContext(function, child)
+	ctx.GasUsed += child.GasUsed
+	ctx.tracer.resume()
+	if err != nil {
+		return nil, fmt.Errorf("call to %s.%s failed: %v", truncateAddress(to), function, err)
+	}
+
+	ctx.merge(child)
+	return result, nil
+}
+
+// Transfer sends native coins from the executing contract to an address
+func (ctx *ContractContext) Transfer(to string, amount float64) error {
+	if err := ctx.transfer(ctx.Address, to, amount); err != nil {
+		return err
+	}
+	ctx.tracer.transfer(ctx, to, amount)
+	return nil
+}
+
+// executeStoredCall marks a stored transaction or proposal executed, then sends its value
+// and runs its call data; marking it first stops a reentrant call from executing it twice
+func (sc *SmartContract) executeStoredCall(ctx *ContractContext, entry map[string]interface{}, to string, value float64, data string) (interface{}, error) {
+	sc.mu.Lock()
+	entry["executed"] = true
+	sc.mu.Unlock()
+
+	if data == "" {
+		if value > 0 {
+			return nil, ctx.Transfer(to, value)
+		}
+		return nil, nil
+	}
+	call, err := ParseContractCall(data)
+	if err != nil {
+		return nil, err
+	}
+	return ctx.Call(to, call.Function, call.Args, value)
+}
+
+// BalanceOf returns the native balance of an address including transfers pending in this call chain
+func (ctx *ContractContext) BalanceOf(address string) float64 {
+	balance := 0.0
+	if ctx.chain != nil {
+		balance = ctx.chain.GetBalanceAt(address, ctx.BlockNumber)
+	}
+	for frame := ctx; frame != nil; frame = frame.parent {
+		for _, t := range frame.Transfers {
+			if t.From == address {
+				balance -= t.Amount
+			}
+			if t.To == address {
+				balance += t.Amount
+			}
+		}
+	}
+	return balance
+}
+
+// transfer records a native transfer after checking the sender's balance
+func (ctx *ContractContext) transfer(from, to string, amount float64) error {
+	if ctx.chain == nil {
+		return fmt.Errorf("native transfers are not available in this context")
+	}
+	if amount <= 0 {
+		return fmt.Errorf("transfer amount must be greater than zero")
+	}
+	if balance := ctx.spendable(from); balance < amount {
+		return fmt.Errorf("insufficient balance for transfer: %.2f < %.2f", balance, amount)
+	}
+	ctx.Transfers = append(ctx.Transfers, &InternalTransfer{
+		From:   from,
+		To:     to,
+		Amount: amount,
+	})
+	return nil
+}
+
+// snapshot saves a contract's state the first time this frame touches it
+func (ctx *ContractContext) snapshot(sc *SmartContract) {
+	for _, s := range ctx.snapshots {
+		if s.contract == sc {
+			return
+		}
+	}
+	sc.mu.RLock()
+	state := cloneState(sc.State)
+	sc.mu.RUnlock()
+	ctx.snapshots = append(ctx.snapshots, contractSnapshot{contract: sc, state: state})
+}
+
+// revert restores every contract touched by this frame and drops its logs and transfers
+func (ctx *ContractContext) revert() {
+	for i := len(ctx.snapshots) - 1; i >= 0; i-- {
+		s := ctx.snapshots[i]
+		s.contract.mu.Lock()
+		s.contract.State = s.state
+		s.contract.mu.Unlock()
+	}
+	ctx.snapshots = nil
+	ctx.Logs = nil
+	ctx.Transfers = nil
+}
+
+// merge folds a successful child frame into its parent so a later revert of the parent undoes it too
+func (ctx *ContractContext) merge(child *ContractContext) {
+	ctx.Logs = append(ctx.Logs, child.Logs...)
+	ctx.Transfers = append(ctx.Transfers, child.Transfers...)
+	for _, s := range child.snapshots {
+		found := false
+		for _, existing := range ctx.snapshots {
+			if existing.contract == s.contract {
+				found = true
+				break
+			}
+		}
+		if !found {
+			ctx.snapshots = append(ctx.snapshots, s)
+		}
+	}
+}
+
+// isActive reports whether a contract is already executing further up the call stack
+func (ctx *ContractContext) isActive(address string) bool {
+	for frame := ctx.parent; frame != nil; frame = frame.parent {
+		if frame.Address == address {
+			return true
+		}
+	}
+	return false
+}
+
+// cloneState deep-copies contract state so it can be restored later
+func cloneState(state map[string]interface{}) map[string]interface{} {
+	clone := make(map[string]interface{}, len(state))
+	for key, value := range state {
+		clone[key] = cloneStateValue(value)
+	}
+	return clone
+}
+
+func cloneStateValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]float64:
+		m := make(map[string]float64, len(v))
+		for k, val := range v {
+			m[k] = val
+		}
+		return m
+	case map[string]int:
+		m := make(map[string]int, len(v))
+		for k, val := range v {
+			m[k] = val
+		}
+		return m
+	case map[string]bool:
+		m := make(map[string]bool, len(v))
+		for k, val := range v {
+			m[k] = val
+		}
+		return m
+	case map[string]string:
+		m := make(map[string]string, len(v))
+		for k, val := range v {
+			m[k] = val
+		}
+		return m
+	case map[string]interface{}:
+		return cloneState(v)
+	case []string:
+		return append([]string(nil), v...)
+	case []interface{}:
+		list := make([]interface{}, len(v))
+		for i, val := range v {
+			list[i] = cloneStateValue(val)
+		}
+		return list
+	default:
+		return v
+	}
+}
+
+// commitTransfers stores the native transfers of a successful top-level call on the chain
+func (bc *Blockchain) commitTransfers(transfers []*InternalTransfer, txHash string, blockNumber int) {
+	for _, t := range transfers {
+		t.TxHash = txHash
+		t.BlockNumber = blockNumber
+		bc.InternalTransfers = append(bc.InternalTransfers, t)
+	}
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"crypto/ecdsa"
+	"encoding/hex"
+	"math/big"
+	"testing"
+)
+
+// TestKeccak256 checks the hash against published Keccak-256 (not SHA3-256) digests
+func TestKeccak256(t *testing.T) {
+	vectors := map[string]string{
+		"":    "c5d2460186f7233c927e7db2dcc703c0e500b653ca82273b7bfad8045d85a470",
+		"abc": "4e03657aea45a94fc7d47ba826c8d667c0d1e6e33a64a036ec44f58fa12d6c45",
+	}
+	for input, want := range vectors {
+		if got := hex.EncodeToString(Keccak256([]byte(input))); got != want {
+			t.Errorf("Keccak256(%q) = %s, want %s", input, got, want)
+		}
+	}
+}
+
+// TestSecp256k1Recovery derives published Ethereum addresses from their private keys,
+// then checks that a recoverable signature recovers the same key
+func TestSecp256k1Recovery(t *testing.T) {
+	vectors := map[string]string{
+		"0000000000000000000000000000000000000000000000000000000000000001": "7e5f4552091a69125d5dfcb7b8c2659029395bdf",
+		"4c0883a69102937d6231471b5dbb6204fe5129617082792ae468d01a3f362318": "2c7536e3605d9c16a7a3d7b1898e529396a65c23",
+	}
+	for keyHex, want := range vectors {
+		d, _ := new(big.Int).SetString(keyHex, 16)
+		key := secp256k1PrivateKey(d)
+		if got := PublicKeyToEthAddress(&key.PublicKey); got != want {
+			t.Fatalf("address of key %s = %s, want %s", keyHex, got, want)
+		}
+
+		hash := Keccak256([]byte("learn-blockchain"))
+		signature, err := SignRecoverable(key, hash)
+		if err != nil {
+			t.Fatalf("SignRecoverable: %v", err)
+		}
+		recovered, err := RecoverPublicKey(hash, signature)
+		if err != nil {
+			t.Fatalf("RecoverPublicKey: %v", err)
+		}
+		if got := PublicKeyToEthAddress(recovered); got != want {
+			t.Errorf("recovered address %s, want %s", got, want)
+		}
+		if !VerifySecp256k1(&key.PublicKey, hash, signature) {
+			t.Errorf("signature by key %s does not verify", keyHex)
+		}
+		if VerifySecp256k1(&key.PublicKey, Keccak256([]byte("other message")), signature) {
+			t.Errorf("signature by key %s verifies a different message", keyHex)
+		}
+	}
+}
+
+// TestMuSig signs with two keys and checks the aggregate signature verifies only for its message
+func TestMuSig(t *testing.T) {
+	keys := make([]*ecdsa.PrivateKey, 2)
+	publicKeys := make([][]byte, len(keys))
+	for i := range keys {
+		keys[i] = secp256k1PrivateKey(big.NewInt(int64(i + 2)))
+		publicKeys[i] = publicKeyBytes(&keys[i].PublicKey)
+	}
+	message := []byte("learn-blockchain")
+
+	session, err := NewMuSigSession(publicKeys, message)
+	if err != nil {
+		t.Fatalf("NewMuSigSession: %v", err)
+	}
+	nonces := make([]*MuSigNonce, len(keys))
+	for i := range keys {
+		if nonces[i], err = NewMuSigNonce(); err != nil {
+			t.Fatalf("NewMuSigNonce: %v", err)
+		}
+		if err := session.AddNonce(i, nonces[i].Public); err != nil {
+			t.Fatalf("AddNonce: %v", err)
+		}
+	}
+	for i, key := range keys {
+		partial, err := session.Sign(i, key, nonces[i])
+		if err != nil {
+			t.Fatalf("Sign: %v", err)
+		}
+		if err := session.AddPartialSignature(i, partial); err != nil {
+			t.Fatalf("AddPartialSignature: %v", err)
+		}
+	}
+	signature, err := session.Signature()
+	if err != nil {
+		t.Fatalf("Signature: %v", err)
+	}
+
+	if !VerifyMuSig(publicKeys, message, signature) {
+		t.Error("aggregate signature does not verify")
+	}
+	if VerifyMuSig(publicKeys, []byte("other message"), signature) {
+		t.Error("aggregate signature verifies a different message")
+	}
+	if VerifyMuSig(publicKeys[:1], message, signature) {
+		t.Error("aggregate signature verifies for a subset of the keys")
+	}
+}
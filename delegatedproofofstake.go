@@ -147,9 +147,9 @@ func (bc *Blockchain) CreateBlockWithDPoS(transactions []*Transaction, validator
 		if err := bc.ValidateTransaction(tx); err != nil {
 			return err
 		}
-		if tx.Signature != "" && !tx.Verify() {
-			return fmt.Errorf("transaction has invalid signature")
-		}
+	}
+	if err := ValidateBlockTransactions(transactions); err != nil {
+		return err
 	}
 
 	prevBlock := bc.Blocks[len(bc.Blocks)-1]
@@ -177,7 +177,7 @@ func (bc *Blockchain) CreateBlockWithDPoS(transactions []*Transaction, validator
 	// Calculate hash (DPoS doesn't require mining, just hash)
 	newBlock.Hash = newBlock.CalculateHash()
 
-	bc.Blocks = append(bc.Blocks, newBlock)
+	bc.appendBlock(newBlock)
 
 	// Remove transactions from mempool
 	txHashes := make([]string, 0)
@@ -0,0 +1,430 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// GovernanceNativeWeight weighs governance votes by native coin balance (stake) instead of a token
+const GovernanceNativeWeight = "native"
+
+// Vote options accepted by governance contracts
+const (
+	VoteFor     = "for"
+	VoteAgainst = "against"
+	VoteAbstain = "abstain"
+)
+
+// Proposal states reported by governance contracts
+const (
+	ProposalPending   = "pending"
+	ProposalActive    = "active"
+	ProposalDefeated  = "defeated"
+	ProposalSucceeded = "succeeded"
+	ProposalExecuted  = "executed"
+)
+
+// Event signatures emitted by governance contracts
+const (
+	EventGovernanceProposal = "GovernanceProposal(address,uint256)"
+	EventGovernanceVote     = "GovernanceVote(address,uint256)"
+	EventDelegateChanged    = "DelegateChanged(address,address)"
+	EventProposalExecuted   = "ProposalExecuted(uint256)"
+)
+
+// executeGovernance executes a token-weighted governance contract.
+// Votes are weighted by a token contract balance or native balance as of the block before voting
+// opens, may be delegated, and are cast between a proposal's start and end blocks. A proposal
+// passes when the votes cast reach the quorum and at least threshold percent of the for/against
+// votes are for it; anyone can then execute it as a native transfer or contract call.
+func (sc *SmartContract) executeGovernance(function string, ctx *ContractContext) (interface{}, error) {
+	if function == ContractConstructor {
+		return sc.initGovernance(ctx)
+	}
+
+	if _, initialized := sc.getStateString("weightSource"); !initialized {
+		return nil, fmt.Errorf("governance not initialized")
+	}
+	if ctx.BlockNumber < 0 {
+		return nil, fmt.Errorf("block height is not available in this context")
+	}
+	proposals := sc.getGovernanceProposals()
+	delegates := sc.getStringMap("delegates") // account -> delegatee
+
+	switch function {
+	case "deposit":
+		if ctx.Value <= 0 {
+			return nil, fmt.Errorf("deposit value must be greater than zero")
+		}
+		sc.emitLog(ctx, EventDeposit, []string{ctx.Caller}, formatAmount(ctx.Value))
+		return fmt.Sprintf("Deposited %.2f coins to the treasury", ctx.Value), nil
+
+	case "propose":
+		if err := validateArgsCount(ctx.Args, 3, "propose"); err != nil {
+			return nil, err
+		}
+		description, target := ctx.StringArg(0), ctx.StringArg(1)
+		value, err := ctx.AmountArg(2)
+		if err != nil {
+			return nil, err
+		}
+		data := ctx.CallDataArg(3)
+		if target == "" && (value > 0 || data != "") {
+			return nil, fmt.Errorf("proposal with value or call data requires a target")
+		}
+		if data != "" {
+			if _, err := ParseContractCall(data); err != nil {
+				return nil, err
+			}
+		}
+
+		delay, _ := sc.getStateFloat("votingDelay")
+		period, _ := sc.getStateFloat("votingPeriod")
+		start := float64(ctx.BlockNumber) + delay
+		count, _ := sc.getStateFloat("proposalCount")
+		proposalID := uint64(count) + 1
+		key := strconv.FormatUint(proposalID, 10)
+
+		sc.mu.Lock()
+		sc.State["proposalCount"] = float64(proposalID)
+		proposals[key] = map[string]interface{}{
+			"description":  description,
+			"proposer":     ctx.Caller,
+			"target":       target,
+			"value":        value,
+			"data":         data,
+			"startBlock":   start,
+			"endBlock":     start + period,
+			"forVotes":     0.0,
+			"againstVotes": 0.0,
+			"abstainVotes": 0.0,
+			"voters":       []string{},
+			"executed":     false,
+		}
+		sc.mu.Unlock()
+
+		sc.emitLog(ctx, EventGovernanceProposal, []string{ctx.Caller, key}, description)
+		return proposalID, nil
+
+	case "vote":
+		if err := validateArgsCount(ctx.Args, 2, "vote"); err != nil {
+			return nil, err
+		}
+		key, proposal, err := sc.governanceProposal(ctx, proposals)
+		if err != nil {
+			return nil, err
+		}
+		support := ctx.StringArg(1)
+		if support != VoteFor && support != VoteAgainst && support != VoteAbstain {
+			return nil, fmt.Errorf("vote must be %q, %q or %q", VoteFor, VoteAgainst, VoteAbstain)
+		}
+		if state := sc.governanceState(ctx, proposal); state != ProposalActive {
+			return nil, fmt.Errorf("proposal %s is %s", key, state)
+		}
+
+		sc.mu.RLock()
+		voted := append([]string(nil), proposal["voters"].([]string)...)
+		delegatee := delegates[ctx.Caller]
+		delegators := make([]string, 0)
+		for account := range delegates {
+			if account != ctx.Caller && resolveDelegate(delegates, account) == ctx.Caller && !containsString(voted, account) {
+				delegators = append(delegators, account)
+			}
+		}
+		snapshot := int(proposal["startBlock"].(float64)) - 1
+		sc.mu.RUnlock()
+
+		if containsString(voted, ctx.Caller) {
+			return nil, fmt.Errorf("address already voted on proposal %s", key)
+		}
+		if delegatee != "" && delegatee != ctx.Caller {
+			return nil, fmt.Errorf("votes are delegated to %s", truncateAddress(delegatee))
+		}
+
+		// The caller votes with their own weight plus that of delegators who have not voted themselves
+		weight := 0.0
+		for _, account := range append(delegators, ctx.Caller) {
+			w, err := sc.governanceWeight(ctx, account, snapshot)
+			if err != nil {
+				return nil, err
+			}
+			weight += w
+		}
+		if weight == 0 {
+			return nil, fmt.Errorf("no voting power at block %d", snapshot)
+		}
+
+		sc.mu.Lock()
+		proposal[support+"Votes"] = proposal[support+"Votes"].(float64) + weight
+		proposal["voters"] = append(append(voted, ctx.Caller), delegators...)
+		sc.mu.Unlock()
+
+		sc.emitLog(ctx, EventGovernanceVote, []string{ctx.Caller, key}, support+","+formatAmount(weight))
+		return fmt.Sprintf("Voted %s proposal %s with weight %.2f", support, key, weight), nil
+
+	case "delegate":
+		if err := validateArgsCount(ctx.Args, 1, "delegate"); err != nil {
+			return nil, err
+		}
+		delegatee := ctx.StringArg(0)
+		sc.mu.Lock()
+		if delegatee != ctx.Caller && resolveDelegate(delegates, delegatee) == ctx.Caller {
+			sc.mu.Unlock()
+			return nil, fmt.Errorf("%s already delegates to you", truncateAddress(delegatee))
+		}
+		if delegatee == ctx.Caller {
+			delete(delegates, ctx.Caller) // Delegating to yourself takes your votes back
+		} else {
+			delegates[ctx.Caller] = delegatee
+		}
+		sc.mu.Unlock()
+
+		sc.emitLog(ctx, EventDelegateChanged, []string{ctx.Caller, delegatee}, "")
+		return fmt.Sprintf("Delegated votes to %s", truncateAddress(delegatee)), nil
+
+	case "execute":
+		if err := validateArgsCount(ctx.Args, 1, "execute"); err != nil {
+			return nil, err
+		}
+		key, proposal, err := sc.governanceProposal(ctx, proposals)
+		if err != nil {
+			return nil, err
+		}
+		if state := sc.governanceState(ctx, proposal); state != ProposalSucceeded {
+			return nil, fmt.Errorf("proposal %s is %s", key, state)
+		}
+
+		sc.mu.RLock()
+		target, _ := proposal["target"].(string)
+		value, _ := proposal["value"].(float64)
+		data, _ := proposal["data"].(string)
+		sc.mu.RUnlock()
+
+		result, err := sc.executeStoredCall(ctx, proposal, target, value, data)
+		if err != nil {
+			return nil, fmt.Errorf("execution of proposal %s failed: %v", key, err)
+		}
+
+		sc.emitLog(ctx, EventProposalExecuted, []string{key}, "")
+		if result != nil {
+			return result, nil
+		}
+		return fmt.Sprintf("Executed proposal %s", key), nil
+
+	case "getProposal", "state":
+		if err := validateArgsCount(ctx.Args, 1, function); err != nil {
+			return nil, err
+		}
+		_, proposal, err := sc.governanceProposal(ctx, proposals)
+		if err != nil {
+			return nil, err
+		}
+		state := sc.governanceState(ctx, proposal)
+		if function == "state" {
+			return state, nil
+		}
+		sc.mu.RLock()
+		defer sc.mu.RUnlock()
+		result := cloneState(proposal)
+		result["state"] = state
+		return result, nil
+
+	case "getVotingPower":
+		if err := validateArgsCount(ctx.Args, 1, "getVotingPower"); err != nil {
+			return nil, err
+		}
+		account := ctx.StringArg(0)
+		sc.mu.RLock()
+		accounts := make([]string, 0)
+		if _, delegated := delegates[account]; !delegated {
+			accounts = append(accounts, account)
+		}
+		for delegator := range delegates {
+			if delegator != account && resolveDelegate(delegates, delegator) == account {
+				accounts = append(accounts, delegator)
+			}
+		}
+		sc.mu.RUnlock()
+
+		power := 0.0
+		for _, a := range accounts {
+			w, err := sc.governanceWeight(ctx, a, ctx.BlockNumber)
+			if err != nil {
+				return nil, err
+			}
+			power += w
+		}
+		return power, nil
+
+	case "getDelegate":
+		if err := validateArgsCount(ctx.Args, 1, "getDelegate"); err != nil {
+			return nil, err
+		}
+		sc.mu.RLock()
+		defer sc.mu.RUnlock()
+		if to := delegates[ctx.StringArg(0)]; to != "" {
+			return to, nil
+		}
+		return ctx.StringArg(0), nil
+
+	default:
+		return nil, fmt.Errorf("unknown function: %s", function)
+	}
+}
+
+// resolveDelegate follows an account's delegation chain to the address that votes for it,
+// or returns "" if the chain loops (delegate rejects loops, but older state may hold one)
+func resolveDelegate(delegates map[string]string, account string) string {
+	for hops := 0; hops <= len(delegates); hops++ {
+		to, delegated := delegates[account]
+		if !delegated {
+			return account
+		}
+		account = to
+	}
+	return ""
+}
+
+// initGovernance sets the vote weight source and voting rules; it runs once, at deployment
+func (sc *SmartContract) initGovernance(ctx *ContractContext) (interface{}, error) {
+	if ctx.Caller != sc.Deployer {
+		return nil, fmt.Errorf("only deployer can initialize governance")
+	}
+	if _, initialized := sc.getStateString("weightSource"); initialized {
+		return nil, fmt.Errorf("governance already initialized")
+	}
+	if err := validateArgsCount(ctx.Args, 5, ContractConstructor); err != nil {
+		return nil, err
+	}
+
+	source := ctx.StringArg(0)
+	if source != GovernanceNativeWeight {
+		if ctx.registry == nil {
+			return nil, fmt.Errorf("contract calls are not available in this context")
+		}
+		token, err := ctx.registry.GetContract(source)
+		if err != nil {
+			return nil, err
+		}
+		if token.Type != ContractTypeToken {
+			return nil, fmt.Errorf("%s is not a token contract", truncateAddress(source))
+		}
+	}
+	delay, err := ctx.UintArg(1)
+	if err != nil {
+		return nil, err
+	}
+	period, err := ctx.UintArg(2)
+	if err != nil {
+		return nil, err
+	}
+	if period == 0 {
+		return nil, fmt.Errorf("voting period must be at least one block")
+	}
+	// Token weights are read from state history as of the block before voting
+	// opens, so the last vote must still fall inside the history window
+	if source != GovernanceNativeWeight && period+1 >= MaxStateHistory {
+		return nil, fmt.Errorf("voting period must be under %d blocks for token-weighted governance", MaxStateHistory-1)
+	}
+	quorum, err := ctx.AmountArg(3)
+	if err != nil {
+		return nil, err
+	}
+	threshold, err := ctx.UintArg(4)
+	if err != nil {
+		return nil, err
+	}
+	if threshold == 0 || threshold > 100 {
+		return nil, fmt.Errorf("threshold must be between 1 and 100 percent")
+	}
+
+	sc.mu.Lock()
+	sc.State["weightSource"] = source
+	sc.State["votingDelay"] = float64(delay)
+	sc.State["votingPeriod"] = float64(period)
+	sc.State["quorum"] = quorum
+	sc.State["threshold"] = float64(threshold)
+	sc.State["proposalCount"] = 0.0
+	sc.mu.Unlock()
+	return fmt.Sprintf("Governance initialized: quorum %.2f, threshold %d%%", quorum, threshold), nil
+}
+
+func (sc *SmartContract) getGovernanceProposals() map[string]interface{} {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+	if _, exists := sc.State["proposals"]; !exists {
+		sc.State["proposals"] = make(map[string]interface{})
+	}
+	return sc.State["proposals"].(map[string]interface{})
+}
+
+// governanceProposal reads the proposal ID argument and returns the proposal
+func (sc *SmartContract) governanceProposal(ctx *ContractContext, proposals map[string]interface{}) (string, map[string]interface{}, error) {
+	proposalID, err := ctx.UintArg(0)
+	if err != nil {
+		return "", nil, err
+	}
+	key := strconv.FormatUint(proposalID, 10)
+
+	sc.mu.RLock()
+	defer sc.mu.RUnlock()
+	proposal, exists := proposals[key].(map[string]interface{})
+	if !exists {
+		return "", nil, fmt.Errorf("proposal %s not found", key)
+	}
+	return key, proposal, nil
+}
+
+// governanceState returns the state of a proposal at the context's block
+func (sc *SmartContract) governanceState(ctx *ContractContext, proposal map[string]interface{}) string {
+	quorum, _ := sc.getStateFloat("quorum")
+	threshold, _ := sc.getStateFloat("threshold")
+
+	sc.mu.RLock()
+	defer sc.mu.RUnlock()
+	current := float64(ctx.BlockNumber)
+	forVotes := proposal["forVotes"].(float64)
+	againstVotes := proposal["againstVotes"].(float64)
+	cast := forVotes + againstVotes + proposal["abstainVotes"].(float64)
+
+	switch {
+	case proposal["executed"].(bool):
+		return ProposalExecuted
+	case current < proposal["startBlock"].(float64):
+		return ProposalPending
+	case current <= proposal["endBlock"].(float64):
+		return ProposalActive
+	case cast < quorum || forVotes == 0 || forVotes*100 < threshold*(forVotes+againstVotes):
+		return ProposalDefeated
+	default:
+		return ProposalSucceeded
+	}
+}
+
+// governanceWeight returns an account's own vote weight as of a block:
+// its native balance, or its balance in the weight token contract
+func (sc *SmartContract) governanceWeight(ctx *ContractContext, account string, blockNumber int) (float64, error) {
+	if ctx.chain == nil {
+		return 0, fmt.Errorf("balances are not available in this context")
+	}
+	if blockNumber < 0 {
+		blockNumber = 0
+	}
+	source, _ := sc.getStateString("weightSource")
+	if source == GovernanceNativeWeight {
+		return ctx.chain.GetBalanceAt(account, blockNumber), nil
+	}
+
+	registry, err := ctx.chain.stateAt(blockNumber)
+	if err != nil {
+		return 0, err
+	}
+	token, err := registry.GetContract(source)
+	if err != nil {
+		return 0, nil // The token did not exist yet, so nobody held any
+	}
+	token.mu.RLock()
+	defer token.mu.RUnlock()
+	balances, _ := token.State["balances"].(map[string]float64)
+	return balances[account], nil
+}
@@ -0,0 +1,313 @@
+package main
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/sha512"
+	"encoding/binary"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// HardenedKeyStart is the first hardened child index; hardened children can only be derived from private keys
+const HardenedKeyStart uint32 = 0x80000000
+
+// HDCoinType is the BIP-44 coin type used in derivation paths (1, the shared testnet type)
+const HDCoinType uint32 = 1
+
+// DefaultGapLimit is how many consecutive unused addresses end address discovery (as in BIP-44)
+const DefaultGapLimit = 20
+
+// BIP-44 change chains
+const (
+	HDExternalChain uint32 = 0 // Receiving addresses
+	HDInternalChain uint32 = 1 // Change addresses
+)
+
+// HMAC keys for master key generation: BIP-32's for secp256k1 and SLIP-0010's for P-256
+const (
+	hdMasterKeySaltSecp256k1 = "Bitcoin seed"
+	hdMasterKeySaltP256      = "Nist256p1 seed"
+)
+
+// HDKey is an extended private key: a key and chain code from which child keys are derived
+// (BIP-32 on secp256k1, or on P-256 for legacy wallets as specified by SLIP-0010)
+type HDKey struct {
+	PrivateKey *ecdsa.PrivateKey
+	ChainCode  []byte
+	Depth      int
+	Index      uint32
+	Path       string
+}
+
+// NewMasterKey derives the root secp256k1 key of a hierarchy from a seed
+func NewMasterKey(seed []byte) (*HDKey, error) {
+	return newMasterKey(seed, secp256k1, hdMasterKeySaltSecp256k1)
+}
+
+// NewLegacyMasterKey derives the root P-256 key of a hierarchy of legacy wallets from a seed
+func NewLegacyMasterKey(seed []byte) (*HDKey, error) {
+	return newMasterKey(seed, elliptic.P256(), hdMasterKeySaltP256)
+}
+
+func newMasterKey(seed []byte, curve elliptic.Curve, salt string) (*HDKey, error) {
+	if len(seed) < 16 || len(seed) > 64 {
+		return nil, fmt.Errorf("seed must be 16 to 64 bytes, got %d", len(seed))
+	}
+	data := seed
+	for {
+		mac := hmac.New(sha512.New, []byte(salt))
+		mac.Write(data)
+		sum := mac.Sum(nil)
+		if key, err := hdPrivateKey(curve, new(big.Int).SetBytes(sum[:32])); err == nil {
+			return &HDKey{PrivateKey: key, ChainCode: sum[32:], Path: "m"}, nil
+		}
+		// SLIP-0010: retry with the whole output if the key is out of range
+		data = sum
+	}
+}
+
+// Child derives the child key at index; indices from HardenedKeyStart are hardened
+func (k *HDKey) Child(index uint32) (*HDKey, error) {
+	if k.Depth >= 255 {
+		return nil, fmt.Errorf("maximum derivation depth reached")
+	}
+	n := k.PrivateKey.Curve.Params().N
+	var data []byte
+	if index >= HardenedKeyStart {
+		data = append([]byte{0x00}, privateKeyBytes(k.PrivateKey)...)
+	} else {
+		data = elliptic.MarshalCompressed(k.PrivateKey.Curve, k.PrivateKey.X, k.PrivateKey.Y)
+	}
+	data = binary.BigEndian.AppendUint32(data, index)
+
+	for {
+		mac := hmac.New(sha512.New, k.ChainCode)
+		mac.Write(data)
+		sum := mac.Sum(nil)
+
+		il := new(big.Int).SetBytes(sum[:32])
+		if il.Cmp(n) < 0 {
+			scalar := il.Add(il, k.PrivateKey.D)
+			scalar.Mod(scalar, n)
+			if key, err := hdPrivateKey(k.PrivateKey.Curve, scalar); err == nil {
+				return &HDKey{
+					PrivateKey: key,
+					ChainCode:  sum[32:],
+					Depth:      k.Depth + 1,
+					Index:      index,
+					Path:       k.Path + "/" + formatPathIndex(index),
+				}, nil
+			}
+		}
+		// SLIP-0010: retry with 0x01 || IR || index if the key is out of range
+		data = binary.BigEndian.AppendUint32(append([]byte{0x01}, sum[32:]...), index)
+	}
+}
+
+// Derive derives the key at a path such as "m/44'/1'/0'/0/5" relative to this key
+func (k *HDKey) Derive(path string) (*HDKey, error) {
+	indices, err := ParseDerivationPath(path)
+	if err != nil {
+		return nil, err
+	}
+	key := k
+	for _, index := range indices {
+		if key, err = key.Child(index); err != nil {
+			return nil, err
+		}
+	}
+	return key, nil
+}
+
+// Wallet returns a wallet for the key
+func (k *HDKey) Wallet() *Wallet {
+	return newWalletFromKey(k.PrivateKey)
+}
+
+// hdPrivateKey turns a scalar into a private key, rejecting zero and values outside the curve order
+func hdPrivateKey(curve elliptic.Curve, scalar *big.Int) (*ecdsa.PrivateKey, error) {
+	if scalar.Sign() == 0 || scalar.Cmp(curve.Params().N) >= 0 {
+		return nil, fmt.Errorf("invalid private key scalar")
+	}
+	if curve == secp256k1 {
+		return secp256k1PrivateKey(scalar), nil
+	}
+	keyBytes := make([]byte, 32)
+	scalar.FillBytes(keyBytes)
+	return ecdsa.ParseRawPrivateKey(curve, keyBytes)
+}
+
+// ParseDerivationPath parses a path like "m/44'/1'/0'/0/5" into child indices; "'" or "h" marks hardened
+func ParseDerivationPath(path string) ([]uint32, error) {
+	parts := strings.Split(strings.TrimSpace(path), "/")
+	if parts[0] != "m" {
+		return nil, fmt.Errorf("derivation path must start with m: %q", path)
+	}
+	indices := make([]uint32, 0, len(parts)-1)
+	for _, part := range parts[1:] {
+		hardened := strings.HasSuffix(part, "'") || strings.HasSuffix(part, "h")
+		if hardened {
+			part = part[:len(part)-1]
+		}
+		value, err := strconv.ParseUint(part, 10, 32)
+		if err != nil || uint32(value) >= HardenedKeyStart {
+			return nil, fmt.Errorf("invalid derivation path component %q in %q", part, path)
+		}
+		index := uint32(value)
+		if hardened {
+			index += HardenedKeyStart
+		}
+		indices = append(indices, index)
+	}
+	return indices, nil
+}
+
+// formatPathIndex writes a child index as a path component
+func formatPathIndex(index uint32) string {
+	if index >= HardenedKeyStart {
+		return fmt.Sprintf("%d'", index-HardenedKeyStart)
+	}
+	return fmt.Sprintf("%d", index)
+}
+
+// DerivationPath returns the BIP-44 path of an address: m/44'/coin'/account'/change/index
+func DerivationPath(account, change, index uint32) string {
+	return fmt.Sprintf("m/44'/%d'/%d'/%d/%d", HDCoinType, account, change, index)
+}
+
+// HDWallet derives every address of a user from one mnemonic phrase (secp256k1 keys)
+type HDWallet struct {
+	master *HDKey
+}
+
+// NewHDWallet creates a random mnemonic and the HD wallet it backs up
+func NewHDWallet(entropyBits int, passphrase string) (*HDWallet, string, error) {
+	mnemonic, err := NewMnemonic(entropyBits)
+	if err != nil {
+		return nil, "", err
+	}
+	hw, err := RestoreHDWallet(mnemonic, passphrase)
+	if err != nil {
+		return nil, "", err
+	}
+	return hw, mnemonic, nil
+}
+
+// RestoreHDWallet recreates an HD wallet from its mnemonic and optional passphrase
+func RestoreHDWallet(mnemonic, passphrase string) (*HDWallet, error) {
+	seed, err := MnemonicToSeed(mnemonic, passphrase)
+	if err != nil {
+		return nil, err
+	}
+	master, err := NewMasterKey(seed)
+	if err != nil {
+		return nil, err
+	}
+	return &HDWallet{master: master}, nil
+}
+
+// Key derives the key at a path
+func (hw *HDWallet) Key(path string) (*HDKey, error) {
+	return hw.master.Derive(path)
+}
+
+// Wallet derives the wallet of an account's address on a change chain
+func (hw *HDWallet) Wallet(account, change, index uint32) (*Wallet, error) {
+	key, err := hw.Key(DerivationPath(account, change, index))
+	if err != nil {
+		return nil, err
+	}
+	return key.Wallet(), nil
+}
+
+// HDAddress is an address found by discovery with its path and balance
+type HDAddress struct {
+	Path    string  `json:"path"`
+	Account uint32  `json:"account"`
+	Change  uint32  `json:"change"`
+	Index   uint32  `json:"index"`
+	Address string  `json:"address"`
+	Balance float64 `json:"balance"`
+	wallet  *Wallet
+}
+
+// Wallet returns the wallet of a discovered address
+func (a *HDAddress) Wallet() *Wallet {
+	return a.wallet
+}
+
+// Discover recovers the wallet's used addresses from chain history, BIP-44 style: each account's
+// receiving and change chains are scanned until gapLimit consecutive addresses have never appeared
+// in a transaction, and accounts are scanned until one has no used addresses at all.
+func (hw *HDWallet) Discover(bc *Blockchain, gapLimit int) ([]*HDAddress, error) {
+	if gapLimit <= 0 {
+		return nil, fmt.Errorf("gap limit must be greater than zero")
+	}
+	used := bc.usedAddresses()
+
+	var found []*HDAddress
+	for account := uint32(0); account < HardenedKeyStart; account++ {
+		accountKey, err := hw.Key(fmt.Sprintf("m/44'/%d'/%d'", HDCoinType, account))
+		if err != nil {
+			return nil, err
+		}
+		accountUsed := false
+		for _, change := range []uint32{HDExternalChain, HDInternalChain} {
+			chainKey, err := accountKey.Child(change)
+			if err != nil {
+				return nil, err
+			}
+			gap := 0
+			for index := uint32(0); gap < gapLimit; index++ {
+				key, err := chainKey.Child(index)
+				if err != nil {
+					return nil, err
+				}
+				wallet := key.Wallet()
+				if !used[wallet.Address] {
+					gap++
+					continue
+				}
+				gap = 0
+				accountUsed = true
+				found = append(found, &HDAddress{
+					Path:    key.Path,
+					Account: account,
+					Change:  change,
+					Index:   index,
+					Address: wallet.Address,
+					Balance: bc.GetBalance(wallet.Address),
+					wallet:  wallet,
+				})
+			}
+		}
+		if !accountUsed {
+			break
+		}
+	}
+	return found, nil
+}
+
+// usedAddresses returns every address that has sent or received a transaction or contract transfer
+func (bc *Blockchain) usedAddresses() map[string]bool {
+	used := make(map[string]bool)
+	for _, block := range bc.Blocks {
+		for _, tx := range block.Transactions {
+			if tx.From != "" {
+				used[tx.From] = true
+			}
+			if tx.To != "" {
+				used[tx.To] = true
+			}
+		}
+	}
+	for _, t := range bc.InternalTransfers {
+		used[t.From] = true
+		used[t.To] = true
+	}
+	return used
+}
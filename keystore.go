@@ -0,0 +1,459 @@
+package main
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/pbkdf2"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// KeystoreVersion is the keystore file format version (modelled on Ethereum's v3 keystore)
+const KeystoreVersion = 3
+
+// PBKDF2 iteration counts: standard for files at rest, light for tests and demos
+const (
+	StandardPBKDF2Iterations = 262144
+	LightPBKDF2Iterations    = 4096
+)
+
+// DefaultUnlockDuration is how long personal_unlockAccount keeps an account unlocked when no
+// duration is given
+const DefaultUnlockDuration = 300 * time.Second
+
+// Keystore crypto parameters
+const (
+	keystoreCipher  = "aes-256-gcm"
+	keystoreKDF     = "pbkdf2"
+	keystorePRF     = "hmac-sha256"
+	keystoreDKLen   = 32
+	keystoreSaltLen = 32
+)
+
+// KeystoreFile is an encrypted private key in a JSON format similar to Ethereum's v3 keystore.
+// The key is derived from the passphrase with PBKDF2 and the private key is sealed with AES-GCM,
+// whose authentication tag replaces v3's separate MAC.
+type KeystoreFile struct {
+	Address string         `json:"address"`
+	ID      string         `json:"id"`
+	Version int            `json:"version"`
+	KeyType string         `json:"keyType"` // Signature scheme of the stored key (see SignatureSchemes)
+	Crypto  KeystoreCrypto `json:"crypto"`
+}
+
+// KeystoreCrypto holds the cipher and key derivation parameters of a keystore file
+type KeystoreCrypto struct {
+	Cipher       string       `json:"cipher"`
+	CipherText   string       `json:"ciphertext"` // Hex of the sealed private key, including the GCM tag
+	CipherParams CipherParams `json:"cipherparams"`
+	KDF          string       `json:"kdf"`
+	KDFParams    PBKDF2Params `json:"kdfparams"`
+}
+
+// CipherParams holds the AES-GCM nonce
+type CipherParams struct {
+	Nonce string `json:"nonce"`
+}
+
+// PBKDF2Params holds the PBKDF2 key derivation parameters
+type PBKDF2Params struct {
+	Iterations int    `json:"c"`
+	DKLen      int    `json:"dklen"`
+	PRF        string `json:"prf"`
+	Salt       string `json:"salt"`
+}
+
+// EncryptWallet encrypts a wallet's private key with a passphrase
+func EncryptWallet(w *Wallet, passphrase string, iterations int) (*KeystoreFile, error) {
+	if iterations <= 0 {
+		return nil, fmt.Errorf("PBKDF2 iterations must be greater than zero")
+	}
+	salt := make([]byte, keystoreSaltLen)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return nil, err
+	}
+
+	params := PBKDF2Params{
+		Iterations: iterations,
+		DKLen:      keystoreDKLen,
+		PRF:        keystorePRF,
+		Salt:       hex.EncodeToString(salt),
+	}
+	gcm, err := keystoreCipherFor(passphrase, params)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+
+	exporter, ok := w.Signer.(keyExporter)
+	if !ok {
+		return nil, fmt.Errorf("wallet key cannot be exported")
+	}
+	privateKey := exporter.privateKeyBytes()
+	// The address is authenticated so a file cannot be relabelled with another account
+	sealed := gcm.Seal(nil, nonce, privateKey, []byte(w.Address))
+
+	return &KeystoreFile{
+		Address: w.Address,
+		ID:      hex.EncodeToString(id),
+		Version: KeystoreVersion,
+		KeyType: w.Signer.Scheme().String(),
+		Crypto: KeystoreCrypto{
+			Cipher:       keystoreCipher,
+			CipherText:   hex.EncodeToString(sealed),
+			CipherParams: CipherParams{Nonce: hex.EncodeToString(nonce)},
+			KDF:          keystoreKDF,
+			KDFParams:    params,
+		},
+	}, nil
+}
+
+// Decrypt unlocks the keystore file with its passphrase and returns the wallet
+func (ks *KeystoreFile) Decrypt(passphrase string) (*Wallet, error) {
+	if ks.Version != KeystoreVersion {
+		return nil, fmt.Errorf("unsupported keystore version: %d", ks.Version)
+	}
+	scheme, err := SignatureSchemeByName(ks.KeyType)
+	if err != nil {
+		return nil, fmt.Errorf("unsupported key type: %s", ks.KeyType)
+	}
+	info, err := GetSignatureScheme(scheme)
+	if err != nil {
+		return nil, err
+	}
+	if info.ParsePrivateKey == nil {
+		return nil, fmt.Errorf("unsupported key type: %s", ks.KeyType)
+	}
+	if ks.Crypto.Cipher != keystoreCipher {
+		return nil, fmt.Errorf("unsupported cipher: %s", ks.Crypto.Cipher)
+	}
+	if ks.Crypto.KDF != keystoreKDF {
+		return nil, fmt.Errorf("unsupported key derivation function: %s", ks.Crypto.KDF)
+	}
+
+	gcm, err := keystoreCipherFor(passphrase, ks.Crypto.KDFParams)
+	if err != nil {
+		return nil, err
+	}
+	nonce, err := hex.DecodeString(ks.Crypto.CipherParams.Nonce)
+	if err != nil || len(nonce) != gcm.NonceSize() {
+		return nil, fmt.Errorf("invalid keystore nonce")
+	}
+	sealed, err := hex.DecodeString(ks.Crypto.CipherText)
+	if err != nil {
+		return nil, fmt.Errorf("invalid keystore ciphertext")
+	}
+	privateKeyBytes, err := gcm.Open(nil, nonce, sealed, []byte(ks.Address))
+	if err != nil {
+		return nil, fmt.Errorf("could not decrypt key with given passphrase")
+	}
+
+	signer, err := info.ParsePrivateKey(privateKeyBytes)
+	if err != nil {
+		return nil, fmt.Errorf("invalid private key in keystore: %v", err)
+	}
+	wallet := NewWalletFromSigner(signer)
+	if wallet.Address != ks.Address {
+		return nil, fmt.Errorf("keystore address mismatch: file is for %s, key is for %s", ks.Address, wallet.Address)
+	}
+	return wallet, nil
+}
+
+// keystoreCipherFor derives the AES key from a passphrase and returns its GCM cipher
+func keystoreCipherFor(passphrase string, params PBKDF2Params) (cipher.AEAD, error) {
+	if params.PRF != keystorePRF {
+		return nil, fmt.Errorf("unsupported PBKDF2 PRF: %s", params.PRF)
+	}
+	if params.DKLen != keystoreDKLen {
+		return nil, fmt.Errorf("unsupported derived key length: %d", params.DKLen)
+	}
+	salt, err := hex.DecodeString(params.Salt)
+	if err != nil {
+		return nil, fmt.Errorf("invalid keystore salt")
+	}
+	key, err := pbkdf2.Key(sha256.New, passphrase, salt, params.Iterations, params.DKLen)
+	if err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// SaveKeystoreFile writes an encrypted keystore file readable only by its owner
+func SaveKeystoreFile(path string, ks *KeystoreFile) error {
+	data, err := json.MarshalIndent(ks, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0600)
+}
+
+// LoadKeystoreFile reads a keystore file without decrypting it
+func LoadKeystoreFile(path string) (*KeystoreFile, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var ks KeystoreFile
+	if err := json.Unmarshal(data, &ks); err != nil {
+		return nil, fmt.Errorf("invalid keystore file %s: %v", path, err)
+	}
+	return &ks, nil
+}
+
+// SaveWallet encrypts a wallet with a passphrase and writes it to path
+func SaveWallet(w *Wallet, path, passphrase string) error {
+	ks, err := EncryptWallet(w, passphrase, StandardPBKDF2Iterations)
+	if err != nil {
+		return err
+	}
+	return SaveKeystoreFile(path, ks)
+}
+
+// LoadWallet reads a keystore file and unlocks it with its passphrase
+func LoadWallet(path, passphrase string) (*Wallet, error) {
+	ks, err := LoadKeystoreFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return ks.Decrypt(passphrase)
+}
+
+// Keystore manages a directory of encrypted accounts, one file per address,
+// and keeps the accounts unlocked in this session in memory
+type Keystore struct {
+	Dir        string
+	Iterations int // PBKDF2 iterations for newly written files
+	unlocked   map[string]*Wallet
+	expiry     map[string]time.Time // When timed unlocks end
+	mu         sync.RWMutex
+}
+
+// NewKeystore opens (creating if needed) a keystore directory
+func NewKeystore(dir string, iterations int) (*Keystore, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, fmt.Errorf("failed to create keystore directory: %v", err)
+	}
+	return &Keystore{
+		Dir:        dir,
+		Iterations: iterations,
+		unlocked:   make(map[string]*Wallet),
+		expiry:     make(map[string]time.Time),
+	}, nil
+}
+
+// NewAccount creates a wallet and stores it encrypted with a passphrase
+func (k *Keystore) NewAccount(passphrase string) (*Wallet, error) {
+	wallet, err := NewWallet()
+	if err != nil {
+		return nil, err
+	}
+	if err := k.Import(wallet, passphrase); err != nil {
+		return nil, err
+	}
+	return wallet, nil
+}
+
+// Import stores an existing wallet encrypted with a passphrase
+func (k *Keystore) Import(w *Wallet, passphrase string) error {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	if _, err := k.findFile(w.Address); err == nil {
+		return fmt.Errorf("account already exists: %s", w.Address)
+	}
+	ks, err := EncryptWallet(w, passphrase, k.Iterations)
+	if err != nil {
+		return err
+	}
+	name := fmt.Sprintf("UTC--%s--%s", time.Now().UTC().Format("2006-01-02T15-04-05.000000000Z"), w.Address)
+	return SaveKeystoreFile(filepath.Join(k.Dir, name), ks)
+}
+
+// Accounts returns the addresses stored in the keystore, in the order they were created
+func (k *Keystore) Accounts() ([]string, error) {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+	files, err := k.files()
+	if err != nil {
+		return nil, err
+	}
+	accounts := make([]string, 0, len(files))
+	for _, path := range files {
+		accounts = append(accounts, accountOfKeystoreFile(path))
+	}
+	return accounts, nil
+}
+
+// HasAccount reports whether the keystore holds an address
+func (k *Keystore) HasAccount(address string) bool {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+	_, err := k.findFile(address)
+	return err == nil
+}
+
+// Unlock decrypts an account and keeps it available for signing until it is locked
+func (k *Keystore) Unlock(address, passphrase string) (*Wallet, error) {
+	return k.TimedUnlock(address, passphrase, 0)
+}
+
+// TimedUnlock decrypts an account and keeps it available for signing for the given duration,
+// or until it is locked if the duration is 0. Unlocking again replaces the previous duration.
+func (k *Keystore) TimedUnlock(address, passphrase string, duration time.Duration) (*Wallet, error) {
+	if duration < 0 {
+		return nil, fmt.Errorf("negative unlock duration: %v", duration)
+	}
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	wallet, err := k.decrypt(address, passphrase)
+	if err != nil {
+		return nil, err
+	}
+	k.unlocked[address] = wallet
+	if duration > 0 {
+		k.expiry[address] = time.Now().Add(duration)
+	} else {
+		delete(k.expiry, address)
+	}
+	return wallet, nil
+}
+
+// Lock removes an unlocked account from memory
+func (k *Keystore) Lock(address string) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	delete(k.unlocked, address)
+	delete(k.expiry, address)
+}
+
+// Wallet returns an unlocked account, locking it first if its unlock has expired
+func (k *Keystore) Wallet(address string) (*Wallet, error) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	if expiry, timed := k.expiry[address]; timed && !time.Now().Before(expiry) {
+		delete(k.unlocked, address)
+		delete(k.expiry, address)
+	}
+	if wallet, unlocked := k.unlocked[address]; unlocked {
+		return wallet, nil
+	}
+	if _, err := k.findFile(address); err != nil {
+		return nil, err
+	}
+	return nil, fmt.Errorf("account is locked: %s", address)
+}
+
+// SignTransaction signs a transaction with its sender's unlocked account
+func (k *Keystore) SignTransaction(tx *Transaction) error {
+	wallet, err := k.Wallet(tx.From)
+	if err != nil {
+		return err
+	}
+	return wallet.SignTransaction(tx)
+}
+
+// ChangePassphrase re-encrypts an account under a new passphrase with a fresh salt and nonce
+func (k *Keystore) ChangePassphrase(address, oldPassphrase, newPassphrase string) error {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	path, err := k.findFile(address)
+	if err != nil {
+		return err
+	}
+	wallet, err := k.decrypt(address, oldPassphrase)
+	if err != nil {
+		return err
+	}
+	ks, err := EncryptWallet(wallet, newPassphrase, k.Iterations)
+	if err != nil {
+		return err
+	}
+
+	// Write the new file next to the old one and rename it over it, so a crash never loses the key
+	tmp := path + ".tmp"
+	if err := SaveKeystoreFile(tmp, ks); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// Delete removes an account from the keystore after checking its passphrase
+func (k *Keystore) Delete(address, passphrase string) error {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	path, err := k.findFile(address)
+	if err != nil {
+		return err
+	}
+	if _, err := k.decrypt(address, passphrase); err != nil {
+		return err
+	}
+	delete(k.unlocked, address)
+	delete(k.expiry, address)
+	return os.Remove(path)
+}
+
+// decrypt loads and decrypts an account's file; the caller holds the lock
+func (k *Keystore) decrypt(address, passphrase string) (*Wallet, error) {
+	path, err := k.findFile(address)
+	if err != nil {
+		return nil, err
+	}
+	return LoadWallet(path, passphrase)
+}
+
+// findFile returns the keystore file of an address
+func (k *Keystore) findFile(address string) (string, error) {
+	files, err := k.files()
+	if err != nil {
+		return "", err
+	}
+	for _, path := range files {
+		if accountOfKeystoreFile(path) == address {
+			return path, nil
+		}
+	}
+	return "", fmt.Errorf("account not found: %s", address)
+}
+
+// files lists the keystore files in the directory; their UTC-- names sort by creation time
+func (k *Keystore) files() ([]string, error) {
+	entries, err := os.ReadDir(k.Dir)
+	if err != nil {
+		return nil, err
+	}
+	var files []string
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasPrefix(entry.Name(), "UTC--") || strings.HasSuffix(entry.Name(), ".tmp") {
+			continue
+		}
+		files = append(files, filepath.Join(k.Dir, entry.Name()))
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+// accountOfKeystoreFile returns the address encoded in a keystore file name
+func accountOfKeystoreFile(path string) string {
+	name := filepath.Base(path)
+	return name[strings.LastIndex(name, "--")+2:]
+}
@@ -0,0 +1,227 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"strings"
+)
+
+// BloomByteLength is the size of a log bloom filter in bytes (2048 bits, as in Ethereum)
+const BloomByteLength = 256
+
+// Log represents an indexed event emitted by a smart contract during execution
+type Log struct {
+	Address     string   `json:"address"`          // Contract that emitted the log
+	Topics      []string `json:"topics"`           // Topic 0 is the event signature, the rest are indexed arguments
+	Data        string   `json:"data"`             // Non-indexed event data
+	BlockNumber int      `json:"blockNumber"`      // Block that included the transaction
+	TxHash      string   `json:"transactionHash"`  // Transaction that produced the log
+	TxIndex     int      `json:"transactionIndex"` // Position of the transaction in the block
+	LogIndex    int      `json:"logIndex"`         // Position of the log in the block
+}
+
+// Receipt records the outcome of a contract transaction included in a block
+type Receipt struct {
+	TxHash          string      `json:"transactionHash"`
+	BlockNumber     int         `json:"blockNumber"`
+	TxIndex         int         `json:"transactionIndex"`
+	From            string      `json:"from"`
+	To              string      `json:"to"`
+	ContractAddress string      `json:"contractAddress,omitempty"` // Contract created by a deployment transaction
+	Status          bool        `json:"status"`                    // True if the contract call succeeded
+	Result          interface{} `json:"result,omitempty"`
+	Error           string      `json:"error,omitempty"`
+	GasUsed         uint64      `json:"gasUsed"` // Gas consumed by WASM execution
+	Logs            []*Log      `json:"logs"`
+	LogsBloom       string      `json:"logsBloom"`
+	// Transfers are native coins moved by contracts during the call
+	Transfers []*InternalTransfer `json:"transfers,omitempty"`
+}
+
+// Bloom is a 2048-bit bloom filter over log addresses and topics
+type Bloom [BloomByteLength]byte
+
+// EventTopic returns the topic identifying an event signature, e.g. "Transfer(address,address,uint256)"
+func EventTopic(signature string) string {
+	return "0x" + CalculateHash(signature)
+}
+
+// Add sets the three bloom bits derived from the SHA-256 hash of data
+func (b *Bloom) Add(data []byte) {
+	for _, bit := range bloomBits(data) {
+		b[BloomByteLength-1-bit/8] |= 1 << (bit % 8)
+	}
+}
+
+// Test reports whether data may be in the bloom (false positives are possible)
+func (b *Bloom) Test(data []byte) bool {
+	for _, bit := range bloomBits(data) {
+		if b[BloomByteLength-1-bit/8]&(1<<(bit%8)) == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// Or merges another bloom into this one
+func (b *Bloom) Or(other Bloom) {
+	for i := range b {
+		b[i] |= other[i]
+	}
+}
+
+// Hex returns the bloom as a hex string
+func (b Bloom) Hex() string {
+	return hex.EncodeToString(b[:])
+}
+
+// BloomFromHex parses a hex-encoded bloom, treating an empty string as an empty bloom
+func BloomFromHex(s string) (Bloom, error) {
+	var bloom Bloom
+	if s == "" {
+		return bloom, nil
+	}
+	data, err := hex.DecodeString(strings.TrimPrefix(s, "0x"))
+	if err != nil {
+		return bloom, fmt.Errorf("invalid bloom: %v", err)
+	}
+	if len(data) != BloomByteLength {
+		return bloom, fmt.Errorf("invalid bloom length: %d", len(data))
+	}
+	copy(bloom[:], data)
+	return bloom, nil
+}
+
+// LogsBloom builds a bloom filter containing the address and topics of every log
+func LogsBloom(logs []*Log) Bloom {
+	var bloom Bloom
+	for _, log := range logs {
+		bloom.Add([]byte(log.Address))
+		for _, topic := range log.Topics {
+			bloom.Add([]byte(topic))
+		}
+	}
+	return bloom
+}
+
+// bloomBits takes three 11-bit values from the hash of data
+func bloomBits(data []byte) [3]uint {
+	hash := sha256.Sum256(data)
+	var bits [3]uint
+	for i := 0; i < 3; i++ {
+		bits[i] = uint(binary.BigEndian.Uint16(hash[i*2:])) & 2047
+	}
+	return bits
+}
+
+// LogFilter selects logs by block range, contract address and topics
+type LogFilter struct {
+	FromBlock int        // First block to search
+	ToBlock   int        // Last block to search (-1 means latest)
+	Addresses []string   // Match any of these addresses (empty matches all)
+	Topics    [][]string // Per-position alternatives (empty position matches anything)
+}
+
+// Matches reports whether a log satisfies the filter's address and topic criteria
+func (f *LogFilter) Matches(log *Log) bool {
+	if len(f.Addresses) > 0 && !containsString(f.Addresses, log.Address) {
+		return false
+	}
+	if len(f.Topics) > len(log.Topics) {
+		return false
+	}
+	for i, alternatives := range f.Topics {
+		if len(alternatives) > 0 && !containsString(alternatives, log.Topics[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// mayMatchBloom reports whether a block bloom could contain logs matching the filter
+func (f *LogFilter) mayMatchBloom(bloom *Bloom) bool {
+	if len(f.Addresses) > 0 {
+		found := false
+		for _, address := range f.Addresses {
+			if bloom.Test([]byte(address)) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	for _, alternatives := range f.Topics {
+		if len(alternatives) == 0 {
+			continue
+		}
+		found := false
+		for _, topic := range alternatives {
+			if bloom.Test([]byte(topic)) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+func containsString(values []string, target string) bool {
+	for _, v := range values {
+		if v == target {
+			return true
+		}
+	}
+	return false
+}
+
+// GetLogs returns all logs in the filter's block range that match it
+func (bc *Blockchain) GetLogs(filter *LogFilter) []*Log {
+	logs := make([]*Log, 0)
+	toBlock := filter.ToBlock
+	if toBlock < 0 || toBlock >= len(bc.Blocks) {
+		toBlock = len(bc.Blocks) - 1
+	}
+
+	for i := filter.FromBlock; i <= toBlock; i++ {
+		if i < 0 {
+			continue
+		}
+		block := bc.Blocks[i]
+		if block.LogsBloom == "" {
+			continue
+		}
+		bloom, err := BloomFromHex(block.LogsBloom)
+		if err != nil || !filter.mayMatchBloom(&bloom) {
+			continue
+		}
+		for _, tx := range block.Transactions {
+			receipt, exists := bc.Receipts[hex.EncodeToString(tx.Hash())]
+			if !exists || receipt.BlockNumber != block.Index {
+				continue
+			}
+			for _, log := range receipt.Logs {
+				if filter.Matches(log) {
+					logs = append(logs, log)
+				}
+			}
+		}
+	}
+
+	return logs
+}
+
+// GetReceipt returns the receipt of a contract transaction by hash
+func (bc *Blockchain) GetReceipt(txHash string) (*Receipt, error) {
+	receipt, exists := bc.Receipts[strings.TrimPrefix(txHash, "0x")]
+	if !exists {
+		return nil, fmt.Errorf("receipt not found: %s", txHash)
+	}
+	return receipt, nil
+}
@@ -1,11 +1,32 @@
 package main
 
 import (
+	"bufio"
+	"bytes"
+	"flag"
 	"fmt"
+	"os"
+	"os/exec"
+	"strings"
 	"time"
 )
 
+// passphraseEnv names the environment variable holding the keystore passphrase
+const passphraseEnv = "LEARN_BLOCKCHAIN_PASSWORD"
+
 func main() {
+	keystoreDir := flag.String("keystore", "", "Keystore directory to load (or create) Alice's, Bob's and Charlie's accounts from")
+	checkAddress := flag.String("address", "", "Check an address (encoded or hex), print its forms and exit")
+	flag.Parse()
+
+	if *checkAddress != "" {
+		if err := printAddress(*checkAddress); err != nil {
+			fmt.Printf("Invalid address: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
 	fmt.Println("=== Enhanced Blockchain Implementation ===")
 	fmt.Println("Features: Transactions, Merkle Tree, Wallet & Signing, Balance System")
 	fmt.Println("          Mempool, Full Signature Verification, Proof of Stake")
@@ -16,26 +37,53 @@ func main() {
 
 	// Create wallets
 	fmt.Println("1. Creating wallets...")
-	aliceWallet, err := NewWallet()
-	if err != nil {
-		fmt.Printf("Error creating Alice's wallet: %v\n", err)
-		return
+	var aliceWallet, bobWallet, charlieWallet *Wallet
+	var err error
+	if *keystoreDir != "" {
+		passphrase, err := readPassphrase()
+		if err != nil {
+			fmt.Printf("Error reading passphrase: %v\n", err)
+			return
+		}
+		wallets, err := loadDemoWallets(*keystoreDir, passphrase, 3)
+		if err != nil {
+			fmt.Printf("Error loading keystore: %v\n", err)
+			return
+		}
+		aliceWallet, bobWallet, charlieWallet = wallets[0], wallets[1], wallets[2]
+		fmt.Printf("   Loaded accounts from keystore %s\n", *keystoreDir)
+	} else {
+		aliceWallet, err = NewWallet()
+		if err != nil {
+			fmt.Printf("Error creating Alice's wallet: %v\n", err)
+			return
+		}
+		bobWallet, err = NewWallet()
+		if err != nil {
+			fmt.Printf("Error creating Bob's wallet: %v\n", err)
+			return
+		}
+		charlieWallet, err = NewWallet()
+		if err != nil {
+			fmt.Printf("Error creating Charlie's wallet: %v\n", err)
+			return
+		}
 	}
-	fmt.Printf("   Alice's wallet: %s\n", aliceWallet.Address)
-
-	bobWallet, err := NewWallet()
-	if err != nil {
-		fmt.Printf("Error creating Bob's wallet: %v\n", err)
-		return
+	fmt.Printf("   Alice's wallet: %s (%s)\n", aliceWallet.Address, FormatAddress(aliceWallet.Address))
+	fmt.Printf("   Bob's wallet: %s (%s)\n", bobWallet.Address, FormatAddress(bobWallet.Address))
+	fmt.Printf("   Charlie's wallet: %s (%s)\n", charlieWallet.Address, FormatAddress(charlieWallet.Address))
+
+	// A mistyped encoded address fails its checksum instead of naming an unspendable account
+	encodedAlice := FormatAddress(aliceWallet.Address)
+	typo := []byte(encodedAlice)
+	if typo[10] == 'q' {
+		typo[10] = 'p'
+	} else {
+		typo[10] = 'q'
 	}
-	fmt.Printf("   Bob's wallet: %s\n", bobWallet.Address)
-
-	charlieWallet, err := NewWallet()
-	if err != nil {
-		fmt.Printf("Error creating Charlie's wallet: %v\n", err)
-		return
+	if _, err := ParseAddress(string(typo)); err != nil {
+		fmt.Printf("   Mistyped address %s rejected: %v\n", typo, err)
 	}
-	fmt.Printf("   Charlie's wallet: %s\n", charlieWallet.Address)
 	time.Sleep(1 * time.Second)
 
 	// Create a new blockchain
@@ -44,14 +92,23 @@ func main() {
 	time.Sleep(1 * time.Second)
 
 	// Give initial balances using coinbase transactions
+	// (one per block, each creating at most the block reward)
 	fmt.Println("\n3. Distributing initial balances (coinbase transactions)...")
-	coinbase1 := bc.AddCoinbaseTransaction(aliceWallet.Address, 100.0)
-	coinbase2 := bc.AddCoinbaseTransaction(bobWallet.Address, 50.0)
-	coinbase3 := bc.AddCoinbaseTransaction(charlieWallet.Address, 30.0)
-
-	if err := bc.AddBlock([]*Transaction{coinbase1, coinbase2, coinbase3}); err != nil {
-		fmt.Printf("Error adding coinbase block: %v\n", err)
-		return
+	allocations := []struct {
+		wallet *Wallet
+		amount float64
+	}{
+		{aliceWallet, 50.0},
+		{aliceWallet, 50.0},
+		{bobWallet, 50.0},
+		{charlieWallet, 30.0},
+	}
+	for _, allocation := range allocations {
+		coinbase := bc.AddCoinbaseTransaction(allocation.wallet.Address, allocation.amount)
+		if err := bc.AddBlock([]*Transaction{coinbase}); err != nil {
+			fmt.Printf("Error adding coinbase block: %v\n", err)
+			return
+		}
 	}
 
 	fmt.Printf("   Alice received: 100.0 coins\n")
@@ -93,8 +150,12 @@ func main() {
 	}
 	fmt.Printf("   Transaction 2: %s\n", tx2.String())
 
-	// Transaction 3: Charlie sends 3 coins to Alice (no fee)
-	tx3 := NewTransaction(charlieWallet.Address, aliceWallet.Address, 3.0)
+	// Transaction 3: Charlie sends 3 coins to Alice (no fee) as a typed transfer with a memo
+	tx3, err := NewTypedTransaction(charlieWallet.Address, aliceWallet.Address, 3.0, 0, &TransferPayload{Memo: "lunch"})
+	if err != nil {
+		fmt.Printf("Error creating transaction 3: %v\n", err)
+		return
+	}
 	if err := bc.ValidateTransaction(tx3); err != nil {
 		fmt.Printf("Error: Transaction 3 is invalid: %v\n", err)
 		return
@@ -104,6 +165,20 @@ func main() {
 		return
 	}
 	fmt.Printf("   Transaction 3: %s\n", tx3.String())
+
+	// Typed transactions have a canonical binary encoding that decodes to the same hash
+	encodedTx, err := tx3.MarshalBinary()
+	if err != nil {
+		fmt.Printf("Error encoding transaction 3: %v\n", err)
+		return
+	}
+	var decodedTx Transaction
+	if err := decodedTx.UnmarshalBinary(encodedTx); err != nil {
+		fmt.Printf("Error decoding transaction 3: %v\n", err)
+		return
+	}
+	fmt.Printf("   Encoded as %d bytes; decoded hash matches: %v, signature valid: %v\n",
+		len(encodedTx), bytes.Equal(decodedTx.Hash(), tx3.Hash()), decodedTx.Verify())
 	time.Sleep(1 * time.Second)
 
 	// Add blocks with transactions and miner rewards
@@ -164,6 +239,9 @@ func main() {
 	} else {
 		fmt.Println("   PROBLEM: Blockchain is invalid! Something is wrong.")
 	}
+	verifyStats := SignatureVerificationStats()
+	fmt.Printf("   Signature checks: %d (%d verified, %d cached from the mempool or earlier blocks), %d worker(s), %.0f tx/s\n",
+		verifyStats.Transactions, verifyStats.Verified, verifyStats.CacheHits, verifyStats.Workers, verifyStats.Throughput())
 
 	// Test insufficient balance
 	fmt.Println("\n10. Testing insufficient balance scenario...")
@@ -177,9 +255,9 @@ func main() {
 	// Test tampering detection - Scenario 1: Modify transaction without recalculating Merkle root
 	fmt.Println("\n11. Testing tampering detection...")
 
-	// Use block 2 (first transaction block after coinbase)
-	// Block 0: Genesis, Block 1: Coinbase, Block 2: tx1, Block 3: tx2, Block 4: tx3
-	tamperBlockIndex := 2
+	// Use block 5 (first transaction block after the coinbase blocks)
+	// Block 0: Genesis, Blocks 1-4: Coinbase, Block 5: tx1, Block 6: tx2, Block 7: tx3
+	tamperBlockIndex := 5
 	if len(bc.Blocks) <= tamperBlockIndex || len(bc.Blocks[tamperBlockIndex].Transactions) == 0 {
 		fmt.Println("   ERROR: Cannot find block for tampering test")
 		return
@@ -232,7 +310,8 @@ func main() {
 	} else {
 		fmt.Println("   GOOD: Tampering was successfully detected!")
 		fmt.Println("   The security system worked correctly - it detected the tampering.")
-		fmt.Println("   Reason: Proof of work invalid - hash doesn't meet difficulty requirement")
+		fmt.Println("   Reason: The tampered coinbase creates more than the block reward plus fees")
+		fmt.Println("   (and the block's proof of work no longer meets the difficulty requirement)")
 	}
 
 	// Demo: Mempool functionality
@@ -400,7 +479,7 @@ func main() {
 
 		// Call get function
 		fmt.Println("\n   Calling get(key='name')...")
-		result, err := bc.CallContract(simpleContract.GetAddress(), "get", []string{"name"}, aliceWallet.Address, 0)
+		result, err := bc.ViewContract(simpleContract.GetAddress(), "get", []string{"name"}, aliceWallet.Address)
 		if err != nil {
 			fmt.Printf("Error calling contract: %v\n", err)
 		} else {
@@ -436,7 +515,7 @@ func main() {
 
 		// Check balance
 		fmt.Println("\n   Checking Charlie's token balance...")
-		balance, err := bc.CallContract(tokenContract.GetAddress(), "balanceOf", []string{charlieWallet.Address}, charlieWallet.Address, 0)
+		balance, err := bc.ViewContract(tokenContract.GetAddress(), "balanceOf", []string{charlieWallet.Address}, charlieWallet.Address)
 		if err != nil {
 			fmt.Printf("Error calling contract: %v\n", err)
 		} else {
@@ -479,7 +558,7 @@ func main() {
 
 		// Get results
 		fmt.Println("\n   Getting voting results...")
-		results, err := bc.CallContract(votingContract.GetAddress(), "getResults", []string{}, charlieWallet.Address, 0)
+		results, err := bc.ViewContract(votingContract.GetAddress(), "getResults", []string{}, charlieWallet.Address)
 		if err != nil {
 			fmt.Printf("Error calling contract: %v\n", err)
 		} else {
@@ -487,6 +566,41 @@ func main() {
 		}
 	}
 
+	// Deploy an NFT contract
+	fmt.Println("\n   Deploying NFT Contract...")
+	nftContract, err := bc.DeployContract(aliceWallet.Address, ContractTypeNFT, "nft_contract")
+	if err != nil {
+		fmt.Printf("Error deploying contract: %v\n", err)
+	} else {
+		fmt.Printf("   Contract deployed at: %s\n", nftContract.GetAddress())
+
+		// Mint an NFT
+		fmt.Println("\n   Minting NFT #1 to Alice...")
+		tx7 := NewContractCallTransaction(aliceWallet.Address, nftContract.GetAddress(), "mint", []string{aliceWallet.Address, "ipfs://artwork-1"}, 0, 0.1)
+		if err := aliceWallet.SignTransaction(tx7); err == nil {
+			if err := bc.AddBlockWithReward([]*Transaction{tx7}, minerWallet.Address); err != nil {
+				fmt.Printf("Error adding block: %v\n", err)
+			}
+		}
+
+		// Transfer the NFT
+		fmt.Println("\n   Alice transferring NFT #1 to Bob...")
+		tx8 := NewContractCallTransaction(aliceWallet.Address, nftContract.GetAddress(), "transfer", []string{bobWallet.Address, "1"}, 0, 0.1)
+		if err := aliceWallet.SignTransaction(tx8); err == nil {
+			if err := bc.AddBlockWithReward([]*Transaction{tx8}, minerWallet.Address); err != nil {
+				fmt.Printf("Error adding block: %v\n", err)
+			}
+		}
+
+		owner, err := bc.ViewContract(nftContract.GetAddress(), "ownerOf", []string{"1"}, bobWallet.Address)
+		if err != nil {
+			fmt.Printf("Error calling contract: %v\n", err)
+		} else {
+			uri, _ := bc.ViewContract(nftContract.GetAddress(), "tokenURI", []string{"1"}, bobWallet.Address)
+			fmt.Printf("   NFT #1 owner: %s (metadata: %v)\n", owner, uri)
+		}
+	}
+
 	// Demo: Web3 Integration
 	fmt.Println("\n19. Demonstrating Web3 Integration...")
 
@@ -504,7 +618,11 @@ func main() {
 		fmt.Println("   - eth_getTransactionCount - Get transaction count")
 		fmt.Println("   - eth_sendTransaction - Send new transaction")
 		fmt.Println("   - eth_call - Execute contract call (read-only)")
+		fmt.Println("   - contract_getABI - Get contract ABI")
 		fmt.Println("   - eth_getCode - Get contract code")
+		fmt.Println("   - eth_getTransactionReceipt - Get contract transaction receipt")
+		fmt.Println("   - eth_getLogs - Get contract event logs")
+		fmt.Println("   - eth_newFilter / eth_getFilterChanges - Poll for new logs")
 
 		fmt.Println("\n   Example curl commands:")
 		fmt.Println("   curl -X POST http://localhost:8545 \\")
@@ -544,8 +662,20 @@ func main() {
 
 		// Create block using PBFT consensus
 		fmt.Println("\n   Creating block using PBFT consensus...")
-		if err := bc.CreateBlockWithPBFT([]*Transaction{pbftTx1}, pbftNodes, aliceWallet.Address); err != nil {
+		// Validators sign their messages; with secp256k1 keys they also aggregate their commit votes into a quorum certificate
+		pbftValidators := []*Wallet{aliceWallet, bobWallet, charlieWallet, minerWallet}
+		validatorKeys, keyErr := ValidatorKeys(pbftValidators)
+		if err := bc.CreateBlockWithPBFTValidators([]*Transaction{pbftTx1}, pbftValidators, aliceWallet.Address); err != nil {
 			fmt.Printf("Error creating PBFT block: %v\n", err)
+		} else if keyErr == nil {
+			// A light client checks finality with the validator keys alone
+			pbftBlock := bc.Blocks[len(bc.Blocks)-1]
+			fmt.Println("   Light client verifying the block's quorum certificate...")
+			if err := pbftBlock.VerifyQuorumCertificate(validatorKeys); err != nil {
+				fmt.Printf("   Quorum certificate rejected: %v\n", err)
+			} else {
+				fmt.Printf("   Block #%d is final: one aggregate signature from %d validators\n", pbftBlock.Index, pbftBlock.QuorumCert.SignerCount())
+			}
 		}
 	}
 
@@ -554,6 +684,7 @@ func main() {
 	fmt.Println("   Byzantine fault tolerance (tolerates malicious nodes)")
 	fmt.Println("   Deterministic finality (no forks)")
 	fmt.Println("   Quorum-based consensus (2f+1 votes required)")
+	fmt.Println("   Quorum certificates (MuSig2 aggregate signature + signer bitmap)")
 
 	fmt.Println("\n=== Demo Complete ===")
 
@@ -589,7 +720,8 @@ func main() {
 
 			// Create block using Raft consensus
 			fmt.Println("\n   Creating block using Raft consensus...")
-			if err := bc.CreateBlockWithRaft([]*Transaction{raftTx1, raftTx2}, aliceWallet.Address, raftNodes); err != nil {
+			raftValidators := []*Wallet{aliceWallet, bobWallet, charlieWallet, minerWallet}
+			if err := bc.CreateBlockWithRaftValidators([]*Transaction{raftTx1, raftTx2}, aliceWallet.Address, raftValidators); err != nil {
 				fmt.Printf("Error creating Raft block: %v\n", err)
 			}
 		}
@@ -614,9 +746,9 @@ func main() {
 
 	fmt.Println("\n   Setting up payment channel...")
 	// Alice and Bob want to create a payment channel
-	channel, err := bc.ChannelManager.CreateChannel(
-		aliceWallet.Address,
-		bobWallet.Address,
+	channel, err := bc.ChannelManager.CreateChannelWithKeys(
+		aliceWallet.MultisigKey(),
+		bobWallet.MultisigKey(),
 		20.0, // Alice deposits 20 coins
 		10.0, // Bob deposits 10 coins
 		24*time.Hour, // 24 hour timeout
@@ -638,8 +770,8 @@ func main() {
 			fmt.Printf("Error: %v\n", err)
 		} else {
 			// Sign the new state
-			sig1, _ := channel.SignState(newState1, aliceWallet.Address)
-			sig2, _ := channel.SignState(newState1, bobWallet.Address)
+			sig1, _ := channel.SignStateWith(newState1, aliceWallet.Signer)
+			sig2, _ := channel.SignStateWith(newState1, bobWallet.Signer)
 
 			// Commit the signed state
 			signedState := &ChannelSignature{
@@ -657,8 +789,8 @@ func main() {
 		if err != nil {
 			fmt.Printf("Error: %v\n", err)
 		} else {
-			sig1, _ := channel.SignState(newState2, aliceWallet.Address)
-			sig2, _ := channel.SignState(newState2, bobWallet.Address)
+			sig1, _ := channel.SignStateWith(newState2, aliceWallet.Signer)
+			sig2, _ := channel.SignStateWith(newState2, bobWallet.Signer)
 
 			signedState := &ChannelSignature{
 				State:      newState2,
@@ -675,8 +807,8 @@ func main() {
 		if err != nil {
 			fmt.Printf("Error: %v\n", err)
 		} else {
-			sig1, _ := channel.SignState(newState3, aliceWallet.Address)
-			sig2, _ := channel.SignState(newState3, bobWallet.Address)
+			sig1, _ := channel.SignStateWith(newState3, aliceWallet.Signer)
+			sig2, _ := channel.SignStateWith(newState3, bobWallet.Signer)
 
 			signedState := &ChannelSignature{
 				State:      newState3,
@@ -700,8 +832,8 @@ func main() {
 
 			newState, err := channel.MicroPayment(sender, amount)
 			if err == nil {
-				sig1, _ := channel.SignState(newState, aliceWallet.Address)
-				sig2, _ := channel.SignState(newState, bobWallet.Address)
+				sig1, _ := channel.SignStateWith(newState, aliceWallet.Signer)
+				sig2, _ := channel.SignStateWith(newState, bobWallet.Signer)
 
 				signedState := &ChannelSignature{
 					State:      newState,
@@ -719,10 +851,12 @@ func main() {
 
 		// Close the channel
 		fmt.Println("\n   Closing payment channel...")
+		finalSig1, _ := channel.SignStateWith(channel.State, aliceWallet.Signer)
+		finalSig2, _ := channel.SignStateWith(channel.State, bobWallet.Signer)
 		finalSig := &ChannelSignature{
 			State:      channel.State,
-			Signature1: "final_signature_1",
-			Signature2: "final_signature_2",
+			Signature1: finalSig1,
+			Signature2: finalSig2,
 		}
 		if err := channel.CloseChannel(finalSig); err != nil {
 			fmt.Printf("Error closing channel: %v\n", err)
@@ -762,13 +896,15 @@ func main() {
 
 	// Give Alice coins on mainnet
 	fmt.Println("\n   Funding Alice on Mainnet...")
-	coinbase := mainnet.AddCoinbaseTransaction(aliceWallet.Address, 100.0)
-	mainnet.AddBlock([]*Transaction{coinbase})
+	for i := 0; i < 2; i++ {
+		coinbase := mainnet.AddCoinbaseTransaction(aliceWallet.Address, BlockReward)
+		mainnet.AddBlock([]*Transaction{coinbase})
+	}
 	fmt.Printf("   Alice's Mainnet balance: %.2f\n", mainnet.GetBalance(aliceWallet.Address))
 	time.Sleep(500 * time.Millisecond)
 
 	// Create bridge between Mainnet and Sidechain
-	bridge := NewBridge(
+	bridge, err := NewBridge(
 		"eth-bsc-bridge",
 		mainnet,
 		sidechain,
@@ -776,22 +912,37 @@ func main() {
 		"Sidechain",
 		3, // Require 3 validator signatures
 	)
+	if err != nil {
+		fmt.Printf("Error creating bridge: %v\n", err)
+		return
+	}
 
 	// Add validators to the bridge
 	fmt.Println("\n   Adding bridge validators...")
-	bridge.AddValidator("validator1", bobWallet.Address, 1000.0, 1)
-	bridge.AddValidator("validator2", charlieWallet.Address, 1000.0, 1)
-	bridge.AddValidator("validator3", minerWallet.Address, 1000.0, 1)
-	bridge.AddValidator("validator4", daveWallet.Address, 500.0, 1)
+	bridgeValidators := []struct {
+		id     string
+		wallet *Wallet
+		stake  float64
+	}{
+		{"validator1", bobWallet, 1000.0},
+		{"validator2", charlieWallet, 1000.0},
+		{"validator3", minerWallet, 1000.0},
+		{"validator4", daveWallet, 500.0},
+	}
+	for _, v := range bridgeValidators {
+		if err := bridge.AddValidator(v.id, v.wallet, v.stake, 1); err != nil {
+			fmt.Printf("Error adding bridge validator: %v\n", err)
+		}
+	}
 	time.Sleep(500 * time.Millisecond)
 
 	// Lock funds on Mainnet
 	fmt.Println("\n   Transferring funds from Mainnet to Sidechain...")
 	bridgeTx, err := bridge.LockFunds(
+		aliceWallet,
 		aliceWallet.Address,
-		aliceWallet.Address,
-		50.0,  // Amount
-		"ETH", // Token
+		50.0,        // Amount
+		NativeAsset, // Token (the native coin)
 	)
 	if err != nil {
 		fmt.Printf("Error locking funds: %v\n", err)
@@ -801,19 +952,14 @@ func main() {
 		// Validators approve the transaction
 		fmt.Println("\n   Bridge validators approving transaction...")
 
-		// Simulate validator approvals
-		validators := []struct {
-			id string
-			sig string
-		}{
-			{"validator1", "sig_1_" + bridgeTx.TxID[:8]},
-			{"validator2", "sig_2_" + bridgeTx.TxID[:8]},
-			{"validator3", "sig_3_" + bridgeTx.TxID[:8]},
-		}
+		// Three of the four validators approve with their keys; their approvals are aggregated into one certificate
+		approvers := []*Wallet{bobWallet, charlieWallet, minerWallet}
 
-		for _, v := range validators {
+		for _, v := range approvers {
 			time.Sleep(300 * time.Millisecond)
-			bridge.ApproveTransaction(bridgeTx.TxID, v.id, v.sig)
+			if err := bridge.ApproveTransaction(bridgeTx.TxID, v); err != nil {
+				fmt.Printf("Error approving bridge transaction: %v\n", err)
+			}
 		}
 
 		time.Sleep(500 * time.Millisecond)
@@ -824,8 +970,8 @@ func main() {
 			fmt.Printf("Error unlocking funds: %v\n", err)
 		} else {
 			fmt.Printf("\n   ✓ Transfer complete!\n")
-			fmt.Printf("   Alice's Mainnet balance: %.2f ETH (locked)\n", mainnet.GetBalance(aliceWallet.Address))
-			fmt.Printf("   Alice's Sidechain balance: %.2f ETH\n", sidechain.GetBalance(aliceWallet.Address))
+			fmt.Printf("   Alice's Mainnet balance: %.2f coins (locked)\n", mainnet.GetBalance(aliceWallet.Address))
+			fmt.Printf("   Alice's Sidechain balance: %.2f coins\n", sidechain.GetBalance(aliceWallet.Address))
 		}
 
 		time.Sleep(1 * time.Second)
@@ -834,10 +980,10 @@ func main() {
 		fmt.Println("\n   Performing reverse transfer (Sidechain -> Mainnet)...")
 
 		reverseTx, err := bridge.ReverseTransfer(
-			aliceWallet.Address,
+			aliceWallet,
 			aliceWallet.Address,
 			20.0,
-			"ETH",
+			NativeAsset,
 		)
 		if err != nil {
 			fmt.Printf("Error creating reverse transfer: %v\n", err)
@@ -846,9 +992,11 @@ func main() {
 
 			// Validators approve reverse transaction
 			fmt.Println("\n   Validators approving reverse transfer...")
-			for _, v := range validators {
+			for _, v := range approvers {
 				time.Sleep(200 * time.Millisecond)
-				bridge.ApproveTransaction(reverseTx.TxID, v.id, v.sig+"_rev")
+				if err := bridge.ApproveTransaction(reverseTx.TxID, v); err != nil {
+					fmt.Printf("Error approving reverse transfer: %v\n", err)
+				}
 			}
 
 			time.Sleep(300 * time.Millisecond)
@@ -860,8 +1008,8 @@ func main() {
 					fmt.Printf("Error unlocking reverse transfer: %v\n", err)
 				} else {
 					fmt.Printf("\n   ✓ Reverse transfer complete!\n")
-					fmt.Printf("   Alice's Mainnet balance: %.2f ETH\n", mainnet.GetBalance(aliceWallet.Address))
-					fmt.Printf("   Alice's Sidechain balance: %.2f ETH\n", sidechain.GetBalance(aliceWallet.Address))
+					fmt.Printf("   Alice's Mainnet balance: %.2f coins\n", mainnet.GetBalance(aliceWallet.Address))
+					fmt.Printf("   Alice's Sidechain balance: %.2f coins\n", sidechain.GetBalance(aliceWallet.Address))
 				}
 			}
 		}
@@ -877,7 +1025,7 @@ func main() {
 		fmt.Printf("   Required Signatures: %d\n", stats["required_sigs"])
 		fmt.Printf("   Pending Transactions: %d\n", stats["pending_txs"])
 		fmt.Printf("   Completed Transactions: %d\n", stats["completed_txs"])
-		fmt.Printf("   Total Volume: %.2f ETH\n", stats["total_volume"])
+		fmt.Printf("   Total Volume: %.2f coins\n", stats["total_volume"])
 		fmt.Printf("   Bridge Fee: %.1f%%\n", stats["fee"].(float64)*100)
 
 		fmt.Println("\n   Cross-Chain Bridge Benefits:")
@@ -896,3 +1044,85 @@ func main() {
 
 	fmt.Println("\n=== Demo Complete ===")
 }
+
+// printAddress prints the type, internal form and encoded form of an address given in either form
+func printAddress(input string) error {
+	address, err := ParseAddress(input)
+	if err != nil {
+		return err
+	}
+	addressType, err := AddressTypeOf(address)
+	if err != nil {
+		return err
+	}
+	encoded, err := EncodeAddress(address)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Type:     %s\n", addressType)
+	fmt.Printf("Hex:      %s\n", address)
+	fmt.Printf("Encoded:  %s\n", encoded)
+	return nil
+}
+
+// readPassphrase reads the keystore passphrase from the environment, or else from standard input,
+// prompting with echo turned off when it is a terminal. It is never taken as a flag, which would
+// show it in the process list and shell history.
+func readPassphrase() (string, error) {
+	if passphrase, ok := os.LookupEnv(passphraseEnv); ok {
+		return passphrase, nil
+	}
+	if info, err := os.Stdin.Stat(); err == nil && info.Mode()&os.ModeCharDevice != 0 {
+		fmt.Print("Keystore passphrase: ")
+		if err := setTerminalEcho(false); err == nil {
+			defer setTerminalEcho(true)
+		}
+		defer fmt.Println()
+	}
+	line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil && line == "" {
+		return "", fmt.Errorf("set %s or enter the passphrase on standard input: %v", passphraseEnv, err)
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
+
+// setTerminalEcho turns the echo of typed characters on the terminal on or off
+func setTerminalEcho(on bool) error {
+	mode := "-echo"
+	if on {
+		mode = "echo"
+	}
+	cmd := exec.Command("stty", mode)
+	cmd.Stdin = os.Stdin
+	return cmd.Run()
+}
+
+// loadDemoWallets unlocks the first n accounts of a keystore, creating any that are missing
+func loadDemoWallets(dir, passphrase string, n int) ([]*Wallet, error) {
+	keystore, err := NewKeystore(dir, StandardPBKDF2Iterations)
+	if err != nil {
+		return nil, err
+	}
+	accounts, err := keystore.Accounts()
+	if err != nil {
+		return nil, err
+	}
+
+	wallets := make([]*Wallet, 0, n)
+	for i := 0; i < n; i++ {
+		if i < len(accounts) {
+			wallet, err := keystore.Unlock(accounts[i], passphrase)
+			if err != nil {
+				return nil, err
+			}
+			wallets = append(wallets, wallet)
+			continue
+		}
+		wallet, err := keystore.NewAccount(passphrase)
+		if err != nil {
+			return nil, err
+		}
+		wallets = append(wallets, wallet)
+	}
+	return wallets, nil
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"crypto/pbkdf2"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/sha512"
+	_ "embed"
+	"fmt"
+	"math/big"
+	"strings"
+)
+
+// mnemonicWordlistText is the BIP-39 English wordlist, one word per line
+//
+//go:embed mnemonic_english.txt
+var mnemonicWordlistText string
+
+// mnemonicWordlist is the 2048-word BIP-39 English wordlist in index order
+var mnemonicWordlist = strings.Fields(mnemonicWordlistText)
+
+// mnemonicWordIndex maps each wordlist word to its 11-bit index
+var mnemonicWordIndex = func() map[string]int {
+	index := make(map[string]int, len(mnemonicWordlist))
+	for i, word := range mnemonicWordlist {
+		index[word] = i
+	}
+	return index
+}()
+
+// Mnemonic entropy sizes in bits (12 and 24 words)
+const (
+	MnemonicEntropy12Words = 128
+	MnemonicEntropy24Words = 256
+)
+
+// mnemonicSeedIterations is the PBKDF2 iteration count BIP-39 uses to stretch a mnemonic into a seed
+const mnemonicSeedIterations = 2048
+
+// NewMnemonic generates a random BIP-39 mnemonic from entropyBits of entropy (128 to 256, a multiple of 32)
+func NewMnemonic(entropyBits int) (string, error) {
+	if entropyBits < 128 || entropyBits > 256 || entropyBits%32 != 0 {
+		return "", fmt.Errorf("entropy must be 128 to 256 bits in steps of 32, got %d", entropyBits)
+	}
+	entropy := make([]byte, entropyBits/8)
+	if _, err := rand.Read(entropy); err != nil {
+		return "", err
+	}
+	return MnemonicFromEntropy(entropy)
+}
+
+// MnemonicFromEntropy encodes entropy as BIP-39 words: the entropy followed by the first
+// len/32 bits of its SHA-256 as checksum, split into 11-bit word indices
+func MnemonicFromEntropy(entropy []byte) (string, error) {
+	entropyBits := len(entropy) * 8
+	if entropyBits < 128 || entropyBits > 256 || entropyBits%32 != 0 {
+		return "", fmt.Errorf("entropy must be 16 to 32 bytes in steps of 4, got %d", len(entropy))
+	}
+	checksumBits := entropyBits / 32
+	hash := sha256.Sum256(entropy)
+
+	bits := new(big.Int).SetBytes(entropy)
+	bits.Lsh(bits, uint(checksumBits))
+	bits.Or(bits, big.NewInt(int64(hash[0]>>(8-checksumBits))))
+
+	wordCount := (entropyBits + checksumBits) / 11
+	words := make([]string, wordCount)
+	mask := big.NewInt(2047)
+	for i := wordCount - 1; i >= 0; i-- {
+		words[i] = mnemonicWordlist[new(big.Int).And(bits, mask).Int64()]
+		bits.Rsh(bits, 11)
+	}
+	return strings.Join(words, " "), nil
+}
+
+// MnemonicToEntropy decodes a mnemonic back to its entropy, checking its words and checksum
+func MnemonicToEntropy(mnemonic string) ([]byte, error) {
+	words := strings.Fields(mnemonic)
+	switch len(words) {
+	case 12, 15, 18, 21, 24:
+	default:
+		return nil, fmt.Errorf("mnemonic must have 12, 15, 18, 21 or 24 words, got %d", len(words))
+	}
+
+	bits := new(big.Int)
+	for _, word := range words {
+		index, known := mnemonicWordIndex[word]
+		if !known {
+			return nil, fmt.Errorf("unknown mnemonic word: %q", word)
+		}
+		bits.Lsh(bits, 11)
+		bits.Or(bits, big.NewInt(int64(index)))
+	}
+
+	checksumBits := len(words) * 11 / 33
+	entropyBits := len(words)*11 - checksumBits
+	checksum := new(big.Int).And(bits, big.NewInt(int64(1<<checksumBits-1))).Int64()
+	bits.Rsh(bits, uint(checksumBits))
+
+	entropy := make([]byte, entropyBits/8)
+	bits.FillBytes(entropy)
+	hash := sha256.Sum256(entropy)
+	if int64(hash[0]>>(8-checksumBits)) != checksum {
+		return nil, fmt.Errorf("invalid mnemonic checksum")
+	}
+	return entropy, nil
+}
+
+// ValidateMnemonic checks that a mnemonic uses wordlist words and has a valid checksum
+func ValidateMnemonic(mnemonic string) error {
+	_, err := MnemonicToEntropy(mnemonic)
+	return err
+}
+
+// MnemonicToSeed validates a mnemonic and stretches it with an optional passphrase into a 64-byte seed.
+// Words are joined by single spaces; the passphrase is used as given (BIP-39's NFKD normalization
+// only affects non-ASCII passphrases).
+func MnemonicToSeed(mnemonic, passphrase string) ([]byte, error) {
+	if err := ValidateMnemonic(mnemonic); err != nil {
+		return nil, err
+	}
+	normalized := strings.Join(strings.Fields(mnemonic), " ")
+	return pbkdf2.Key(sha512.New, normalized, []byte("mnemonic"+passphrase), mnemonicSeedIterations, 64)
+}
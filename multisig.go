@@ -0,0 +1,238 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// Event signatures emitted by multisig wallet contracts
+const (
+	EventSubmission   = "Submission(address,uint256)"
+	EventConfirmation = "Confirmation(address,uint256)"
+	EventRevocation   = "Revocation(address,uint256)"
+	EventExecution    = "Execution(uint256)"
+)
+
+// executeMultisig executes an M-of-N multisig wallet contract.
+// Owners submit transactions (native transfers, or contract calls when data is set) which
+// can be executed once at least threshold owners have confirmed them.
+func (sc *SmartContract) executeMultisig(function string, ctx *ContractContext) (interface{}, error) {
+	if function == ContractConstructor {
+		return sc.initMultisig(ctx)
+	}
+
+	sc.mu.RLock()
+	owners, initialized := sc.State["owners"].([]string)
+	sc.mu.RUnlock()
+	if !initialized {
+		return nil, fmt.Errorf("multisig not initialized")
+	}
+	threshold, _ := sc.getStateFloat("threshold")
+	transactions := sc.getMultisigTransactions()
+
+	switch function {
+	case "deposit":
+		if ctx.Value <= 0 {
+			return nil, fmt.Errorf("deposit value must be greater than zero")
+		}
+		sc.emitLog(ctx, EventDeposit, []string{ctx.Caller}, formatAmount(ctx.Value))
+		return fmt.Sprintf("Deposited %.2f coins to multisig", ctx.Value), nil
+
+	case "submit":
+		if !containsString(owners, ctx.Caller) {
+			return nil, fmt.Errorf("only owners can submit transactions")
+		}
+		if err := validateArgsCount(ctx.Args, 2, "submit"); err != nil {
+			return nil, err
+		}
+		to := ctx.StringArg(0)
+		value, err := ctx.AmountArg(1)
+		if err != nil {
+			return nil, err
+		}
+		data := ctx.CallDataArg(2)
+		if data == "" && value == 0 {
+			return nil, fmt.Errorf("transaction must transfer value or call a contract")
+		}
+
+		count, _ := sc.getStateFloat("txCount")
+		txID := uint64(count)
+		key := strconv.FormatUint(txID, 10)
+
+		sc.mu.Lock()
+		sc.State["txCount"] = count + 1
+		transactions[key] = map[string]interface{}{
+			"to":            to,
+			"value":         value,
+			"data":          data,
+			"executed":      false,
+			"confirmations": []string{ctx.Caller}, // The submitter confirms automatically
+		}
+		sc.mu.Unlock()
+
+		sc.emitLog(ctx, EventSubmission, []string{ctx.Caller, key}, "")
+		sc.emitLog(ctx, EventConfirmation, []string{ctx.Caller, key}, "")
+		return txID, nil
+
+	case "confirm", "revoke":
+		if !containsString(owners, ctx.Caller) {
+			return nil, fmt.Errorf("only owners can %s transactions", function)
+		}
+		key, tx, err := sc.multisigTransaction(ctx, transactions, true)
+		if err != nil {
+			return nil, err
+		}
+
+		sc.mu.Lock()
+		confirmations := tx["confirmations"].([]string)
+		confirmed := containsString(confirmations, ctx.Caller)
+		if function == "confirm" {
+			if confirmed {
+				sc.mu.Unlock()
+				return nil, fmt.Errorf("transaction %s already confirmed by caller", key)
+			}
+			tx["confirmations"] = append(confirmations, ctx.Caller)
+		} else {
+			if !confirmed {
+				sc.mu.Unlock()
+				return nil, fmt.Errorf("transaction %s not confirmed by caller", key)
+			}
+			remaining := make([]string, 0, len(confirmations)-1)
+			for _, owner := range confirmations {
+				if owner != ctx.Caller {
+					remaining = append(remaining, owner)
+				}
+			}
+			tx["confirmations"] = remaining
+		}
+		count := len(tx["confirmations"].([]string))
+		sc.mu.Unlock()
+
+		if function == "confirm" {
+			sc.emitLog(ctx, EventConfirmation, []string{ctx.Caller, key}, "")
+			return fmt.Sprintf("Confirmed transaction %s (%d/%d)", key, count, int(threshold)), nil
+		}
+		sc.emitLog(ctx, EventRevocation, []string{ctx.Caller, key}, "")
+		return fmt.Sprintf("Revoked confirmation of transaction %s (%d/%d)", key, count, int(threshold)), nil
+
+	case "execute":
+		if !containsString(owners, ctx.Caller) {
+			return nil, fmt.Errorf("only owners can execute transactions")
+		}
+		key, tx, err := sc.multisigTransaction(ctx, transactions, true)
+		if err != nil {
+			return nil, err
+		}
+
+		sc.mu.RLock()
+		to, _ := tx["to"].(string)
+		value, _ := tx["value"].(float64)
+		data, _ := tx["data"].(string)
+		count := len(tx["confirmations"].([]string))
+		sc.mu.RUnlock()
+		if count < int(threshold) {
+			return nil, fmt.Errorf("transaction %s has %d of %d required confirmations", key, count, int(threshold))
+		}
+
+		result, err := sc.executeStoredCall(ctx, tx, to, value, data)
+		if err != nil {
+			return nil, fmt.Errorf("execution of transaction %s failed: %v", key, err)
+		}
+
+		sc.emitLog(ctx, EventExecution, []string{key}, "")
+		if result != nil {
+			return result, nil
+		}
+		return fmt.Sprintf("Executed transaction %s: sent %.2f coins to %s", key, value, truncateAddress(to)), nil
+
+	case "getTransaction":
+		_, tx, err := sc.multisigTransaction(ctx, transactions, false)
+		if err != nil {
+			return nil, err
+		}
+		sc.mu.RLock()
+		defer sc.mu.RUnlock()
+		return cloneState(tx), nil
+
+	case "getOwners":
+		return map[string]interface{}{
+			"owners":    append([]string(nil), owners...),
+			"threshold": int(threshold),
+		}, nil
+
+	default:
+		return nil, fmt.Errorf("unknown function: %s", function)
+	}
+}
+
+// initMultisig sets the owners and confirmation threshold; it runs once, at deployment
+func (sc *SmartContract) initMultisig(ctx *ContractContext) (interface{}, error) {
+	if ctx.Caller != sc.Deployer {
+		return nil, fmt.Errorf("only deployer can initialize the multisig")
+	}
+	sc.mu.RLock()
+	_, initialized := sc.State["owners"]
+	sc.mu.RUnlock()
+	if initialized {
+		return nil, fmt.Errorf("multisig already initialized")
+	}
+	if err := validateArgsCount(ctx.Args, 2, ContractConstructor); err != nil {
+		return nil, err
+	}
+
+	threshold, err := ctx.UintArg(0)
+	if err != nil {
+		return nil, err
+	}
+	owners := ctx.StringListArg(1)
+	unique := make([]string, 0, len(owners))
+	for _, owner := range owners {
+		if containsString(unique, owner) {
+			return nil, fmt.Errorf("duplicate owner: %s", owner)
+		}
+		unique = append(unique, owner)
+	}
+	if threshold == 0 || threshold > uint64(len(unique)) {
+		return nil, fmt.Errorf("threshold must be between 1 and %d", len(unique))
+	}
+
+	sc.mu.Lock()
+	sc.State["owners"] = unique
+	sc.State["threshold"] = float64(threshold)
+	sc.State["txCount"] = 0.0
+	sc.mu.Unlock()
+	return fmt.Sprintf("Multisig initialized: %d-of-%d", threshold, len(unique)), nil
+}
+
+func (sc *SmartContract) getMultisigTransactions() map[string]interface{} {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+	if _, exists := sc.State["transactions"]; !exists {
+		sc.State["transactions"] = make(map[string]interface{})
+	}
+	return sc.State["transactions"].(map[string]interface{})
+}
+
+// multisigTransaction reads the transaction ID argument and returns the transaction,
+// rejecting already executed ones when pendingOnly is set
+func (sc *SmartContract) multisigTransaction(ctx *ContractContext, transactions map[string]interface{}, pendingOnly bool) (string, map[string]interface{}, error) {
+	if err := validateArgsCount(ctx.Args, 1, "transaction"); err != nil {
+		return "", nil, err
+	}
+	txID, err := ctx.UintArg(0)
+	if err != nil {
+		return "", nil, err
+	}
+	key := strconv.FormatUint(txID, 10)
+
+	sc.mu.RLock()
+	defer sc.mu.RUnlock()
+	tx, exists := transactions[key].(map[string]interface{})
+	if !exists {
+		return "", nil, fmt.Errorf("transaction %s not found", key)
+	}
+	if executed, _ := tx["executed"].(bool); executed && pendingOnly {
+		return "", nil, fmt.Errorf("transaction %s already executed", key)
+	}
+	return key, tx, nil
+}
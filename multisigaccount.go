@@ -0,0 +1,238 @@
+package main
+
+import (
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// MaxMultisigKeys is the largest number of keys a multisig account can have
+const MaxMultisigKeys = 16
+
+// MultisigAddressPrefix starts every multisig address, followed by 40 hex characters
+const MultisigAddressPrefix = "M"
+
+// MultisigKey is one public key of a multisig account, in any registered signature scheme
+type MultisigKey struct {
+	Scheme    SignatureScheme `json:"scheme"`
+	PublicKey string          `json:"public_key"` // Hex-encoded, as returned by Signer.PublicKey
+}
+
+// MultisigKeyOf returns the multisig key of a signer
+func MultisigKeyOf(signer Signer) MultisigKey {
+	return MultisigKey{Scheme: signer.Scheme(), PublicKey: hex.EncodeToString(signer.PublicKey())}
+}
+
+// Address returns the single-key address of the key
+func (k MultisigKey) Address() (string, error) {
+	info, err := GetSignatureScheme(k.Scheme)
+	if err != nil {
+		return "", err
+	}
+	publicKey, err := hex.DecodeString(k.PublicKey)
+	if err != nil {
+		return "", fmt.Errorf("invalid public key encoding")
+	}
+	return info.Address(publicKey)
+}
+
+// less orders keys by scheme, then by public key bytes
+func (k MultisigKey) less(other MultisigKey) bool {
+	if k.Scheme != other.Scheme {
+		return k.Scheme < other.Scheme
+	}
+	return strings.ToLower(k.PublicKey) < strings.ToLower(other.PublicKey)
+}
+
+// MultisigAccount is an address controlled by Threshold of its Keys (M of N). The keys are kept
+// sorted, so the same keys and threshold always give the same address whatever order they came in.
+type MultisigAccount struct {
+	Threshold int           `json:"threshold"`
+	Keys      []MultisigKey `json:"keys"`
+}
+
+// NewMultisigAccount creates an M-of-N account from keys in any order
+func NewMultisigAccount(threshold int, keys []MultisigKey) (*MultisigAccount, error) {
+	sorted := make([]MultisigKey, len(keys))
+	for i, key := range keys {
+		sorted[i] = MultisigKey{Scheme: key.Scheme, PublicKey: strings.ToLower(key.PublicKey)}
+	}
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].less(sorted[j]) })
+
+	account := &MultisigAccount{Threshold: threshold, Keys: sorted}
+	if err := account.Validate(); err != nil {
+		return nil, err
+	}
+	return account, nil
+}
+
+// NewMultisigAccountFromWallets creates an M-of-N account controlled by the keys of wallets
+func NewMultisigAccountFromWallets(threshold int, wallets ...*Wallet) (*MultisigAccount, error) {
+	keys := make([]MultisigKey, len(wallets))
+	for i, w := range wallets {
+		keys[i] = w.MultisigKey()
+	}
+	return NewMultisigAccount(threshold, keys)
+}
+
+// Validate checks the threshold and that the keys are valid, distinct and in canonical order
+func (a *MultisigAccount) Validate() error {
+	if len(a.Keys) == 0 || len(a.Keys) > MaxMultisigKeys {
+		return fmt.Errorf("multisig account needs 1 to %d keys, got %d", MaxMultisigKeys, len(a.Keys))
+	}
+	if a.Threshold < 1 || a.Threshold > len(a.Keys) {
+		return fmt.Errorf("multisig threshold must be between 1 and %d, got %d", len(a.Keys), a.Threshold)
+	}
+	for i, key := range a.Keys {
+		if key.PublicKey != strings.ToLower(key.PublicKey) {
+			return fmt.Errorf("multisig key %d must be lowercase hex", i)
+		}
+		if _, err := key.Address(); err != nil {
+			return fmt.Errorf("invalid multisig key %d: %v", i, err)
+		}
+		if i > 0 && !a.Keys[i-1].less(key) {
+			return fmt.Errorf("multisig keys must be distinct and sorted")
+		}
+	}
+	return nil
+}
+
+// Address returns the account's address: the prefix and the last 20 bytes of the Keccak-256 hash
+// of the threshold and keys
+func (a *MultisigAccount) Address() string {
+	var data bytes.Buffer
+	data.WriteString("multisig")
+	data.WriteByte(byte(a.Threshold))
+	for _, key := range a.Keys {
+		publicKey, _ := hex.DecodeString(key.PublicKey)
+		data.WriteByte(byte(key.Scheme))
+		data.WriteByte(byte(len(publicKey)))
+		data.Write(publicKey)
+	}
+	return MultisigAddressPrefix + hex.EncodeToString(Keccak256(data.Bytes())[12:])
+}
+
+// KeyIndex returns the position of a key in the account, or -1 if it is not one of its keys
+func (a *MultisigAccount) KeyIndex(key MultisigKey) int {
+	for i, k := range a.Keys {
+		if k.Scheme == key.Scheme && k.PublicKey == strings.ToLower(key.PublicKey) {
+			return i
+		}
+	}
+	return -1
+}
+
+// VerifySignatures checks hex-encoded signatures of a hash, one per key in key order ("" for keys
+// that did not sign). Every signature given must be valid, and at least Threshold must be given.
+func (a *MultisigAccount) VerifySignatures(hash []byte, signatures []string) error {
+	if len(signatures) != len(a.Keys) {
+		return fmt.Errorf("expected %d multisig signature slots, got %d", len(a.Keys), len(signatures))
+	}
+	valid := 0
+	for i, encoded := range signatures {
+		if encoded == "" {
+			continue
+		}
+		info, err := GetSignatureScheme(a.Keys[i].Scheme)
+		if err != nil {
+			return err
+		}
+		publicKey, _ := hex.DecodeString(a.Keys[i].PublicKey)
+		signature, err := hex.DecodeString(encoded)
+		if err != nil || !info.Verify(publicKey, hash, signature) {
+			return fmt.Errorf("invalid signature from multisig key %d", i)
+		}
+		valid++
+	}
+	if valid < a.Threshold {
+		return fmt.Errorf("multisig transaction has %d of %d required signatures", valid, a.Threshold)
+	}
+	return nil
+}
+
+// IsMultisigAddress checks if an address is a multisig account address
+func IsMultisigAddress(address string) bool {
+	addressType, err := AddressTypeOf(address)
+	return err == nil && addressType == AddressTypeMultisig
+}
+
+// NewMultisigTransaction creates an unsigned transaction spending from a multisig account.
+// Each key holder adds a signature with SignMultisig (or Wallet.SignMultisigTransaction),
+// and partially signed copies are combined with MergeSignatures.
+func NewMultisigTransaction(account *MultisigAccount, to string, amount, fee float64) *Transaction {
+	tx := NewTransactionWithFee(account.Address(), to, amount, fee)
+	tx.Multisig = account
+	tx.Signatures = make([]string, len(account.Keys))
+	return tx
+}
+
+// SignMultisig adds the signer's signature to a multisig transaction
+func (tx *Transaction) SignMultisig(signer Signer) error {
+	if tx.Multisig == nil {
+		return fmt.Errorf("transaction is not from a multisig account")
+	}
+	index := tx.Multisig.KeyIndex(MultisigKeyOf(signer))
+	if index < 0 {
+		return fmt.Errorf("signer %s is not a key of multisig account %s", signer.Address(), tx.From)
+	}
+	signature, err := signer.Sign(tx.Hash())
+	if err != nil {
+		return err
+	}
+	if len(tx.Signatures) != len(tx.Multisig.Keys) {
+		tx.Signatures = make([]string, len(tx.Multisig.Keys))
+	}
+	tx.Signatures[index] = hex.EncodeToString(signature)
+	return nil
+}
+
+// MergeSignatures copies the signatures of partially signed copies of the same multisig transaction
+func (tx *Transaction) MergeSignatures(partials ...*Transaction) error {
+	if tx.Multisig == nil {
+		return fmt.Errorf("transaction is not from a multisig account")
+	}
+	if len(tx.Signatures) != len(tx.Multisig.Keys) {
+		tx.Signatures = make([]string, len(tx.Multisig.Keys))
+	}
+	hash := tx.Hash()
+	for _, partial := range partials {
+		if !bytes.Equal(partial.Hash(), hash) || partial.Multisig == nil ||
+			partial.Multisig.Address() != tx.Multisig.Address() || len(partial.Signatures) != len(tx.Signatures) {
+			return fmt.Errorf("partial signatures are for a different transaction")
+		}
+		for i, signature := range partial.Signatures {
+			if signature != "" {
+				tx.Signatures[i] = signature
+			}
+		}
+	}
+	return nil
+}
+
+// SignatureCount returns how many keys have signed a multisig transaction
+func (tx *Transaction) SignatureCount() int {
+	count := 0
+	for _, signature := range tx.Signatures {
+		if signature != "" {
+			count++
+		}
+	}
+	return count
+}
+
+// checkMultisigSignatures checks that a multisig transaction's account matches its From address
+// and carries enough valid signatures
+func (tx *Transaction) checkMultisigSignatures() error {
+	if tx.Multisig == nil {
+		return fmt.Errorf("transaction from multisig account %s does not include the account", tx.From)
+	}
+	if err := tx.Multisig.Validate(); err != nil {
+		return err
+	}
+	if address := tx.Multisig.Address(); address != tx.From {
+		return fmt.Errorf("transaction from %s carries the multisig account %s", tx.From, address)
+	}
+	return tx.Multisig.VerifySignatures(tx.Hash(), tx.Signatures)
+}
@@ -0,0 +1,310 @@
+package main
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/sha256"
+	"fmt"
+	"math/big"
+)
+
+// MuSigSignatureLength is the size of an aggregate Schnorr signature: compressed R (33 bytes) || s (32 bytes)
+const MuSigSignatureLength = 65
+
+// MuSigNonceLength is the size of a signer's public nonce: two compressed points
+const MuSigNonceLength = 66
+
+// MuSigNonce is a signer's secret nonce pair for one MuSig2 signing session.
+// It is erased when used, as signing twice with the same nonce would reveal the private key.
+type MuSigNonce struct {
+	k1, k2 *big.Int
+	Public []byte // R1 || R2, sent to the other signers
+}
+
+// NewMuSigNonce draws a fresh random nonce pair
+func NewMuSigNonce() (*MuSigNonce, error) {
+	k1, err := randomScalar()
+	if err != nil {
+		return nil, err
+	}
+	k2, err := randomScalar()
+	if err != nil {
+		return nil, err
+	}
+	x1, y1 := secp256k1.ScalarBaseMult(k1.Bytes())
+	x2, y2 := secp256k1.ScalarBaseMult(k2.Bytes())
+	public := append(compressPoint(x1, y1), compressPoint(x2, y2)...)
+	return &MuSigNonce{k1: k1, k2: k2, Public: public}, nil
+}
+
+// MuSigSession combines the Schnorr signatures of a fixed list of secp256k1 keys over one message
+// into a single signature, following MuSig2: every signer first publishes a nonce pair, then a
+// partial signature. The result verifies against the aggregate of the keys, so n signatures cost
+// as much to store and check as one.
+type MuSigSession struct {
+	Message []byte
+
+	keys         []*ecdsa.PublicKey
+	coefficients []*big.Int
+	aggX, aggY   *big.Int
+	nonces       [][]byte
+	partials     []*big.Int
+}
+
+// NewMuSigSession starts a session for signers with 64-byte X || Y secp256k1 public keys, in signer order
+func NewMuSigSession(publicKeys [][]byte, message []byte) (*MuSigSession, error) {
+	keys, coefficients, aggX, aggY, err := aggregateMuSigKeys(publicKeys)
+	if err != nil {
+		return nil, err
+	}
+	return &MuSigSession{
+		Message:      message,
+		keys:         keys,
+		coefficients: coefficients,
+		aggX:         aggX,
+		aggY:         aggY,
+		nonces:       make([][]byte, len(keys)),
+		partials:     make([]*big.Int, len(keys)),
+	}, nil
+}
+
+// AggregatePublicKey returns the key the final signature verifies against, as 64-byte X || Y
+func (s *MuSigSession) AggregatePublicKey() []byte {
+	return publicKeyBytes(&ecdsa.PublicKey{Curve: secp256k1, X: s.aggX, Y: s.aggY})
+}
+
+// AddNonce records the public nonce of the signer at index
+func (s *MuSigSession) AddNonce(index int, public []byte) error {
+	if index < 0 || index >= len(s.keys) {
+		return fmt.Errorf("signer index %d out of range", index)
+	}
+	if len(public) != MuSigNonceLength {
+		return fmt.Errorf("public nonce must be %d bytes, got %d", MuSigNonceLength, len(public))
+	}
+	for _, offset := range []int{0, 33} {
+		if _, _, err := decompressPoint(public[offset : offset+33]); err != nil {
+			return fmt.Errorf("invalid public nonce: %v", err)
+		}
+	}
+	s.nonces[index] = append([]byte(nil), public...)
+	return nil
+}
+
+// Sign creates the partial signature of the signer at index; all nonces must have been added.
+// The nonce is erased so it cannot be used again.
+func (s *MuSigSession) Sign(index int, key *ecdsa.PrivateKey, nonce *MuSigNonce) ([]byte, error) {
+	if index < 0 || index >= len(s.keys) {
+		return nil, fmt.Errorf("signer index %d out of range", index)
+	}
+	if key.Curve != secp256k1 || key.X.Cmp(s.keys[index].X) != 0 || key.Y.Cmp(s.keys[index].Y) != 0 {
+		return nil, fmt.Errorf("key does not belong to signer %d", index)
+	}
+	if nonce.k1 == nil || !bytes.Equal(nonce.Public, s.nonces[index]) {
+		return nil, fmt.Errorf("nonce was already used or is not the one signer %d published", index)
+	}
+	b, _, _, e, err := s.challenge()
+	if err != nil {
+		return nil, err
+	}
+
+	// s_i = k1 + b·k2 + e·a_i·x_i
+	n := secp256k1.params.N
+	partial := new(big.Int).Mul(b, nonce.k2)
+	partial.Add(partial, nonce.k1)
+	partial.Add(partial, new(big.Int).Mul(e, new(big.Int).Mul(s.coefficients[index], key.D)))
+	partial.Mod(partial, n)
+
+	nonce.k1, nonce.k2 = nil, nil
+	encoded := make([]byte, 32)
+	return partial.FillBytes(encoded), nil
+}
+
+// AddPartialSignature checks and records the partial signature of the signer at index
+func (s *MuSigSession) AddPartialSignature(index int, partial []byte) error {
+	if index < 0 || index >= len(s.keys) {
+		return fmt.Errorf("signer index %d out of range", index)
+	}
+	if len(partial) != 32 {
+		return fmt.Errorf("partial signature must be 32 bytes, got %d", len(partial))
+	}
+	si := new(big.Int).SetBytes(partial)
+	if si.Cmp(secp256k1.params.N) >= 0 {
+		return fmt.Errorf("partial signature out of range")
+	}
+	b, _, _, e, err := s.challenge()
+	if err != nil {
+		return err
+	}
+
+	// s_i·G = R1_i + b·R2_i + e·a_i·P_i
+	r1x, r1y, _ := decompressPoint(s.nonces[index][:33])
+	r2x, r2y, _ := decompressPoint(s.nonces[index][33:])
+	x, y := secp256k1.ScalarMult(r2x, r2y, b.Bytes())
+	x, y = secp256k1.Add(r1x, r1y, x, y)
+	ea := new(big.Int).Mul(e, s.coefficients[index])
+	ea.Mod(ea, secp256k1.params.N)
+	px, py := secp256k1.ScalarMult(s.keys[index].X, s.keys[index].Y, ea.Bytes())
+	x, y = secp256k1.Add(x, y, px, py)
+	gx, gy := secp256k1.ScalarBaseMult(si.Bytes())
+	if gx.Cmp(x) != 0 || gy.Cmp(y) != 0 {
+		return fmt.Errorf("invalid partial signature from signer %d", index)
+	}
+	s.partials[index] = si
+	return nil
+}
+
+// Signature sums the partial signatures into the aggregate signature
+func (s *MuSigSession) Signature() ([]byte, error) {
+	_, rx, ry, _, err := s.challenge()
+	if err != nil {
+		return nil, err
+	}
+	sum := new(big.Int)
+	for i, partial := range s.partials {
+		if partial == nil {
+			return nil, fmt.Errorf("missing partial signature from signer %d", i)
+		}
+		sum.Add(sum, partial)
+	}
+	sum.Mod(sum, secp256k1.params.N)
+	signature := compressPoint(rx, ry)
+	return append(signature, sum.FillBytes(make([]byte, 32))...), nil
+}
+
+// challenge aggregates the nonces into R = R1 + b·R2 and returns the nonce coefficient b,
+// R and the Schnorr challenge e
+func (s *MuSigSession) challenge() (b, rx, ry, e *big.Int, err error) {
+	r1x, r1y, r2x, r2y := new(big.Int), new(big.Int), new(big.Int), new(big.Int)
+	for i, nonce := range s.nonces {
+		if nonce == nil {
+			return nil, nil, nil, nil, fmt.Errorf("missing nonce from signer %d", i)
+		}
+		x1, y1, _ := decompressPoint(nonce[:33])
+		x2, y2, _ := decompressPoint(nonce[33:])
+		r1x, r1y = secp256k1.Add(r1x, r1y, x1, y1)
+		r2x, r2y = secp256k1.Add(r2x, r2y, x2, y2)
+	}
+	aggKey := s.AggregatePublicKey()
+	b = musigHash("musig/noncecoef", aggKey, compressPoint(r1x, r1y), compressPoint(r2x, r2y), s.Message)
+	x, y := secp256k1.ScalarMult(r2x, r2y, b.Bytes())
+	rx, ry = secp256k1.Add(r1x, r1y, x, y)
+	if rx.Sign() == 0 && ry.Sign() == 0 {
+		return nil, nil, nil, nil, fmt.Errorf("aggregate nonce is the point at infinity")
+	}
+	e = musigHash("musig/challenge", compressPoint(rx, ry), aggKey, s.Message)
+	return b, rx, ry, e, nil
+}
+
+// VerifyMuSig checks an aggregate signature of a message by the given signers (64-byte X || Y keys, in
+// signer order): s·G = R + e·X for the aggregate key X
+func VerifyMuSig(publicKeys [][]byte, message, signature []byte) bool {
+	if len(signature) != MuSigSignatureLength {
+		return false
+	}
+	_, _, aggX, aggY, err := aggregateMuSigKeys(publicKeys)
+	if err != nil {
+		return false
+	}
+	rx, ry, err := decompressPoint(signature[:33])
+	if err != nil {
+		return false
+	}
+	s := new(big.Int).SetBytes(signature[33:])
+	if s.Cmp(secp256k1.params.N) >= 0 {
+		return false
+	}
+	aggKey := publicKeyBytes(&ecdsa.PublicKey{Curve: secp256k1, X: aggX, Y: aggY})
+	e := musigHash("musig/challenge", signature[:33], aggKey, message)
+
+	x, y := secp256k1.ScalarMult(aggX, aggY, e.Bytes())
+	x, y = secp256k1.Add(rx, ry, x, y)
+	gx, gy := secp256k1.ScalarBaseMult(s.Bytes())
+	return gx.Cmp(x) == 0 && gy.Cmp(y) == 0
+}
+
+// aggregateMuSigKeys parses the keys and computes X = Σ a_i·P_i with a_i = H(L, P_i), where L commits
+// to the whole key list; the coefficients stop a signer from choosing a key that cancels the others
+func aggregateMuSigKeys(publicKeys [][]byte) ([]*ecdsa.PublicKey, []*big.Int, *big.Int, *big.Int, error) {
+	if len(publicKeys) == 0 {
+		return nil, nil, nil, nil, fmt.Errorf("no signer keys")
+	}
+	keys := make([]*ecdsa.PublicKey, len(publicKeys))
+	seen := make(map[string]bool)
+	for i, data := range publicKeys {
+		key, err := parseECDSAPublicKey(secp256k1, data)
+		if err != nil {
+			return nil, nil, nil, nil, fmt.Errorf("signer %d: %v", i, err)
+		}
+		if seen[string(data)] {
+			return nil, nil, nil, nil, fmt.Errorf("signer %d: duplicate key", i)
+		}
+		seen[string(data)] = true
+		keys[i] = key
+	}
+
+	keyList := sha256.New()
+	for _, data := range publicKeys {
+		keyList.Write(data)
+	}
+	listHash := keyList.Sum(nil)
+
+	coefficients := make([]*big.Int, len(keys))
+	aggX, aggY := new(big.Int), new(big.Int)
+	for i, key := range keys {
+		coefficients[i] = musigHash("musig/keyagg", listHash, publicKeys[i])
+		x, y := secp256k1.ScalarMult(key.X, key.Y, coefficients[i].Bytes())
+		aggX, aggY = secp256k1.Add(aggX, aggY, x, y)
+	}
+	if aggX.Sign() == 0 && aggY.Sign() == 0 {
+		return nil, nil, nil, nil, fmt.Errorf("aggregate key is the point at infinity")
+	}
+	return keys, coefficients, aggX, aggY, nil
+}
+
+// musigHash is a domain-separated SHA-256 of the parts, reduced modulo the curve order
+func musigHash(tag string, parts ...[]byte) *big.Int {
+	h := sha256.New()
+	h.Write([]byte(tag))
+	h.Write([]byte{0})
+	for _, part := range parts {
+		h.Write(part)
+	}
+	return new(big.Int).Mod(new(big.Int).SetBytes(h.Sum(nil)), secp256k1.params.N)
+}
+
+// randomScalar returns a uniformly random scalar in [1, n)
+func randomScalar() (*big.Int, error) {
+	key, err := GenerateSecp256k1Key()
+	if err != nil {
+		return nil, err
+	}
+	return key.D, nil
+}
+
+// compressPoint encodes a secp256k1 point as its y parity (0x02 or 0x03) and 32-byte x
+func compressPoint(x, y *big.Int) []byte {
+	encoded := make([]byte, 33)
+	encoded[0] = 0x02 + byte(y.Bit(0))
+	x.FillBytes(encoded[1:])
+	return encoded
+}
+
+// decompressPoint decodes a compressed secp256k1 point; p ≡ 3 (mod 4) so y = (x³ + 7)^((p+1)/4)
+func decompressPoint(data []byte) (*big.Int, *big.Int, error) {
+	if len(data) != 33 || (data[0] != 0x02 && data[0] != 0x03) {
+		return nil, nil, fmt.Errorf("invalid compressed point")
+	}
+	P := secp256k1.params.P
+	x := new(big.Int).SetBytes(data[1:])
+	if x.Cmp(P) >= 0 {
+		return nil, nil, fmt.Errorf("invalid compressed point")
+	}
+	y := new(big.Int).Exp(secp256k1.rhs(x), new(big.Int).Rsh(new(big.Int).Add(P, big.NewInt(1)), 2), P)
+	if y.Bit(0) != uint(data[0]-0x02) {
+		y.Sub(P, y)
+	}
+	if !secp256k1.IsOnCurve(x, y) {
+		return nil, nil, fmt.Errorf("point is not on the curve")
+	}
+	return x, y, nil
+}
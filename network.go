@@ -33,6 +33,7 @@ type Node struct {
 	Port       int
 	Blockchain *Blockchain
 	Peers      map[string]bool // Map of peer addresses
+	Keystore   *Keystore       // Encrypted accounts of the node operator (nil until OpenKeystore)
 	mu         sync.RWMutex
 	listener   net.Listener
 	running    bool
@@ -63,6 +64,18 @@ func (n *Node) RemovePeer(peerAddress string) {
 	delete(n.Peers, peerAddress)
 }
 
+// OpenKeystore points the node at a keystore directory of encrypted accounts
+func (n *Node) OpenKeystore(dir string) error {
+	keystore, err := NewKeystore(dir, StandardPBKDF2Iterations)
+	if err != nil {
+		return err
+	}
+	n.mu.Lock()
+	n.Keystore = keystore
+	n.mu.Unlock()
+	return nil
+}
+
 // GetAddress returns the full address of the node
 func (n *Node) GetAddress() string {
 	return fmt.Sprintf("%s:%d", n.Address, n.Port)
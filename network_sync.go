@@ -48,6 +48,9 @@ func validateBlockchain(blocks []*Block) bool {
 		return false
 	}
 
+	// Verify all signatures up front across the worker pool; the per-block checks then hit the cache
+	signatureVerifier.VerifyBlocks(blocks[1:])
+
 	// Validate all blocks
 	for i := 0; i < len(blocks); i++ {
 		currentBlock := blocks[i]
@@ -59,12 +62,10 @@ func validateBlockchain(blocks []*Block) bool {
 			return false
 		}
 
-		// Validate transaction signatures
+		// Validate transaction signatures and coinbase rules
 		if i > 0 {
-			for _, tx := range currentBlock.Transactions {
-				if tx.Signature != "" && !tx.Verify() {
-					return false
-				}
+			if err := ValidateBlockTransactions(currentBlock.Transactions); err != nil {
+				return false
 			}
 		}
 
@@ -123,11 +124,9 @@ func (bc *Blockchain) AddReceivedBlock(block *Block) error {
 		return fmt.Errorf("invalid Merkle root")
 	}
 
-	// Validate transaction signatures
-	for _, tx := range block.Transactions {
-		if tx.Signature != "" && !tx.Verify() {
-			return fmt.Errorf("invalid transaction signature")
-		}
+	// Validate transaction signatures and coinbase rules
+	if err := ValidateBlockTransactions(block.Transactions); err != nil {
+		return fmt.Errorf("invalid block transactions: %v", err)
 	}
 
 	// Validate hash
@@ -142,7 +141,7 @@ func (bc *Blockchain) AddReceivedBlock(block *Block) error {
 	}
 
 	// Add block to blockchain
-	bc.Blocks = append(bc.Blocks, block)
+	bc.appendBlock(block)
 
 	// Remove transactions from mempool
 	txHashes := make([]string, 0)
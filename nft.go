@@ -0,0 +1,193 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+)
+
+// EventApproval is emitted when an owner approves another address to transfer their NFT or tokens
+const EventApproval = "Approval(address,address,uint256)"
+
+// executeNFT executes a non-fungible token contract (ERC-721 like).
+// Token IDs are assigned sequentially from 1 by mint; state maps are keyed by the decimal token ID.
+func (sc *SmartContract) executeNFT(function string, ctx *ContractContext) (interface{}, error) {
+	owners := sc.getStringMap("owners")       // tokenId -> owner
+	tokenURIs := sc.getStringMap("tokenURIs") // tokenId -> metadata URI
+	approvals := sc.getStringMap("approvals") // tokenId -> approved address
+
+	switch function {
+	case "mint":
+		if ctx.Caller != sc.Deployer {
+			return nil, fmt.Errorf("only deployer can mint tokens")
+		}
+		if err := validateArgsCount(ctx.Args, 2, "mint"); err != nil {
+			return nil, err
+		}
+		to, uri := ctx.StringArg(0), ctx.StringArg(1)
+
+		minted, _ := sc.getStateFloat("totalMinted")
+		tokenID := uint64(minted) + 1
+		key := strconv.FormatUint(tokenID, 10)
+
+		sc.mu.Lock()
+		sc.State["totalMinted"] = float64(tokenID)
+		owners[key] = to
+		tokenURIs[key] = uri
+		sc.mu.Unlock()
+
+		sc.emitLog(ctx, EventTransfer, []string{"", to, key}, "")
+		return tokenID, nil
+
+	case "transfer":
+		if err := validateArgsCount(ctx.Args, 2, "transfer"); err != nil {
+			return nil, err
+		}
+		to := ctx.StringArg(0)
+		tokenID, err := ctx.UintArg(1)
+		if err != nil {
+			return nil, err
+		}
+		key := strconv.FormatUint(tokenID, 10)
+
+		sc.mu.Lock()
+		owner, exists := owners[key]
+		if !exists {
+			sc.mu.Unlock()
+			return nil, fmt.Errorf("token %d does not exist", tokenID)
+		}
+		if ctx.Caller != owner && ctx.Caller != approvals[key] {
+			sc.mu.Unlock()
+			return nil, fmt.Errorf("caller is not owner or approved for token %d", tokenID)
+		}
+		owners[key] = to
+		delete(approvals, key) // Approvals do not survive a transfer
+		sc.mu.Unlock()
+
+		sc.emitLog(ctx, EventTransfer, []string{owner, to, key}, "")
+		return fmt.Sprintf("Transferred token %d from %s to %s",
+			tokenID, truncateAddress(owner), truncateAddress(to)), nil
+
+	case "approve":
+		if err := validateArgsCount(ctx.Args, 2, "approve"); err != nil {
+			return nil, err
+		}
+		spender := ctx.StringArg(0)
+		tokenID, err := ctx.UintArg(1)
+		if err != nil {
+			return nil, err
+		}
+		key := strconv.FormatUint(tokenID, 10)
+
+		sc.mu.Lock()
+		owner, exists := owners[key]
+		if !exists {
+			sc.mu.Unlock()
+			return nil, fmt.Errorf("token %d does not exist", tokenID)
+		}
+		if ctx.Caller != owner {
+			sc.mu.Unlock()
+			return nil, fmt.Errorf("only the owner can approve token %d", tokenID)
+		}
+		approvals[key] = spender
+		sc.mu.Unlock()
+
+		sc.emitLog(ctx, EventApproval, []string{owner, spender, key}, "")
+		return fmt.Sprintf("Approved %s for token %d", truncateAddress(spender), tokenID), nil
+
+	case "getApproved":
+		key, err := sc.nftTokenKey(ctx, owners)
+		if err != nil {
+			return nil, err
+		}
+		sc.mu.RLock()
+		defer sc.mu.RUnlock()
+		return approvals[key], nil
+
+	case "ownerOf":
+		key, err := sc.nftTokenKey(ctx, owners)
+		if err != nil {
+			return nil, err
+		}
+		sc.mu.RLock()
+		defer sc.mu.RUnlock()
+		return owners[key], nil
+
+	case "tokenURI":
+		key, err := sc.nftTokenKey(ctx, owners)
+		if err != nil {
+			return nil, err
+		}
+		sc.mu.RLock()
+		defer sc.mu.RUnlock()
+		return tokenURIs[key], nil
+
+	case "balanceOf":
+		if err := validateArgsCount(ctx.Args, 1, "balanceOf"); err != nil {
+			return nil, err
+		}
+		return uint64(len(sc.nftTokensOf(ctx.StringArg(0), owners))), nil
+
+	case "tokensOfOwner":
+		if err := validateArgsCount(ctx.Args, 1, "tokensOfOwner"); err != nil {
+			return nil, err
+		}
+		return sc.nftTokensOf(ctx.StringArg(0), owners), nil
+
+	case "tokenOfOwnerByIndex":
+		if err := validateArgsCount(ctx.Args, 2, "tokenOfOwnerByIndex"); err != nil {
+			return nil, err
+		}
+		index, err := ctx.UintArg(1)
+		if err != nil {
+			return nil, err
+		}
+		tokens := sc.nftTokensOf(ctx.StringArg(0), owners)
+		if index >= uint64(len(tokens)) {
+			return nil, fmt.Errorf("owner index out of bounds: %d", index)
+		}
+		return tokens[index], nil
+
+	case "totalSupply":
+		sc.mu.RLock()
+		defer sc.mu.RUnlock()
+		return uint64(len(owners)), nil
+
+	default:
+		return nil, fmt.Errorf("unknown function: %s", function)
+	}
+}
+
+// nftTokenKey reads the token ID argument and checks that the token exists
+func (sc *SmartContract) nftTokenKey(ctx *ContractContext, owners map[string]string) (string, error) {
+	if err := validateArgsCount(ctx.Args, 1, "token query"); err != nil {
+		return "", err
+	}
+	tokenID, err := ctx.UintArg(0)
+	if err != nil {
+		return "", err
+	}
+	key := strconv.FormatUint(tokenID, 10)
+	sc.mu.RLock()
+	_, exists := owners[key]
+	sc.mu.RUnlock()
+	if !exists {
+		return "", fmt.Errorf("token %d does not exist", tokenID)
+	}
+	return key, nil
+}
+
+// nftTokensOf returns the IDs of all tokens held by an owner in ascending order
+func (sc *SmartContract) nftTokensOf(owner string, owners map[string]string) []uint64 {
+	sc.mu.RLock()
+	defer sc.mu.RUnlock()
+	tokens := make([]uint64, 0)
+	for key, holder := range owners {
+		if holder == owner {
+			id, _ := strconv.ParseUint(key, 10, 64)
+			tokens = append(tokens, id)
+		}
+	}
+	sort.Slice(tokens, func(i, j int) bool { return tokens[i] < tokens[j] })
+	return tokens
+}
@@ -35,6 +35,7 @@ type PaymentChannel struct {
 	InitialState    *ChannelState
 	DepositAmount   float64
 	MultiSigAddress string
+	MultisigAccount *MultisigAccount // 2-of-2 account holding the deposits, for channels opened with keys
 	Timeout         time.Duration
 	CreatedAt       time.Time
 	LastUpdate      time.Time
@@ -61,6 +62,28 @@ func NewChannelManager(bc *Blockchain) *ChannelManager {
 
 // CreateChannel creates a new payment channel between two parties
 func (cm *ChannelManager) CreateChannel(participant1, participant2 string, deposit1, deposit2 float64, timeout time.Duration) (*PaymentChannel, error) {
+	return cm.createChannel(participant1, participant2, deposit1, deposit2, timeout, nil)
+}
+
+// CreateChannelWithKeys creates a payment channel whose deposits are held by a native 2-of-2 multisig
+// account of the participants' keys, so the escrow can only be spent with both signatures
+func (cm *ChannelManager) CreateChannelWithKeys(key1, key2 MultisigKey, deposit1, deposit2 float64, timeout time.Duration) (*PaymentChannel, error) {
+	participant1, err := key1.Address()
+	if err != nil {
+		return nil, fmt.Errorf("invalid participant1 key: %v", err)
+	}
+	participant2, err := key2.Address()
+	if err != nil {
+		return nil, fmt.Errorf("invalid participant2 key: %v", err)
+	}
+	account, err := NewMultisigAccount(2, []MultisigKey{key1, key2})
+	if err != nil {
+		return nil, err
+	}
+	return cm.createChannel(participant1, participant2, deposit1, deposit2, timeout, account)
+}
+
+func (cm *ChannelManager) createChannel(participant1, participant2 string, deposit1, deposit2 float64, timeout time.Duration, account *MultisigAccount) (*PaymentChannel, error) {
 	cm.mu.Lock()
 	defer cm.mu.Unlock()
 
@@ -96,14 +119,18 @@ func (cm *ChannelManager) CreateChannel(participant1, participant2 string, depos
 		IsClosed:       false,
 	}
 
-	// Create multisig address (simplified - in reality this would be a proper multisig)
+	// Create multisig address (simplified when the participants' keys are not known)
 	multiSigAddress := generateMultisigAddress(participant1, participant2, channelID)
+	if account != nil {
+		multiSigAddress = account.Address()
+	}
 
 	channel := &PaymentChannel{
 		State:           initialState,
 		InitialState:    initialState,
 		DepositAmount:   deposit1 + deposit2,
 		MultiSigAddress: multiSigAddress,
+		MultisigAccount: account,
 		Timeout:         timeout,
 		CreatedAt:       time.Now(),
 		LastUpdate:      time.Now(),
@@ -189,6 +216,9 @@ func (pc *PaymentChannel) SignState(state *ChannelState, signer string) (string,
 	if signer != state.Participant1 && signer != state.Participant2 {
 		return "", fmt.Errorf("signer is not a participant")
 	}
+	if ValidateAddress(signer) == nil {
+		return "", fmt.Errorf("participant %s is an address; sign with its key using SignStateWith", signer)
+	}
 
 	// Create signature (simplified - in reality this would use ECDSA)
 	signature := signChannelState(state, signer)
@@ -196,6 +226,23 @@ func (pc *PaymentChannel) SignState(state *ChannelState, signer string) (string,
 	return signature, nil
 }
 
+// SignStateWith signs a channel state with a participant's key of any registered signature scheme
+func (pc *PaymentChannel) SignStateWith(state *ChannelState, signer Signer) (string, error) {
+	pc.mu.RLock()
+	defer pc.mu.RUnlock()
+
+	address := signer.Address()
+	if address != state.Participant1 && address != state.Participant2 {
+		return "", fmt.Errorf("signer is not a participant")
+	}
+
+	signature, err := SignMessage(signer, channelStateSigningData(state))
+	if err != nil {
+		return "", err
+	}
+	return signature.Encode(), nil
+}
+
 // CommitState commits a signed state to the channel
 func (pc *PaymentChannel) CommitState(signedState *ChannelSignature) error {
 	pc.mu.Lock()
@@ -205,9 +252,8 @@ func (pc *PaymentChannel) CommitState(signedState *ChannelSignature) error {
 		return fmt.Errorf("channel is closed")
 	}
 
-	// Verify signatures (simplified)
-	if signedState.Signature1 == "" || signedState.Signature2 == "" {
-		return fmt.Errorf("both signatures are required")
+	if err := signedState.Verify(); err != nil {
+		return err
 	}
 
 	// Verify sequence number
@@ -241,6 +287,10 @@ func (pc *PaymentChannel) CloseChannel(finalState *ChannelSignature) error {
 		return fmt.Errorf("channel is already closed")
 	}
 
+	if err := finalState.Verify(); err != nil {
+		return err
+	}
+
 	// Verify final state
 	if finalState.State.SequenceNumber != pc.State.SequenceNumber {
 		return fmt.Errorf("final state sequence number mismatch")
@@ -264,6 +314,25 @@ func (pc *PaymentChannel) CloseChannel(finalState *ChannelSignature) error {
 	return nil
 }
 
+// Verify checks that both participants signed the state. Scheme signatures are verified against the
+// participant addresses; plain hash signatures from SignState are only accepted from participants
+// that are not addresses, and must match the state they claim to sign.
+func (cs *ChannelSignature) Verify() error {
+	if cs.State == nil {
+		return fmt.Errorf("signed state is missing")
+	}
+	if cs.Signature1 == "" || cs.Signature2 == "" {
+		return fmt.Errorf("both signatures are required")
+	}
+	if err := verifyChannelStateSignature(cs.State, cs.Signature1, cs.State.Participant1); err != nil {
+		return fmt.Errorf("participant1 signature: %v", err)
+	}
+	if err := verifyChannelStateSignature(cs.State, cs.Signature2, cs.State.Participant2); err != nil {
+		return fmt.Errorf("participant2 signature: %v", err)
+	}
+	return nil
+}
+
 // GetStatus returns the current status of the channel
 func (pc *PaymentChannel) GetStatus() string {
 	pc.mu.RLock()
@@ -385,6 +454,27 @@ func signChannelState(state *ChannelState, signer string) string {
 	return hex.EncodeToString(hash[:])
 }
 
+// channelStateSigningData returns the bytes a scheme signature on a channel state covers
+func channelStateSigningData(state *ChannelState) []byte {
+	return []byte(fmt.Sprintf("channel:%s:%s:%s:%.8f:%.8f:%d:%d", state.ChannelID, state.Participant1, state.Participant2,
+		state.Balance1, state.Balance2, state.SequenceNumber, state.Nonce))
+}
+
+// verifyChannelStateSignature checks one participant's signature on a state; anyone can compute
+// a plain hash, so participants with real addresses must use a scheme signature
+func verifyChannelStateSignature(state *ChannelState, signature, participant string) error {
+	if IsSchemeSignature(signature) {
+		return VerifyEncodedSignature(signature, channelStateSigningData(state), participant)
+	}
+	if ValidateAddress(participant) == nil {
+		return fmt.Errorf("%s must sign with a scheme signature", participant)
+	}
+	if signature != signChannelState(state, participant) {
+		return fmt.Errorf("signature does not match state")
+	}
+	return nil
+}
+
 func generateClosingTxHash(state *ChannelState) string {
 	data := fmt.Sprintf("closing:%s:%.2f:%.2f:%d", state.ChannelID, state.Balance1, state.Balance2, state.SequenceNumber)
 	hash := sha256.Sum256([]byte(data))
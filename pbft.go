@@ -57,6 +57,7 @@ type PBFT struct {
 	PrePrepared   bool
 	Prepared      bool
 	Committed     bool
+	signer        Signer // Signs this node's messages; nil keeps the plain message hash
 }
 
 // NewPBFT creates a new PBFT instance
@@ -109,6 +110,10 @@ func (pbft *PBFT) PrePreparePhase() (*PBFTMessage, error) {
 		return nil, fmt.Errorf("invalid state for pre-prepare")
 	}
 
+	signature, err := pbft.signMessage(PrePrepare, pbft.Block.Hash)
+	if err != nil {
+		return nil, err
+	}
 	msg := &PBFTMessage{
 		Type:      PrePrepare,
 		BlockHash: pbft.Block.Hash,
@@ -116,7 +121,7 @@ func (pbft *PBFT) PrePreparePhase() (*PBFTMessage, error) {
 		Sequence:  pbft.Sequence,
 		ViewID:    pbft.ViewID,
 		Timestamp: time.Now(),
-		Signature: pbft.signMessage(PrePrepare, pbft.Block.Hash),
+		Signature: signature,
 	}
 
 	pbft.Messages = append(pbft.Messages, msg)
@@ -131,6 +136,10 @@ func (pbft *PBFT) ProcessPrePrepare(msg *PBFTMessage) error {
 	pbft.mu.Lock()
 	defer pbft.mu.Unlock()
 
+	if err := pbft.verifyMessage(msg); err != nil {
+		return err
+	}
+
 	// Verify message is from primary
 	if msg.NodeID != pbft.GetPrimaryNode() {
 		return fmt.Errorf("pre-prepare message not from primary")
@@ -166,6 +175,10 @@ func (pbft *PBFT) PreparePhase() (*PBFTMessage, error) {
 		return nil, fmt.Errorf("invalid state for prepare")
 	}
 
+	signature, err := pbft.signMessage(Prepare, pbft.Block.Hash)
+	if err != nil {
+		return nil, err
+	}
 	msg := &PBFTMessage{
 		Type:      Prepare,
 		BlockHash: pbft.Block.Hash,
@@ -173,7 +186,7 @@ func (pbft *PBFT) PreparePhase() (*PBFTMessage, error) {
 		Sequence:  pbft.Sequence,
 		ViewID:    pbft.ViewID,
 		Timestamp: time.Now(),
-		Signature: pbft.signMessage(Prepare, pbft.Block.Hash),
+		Signature: signature,
 	}
 
 	pbft.Messages = append(pbft.Messages, msg)
@@ -188,6 +201,10 @@ func (pbft *PBFT) ProcessPrepare(msg *PBFTMessage) error {
 	pbft.mu.Lock()
 	defer pbft.mu.Unlock()
 
+	if err := pbft.verifyMessage(msg); err != nil {
+		return err
+	}
+
 	// Verify sequence and view
 	if msg.Sequence != pbft.Sequence || msg.ViewID != pbft.ViewID {
 		return fmt.Errorf("sequence or view mismatch")
@@ -232,6 +249,10 @@ func (pbft *PBFT) CommitPhase() (*PBFTMessage, error) {
 		return nil, fmt.Errorf("invalid state for commit")
 	}
 
+	signature, err := pbft.signMessage(Commit, pbft.Block.Hash)
+	if err != nil {
+		return nil, err
+	}
 	msg := &PBFTMessage{
 		Type:      Commit,
 		BlockHash: pbft.Block.Hash,
@@ -239,7 +260,7 @@ func (pbft *PBFT) CommitPhase() (*PBFTMessage, error) {
 		Sequence:  pbft.Sequence,
 		ViewID:    pbft.ViewID,
 		Timestamp: time.Now(),
-		Signature: pbft.signMessage(Commit, pbft.Block.Hash),
+		Signature: signature,
 	}
 
 	pbft.Messages = append(pbft.Messages, msg)
@@ -254,6 +275,10 @@ func (pbft *PBFT) ProcessCommit(msg *PBFTMessage) error {
 	pbft.mu.Lock()
 	defer pbft.mu.Unlock()
 
+	if err := pbft.verifyMessage(msg); err != nil {
+		return err
+	}
+
 	// Verify sequence and view
 	if msg.Sequence != pbft.Sequence || msg.ViewID != pbft.ViewID {
 		return fmt.Errorf("sequence or view mismatch")
@@ -283,6 +308,20 @@ func (pbft *PBFT) ProcessCommit(msg *PBFTMessage) error {
 	return nil
 }
 
+// commitCertificate aggregates the votes of the validators that sent commit messages for the block
+func (pbft *PBFT) commitCertificate(validators []*Wallet) (*QuorumCertificate, error) {
+	pbft.mu.RLock()
+	committed := make(map[string]bool)
+	for _, msg := range pbft.Messages {
+		if msg.Type == Commit {
+			committed[msg.NodeID] = true
+		}
+	}
+	pbft.mu.RUnlock()
+
+	return buildValidatorCertificate(QuorumDomainPBFTCommit, pbft.Block.Hash, int64(pbft.Block.Index), validators, committed)
+}
+
 // IsFinalized checks if the consensus is finalized
 func (pbft *PBFT) IsFinalized() bool {
 	pbft.mu.RLock()
@@ -300,11 +339,69 @@ func (pbft *PBFT) GetConsensusStatus() string {
 		pbft.Committed, pbft.CommitCount, pbft.RequiredVotes)
 }
 
-// signMessage creates a simple signature for a message
-func (pbft *PBFT) signMessage(msgType PBFTMessageType, blockHash string) string {
-	data := fmt.Sprintf("%s:%s:%s:%d:%d", msgType, blockHash, pbft.NodeID, pbft.Sequence, pbft.ViewID)
-	hash := sha256.Sum256([]byte(data))
-	return hex.EncodeToString(hash[:])
+// SetSigner makes the node sign its messages with a key of any registered scheme.
+// The node ID must be the signer's address so other nodes can check who voted.
+func (pbft *PBFT) SetSigner(signer Signer) error {
+	pbft.mu.Lock()
+	defer pbft.mu.Unlock()
+	if signer.Address() != pbft.NodeID {
+		return fmt.Errorf("signer address %s does not match node ID %s", signer.Address(), pbft.NodeID)
+	}
+	pbft.signer = signer
+	return nil
+}
+
+// signMessage signs a message with the node's signer, or creates a simple hash signature if it has none
+func (pbft *PBFT) signMessage(msgType PBFTMessageType, blockHash string) (string, error) {
+	return signPBFTMessage(pbft.signer, msgType, blockHash, pbft.NodeID, pbft.Sequence, pbft.ViewID)
+}
+
+// signPBFTMessage signs a node's message with its signer, or hashes it if the signer is nil
+func signPBFTMessage(signer Signer, msgType PBFTMessageType, blockHash, nodeID string, sequence, viewID int64) (string, error) {
+	data := pbftSigningData(msgType, blockHash, nodeID, sequence, viewID)
+	if signer != nil {
+		signature, err := SignMessage(signer, data)
+		if err != nil {
+			return "", err
+		}
+		return signature.Encode(), nil
+	}
+	hash := sha256.Sum256(data)
+	return hex.EncodeToString(hash[:]), nil
+}
+
+// pbftSigningData returns the bytes a PBFT message signature covers
+func pbftSigningData(msgType PBFTMessageType, blockHash, nodeID string, sequence, viewID int64) []byte {
+	return []byte(fmt.Sprintf("%s:%s:%s:%d:%d", msgType, blockHash, nodeID, sequence, viewID))
+}
+
+// VerifySignature checks the message's signature against its node ID. A node whose ID is an
+// address must send a scheme signature; anyone can compute a plain hash, so it is only accepted
+// from nodes with plain IDs, and only if it matches the message.
+func (msg *PBFTMessage) VerifySignature() error {
+	data := pbftSigningData(msg.Type, msg.BlockHash, msg.NodeID, msg.Sequence, msg.ViewID)
+	if !IsSchemeSignature(msg.Signature) {
+		if ValidateAddress(msg.NodeID) == nil {
+			return fmt.Errorf("%s message from %s must carry a scheme signature", msg.Type, msg.NodeID)
+		}
+		if hash := sha256.Sum256(data); msg.Signature != hex.EncodeToString(hash[:]) {
+			return fmt.Errorf("invalid %s hash from %s", msg.Type, msg.NodeID)
+		}
+		return nil
+	}
+	if err := VerifyEncodedSignature(msg.Signature, data, msg.NodeID); err != nil {
+		return fmt.Errorf("invalid %s signature from %s: %v", msg.Type, msg.NodeID, err)
+	}
+	return nil
+}
+
+// verifyMessage checks a received message's signature; once this node signs its own
+// messages it accepts only scheme signatures from the others
+func (pbft *PBFT) verifyMessage(msg *PBFTMessage) error {
+	if pbft.signer != nil && !IsSchemeSignature(msg.Signature) {
+		return fmt.Errorf("%s message from %s is not signed with a scheme signature", msg.Type, msg.NodeID)
+	}
+	return msg.VerifySignature()
 }
 
 // Validate validates the PBFT consensus
@@ -327,12 +424,32 @@ func (pbft *PBFT) Validate() bool {
 
 // CreateBlockWithPBFT creates a block using PBFT consensus
 func (bc *Blockchain) CreateBlockWithPBFT(transactions []*Transaction, nodes []string, nodeID string) error {
+	return bc.createBlockWithPBFT(transactions, nodes, nodeID, nil)
+}
+
+// CreateBlockWithPBFTValidators creates a block using PBFT consensus among validator wallets, which
+// sign their messages. If every validator has a secp256k1 key, their commit votes are also stored in
+// the block, aggregated into one quorum certificate.
+func (bc *Blockchain) CreateBlockWithPBFTValidators(transactions []*Transaction, validators []*Wallet, nodeID string) error {
+	nodes := make([]string, len(validators))
+	for i, validator := range validators {
+		nodes[i] = validator.Address
+	}
+	return bc.createBlockWithPBFT(transactions, nodes, nodeID, validators)
+}
+
+// createBlockWithPBFT runs PBFT consensus on a new block; with validator wallets the nodes sign
+// their messages, and secp256k1 validators also certify the block with their aggregated commit votes
+func (bc *Blockchain) createBlockWithPBFT(transactions []*Transaction, nodes []string, nodeID string, validators []*Wallet) error {
 	// Validate all transactions before adding
 	for _, tx := range transactions {
 		if err := bc.ValidateTransaction(tx); err != nil {
 			return err
 		}
 	}
+	if err := ValidateBlockTransactions(transactions); err != nil {
+		return err
+	}
 
 	prevBlock := bc.Blocks[len(bc.Blocks)-1]
 
@@ -356,6 +473,32 @@ func (bc *Blockchain) CreateBlockWithPBFT(transactions []*Transaction, nodes []s
 	sequence := int64(len(bc.Blocks))
 	pbft := NewPBFT(nodeID, nodes, newBlock, sequence)
 
+	// Each validator signs its own messages, including the simulated ones from the other nodes
+	signers := make(map[string]Signer)
+	for _, validator := range validators {
+		signers[validator.Address] = validator.Signer
+	}
+	if signer := signers[nodeID]; signer != nil {
+		if err := pbft.SetSigner(signer); err != nil {
+			return err
+		}
+	}
+	nodeMessage := func(msgType PBFTMessageType, node string) (*PBFTMessage, error) {
+		signature, err := signPBFTMessage(signers[node], msgType, newBlock.Hash, node, sequence, 0)
+		if err != nil {
+			return nil, err
+		}
+		return &PBFTMessage{
+			Type:      msgType,
+			BlockHash: newBlock.Hash,
+			NodeID:    node,
+			Sequence:  sequence,
+			ViewID:    0,
+			Timestamp: time.Now(),
+			Signature: signature,
+		}, nil
+	}
+
 	// Simulate PBFT consensus process
 	fmt.Printf("Starting PBFT consensus for block #%d...\n", newBlock.Index)
 	fmt.Printf("  Total nodes: %d, Required votes: %d (2f+1)\n", pbft.TotalNodes, pbft.RequiredVotes)
@@ -373,13 +516,9 @@ func (bc *Blockchain) CreateBlockWithPBFT(transactions []*Transaction, nodes []s
 	} else {
 		// Simulate receiving pre-prepare from primary
 		fmt.Println("\n  Phase 1: Pre-Prepare (Receiving from primary)")
-		primaryMsg := &PBFTMessage{
-			Type:      PrePrepare,
-			BlockHash: newBlock.Hash,
-			NodeID:    pbft.GetPrimaryNode(),
-			Sequence:  sequence,
-			ViewID:    0,
-			Timestamp: time.Now(),
+		primaryMsg, err := nodeMessage(PrePrepare, pbft.GetPrimaryNode())
+		if err != nil {
+			return err
 		}
 		if err := pbft.ProcessPrePrepare(primaryMsg); err != nil {
 			return fmt.Errorf("processing pre-prepare failed: %v", err)
@@ -397,13 +536,9 @@ func (bc *Blockchain) CreateBlockWithPBFT(transactions []*Transaction, nodes []s
 	// Simulate receiving prepare messages from other nodes
 	for i, node := range nodes {
 		if node != nodeID {
-			msg := &PBFTMessage{
-				Type:      Prepare,
-				BlockHash: newBlock.Hash,
-				NodeID:    node,
-				Sequence:  sequence,
-				ViewID:    0,
-				Timestamp: time.Now(),
+			msg, err := nodeMessage(Prepare, node)
+			if err != nil {
+				return err
 			}
 			pbft.ProcessPrepare(msg)
 			if i < 3 { // Show first 3 for clarity
@@ -428,13 +563,9 @@ func (bc *Blockchain) CreateBlockWithPBFT(transactions []*Transaction, nodes []s
 	// Simulate receiving commit messages from other nodes
 	for i, node := range nodes {
 		if node != nodeID {
-			msg := &PBFTMessage{
-				Type:      Commit,
-				BlockHash: newBlock.Hash,
-				NodeID:    node,
-				Sequence:  sequence,
-				ViewID:    0,
-				Timestamp: time.Now(),
+			msg, err := nodeMessage(Commit, node)
+			if err != nil {
+				return err
 			}
 			pbft.ProcessCommit(msg)
 			if i < 3 { // Show first 3 for clarity
@@ -455,7 +586,19 @@ func (bc *Blockchain) CreateBlockWithPBFT(transactions []*Transaction, nodes []s
 		return fmt.Errorf("PBFT consensus validation failed")
 	}
 
-	bc.Blocks = append(bc.Blocks, newBlock)
+	if _, keyErr := ValidatorKeys(validators); validators != nil && keyErr != nil {
+		fmt.Printf("    No quorum certificate: %v\n", keyErr)
+	} else if validators != nil {
+		qc, err := pbft.commitCertificate(validators)
+		if err != nil {
+			return fmt.Errorf("quorum certificate failed: %v", err)
+		}
+		newBlock.QuorumCert = qc
+		fmt.Printf("    Quorum certificate: %d/%d signers, %d bytes (instead of %d signatures)\n",
+			qc.SignerCount(), len(validators), qc.Size(), qc.SignerCount())
+	}
+
+	bc.appendBlock(newBlock)
 	fmt.Printf("\nBlock #%d added to the blockchain using PBFT!\n", newBlock.Index)
 	fmt.Printf("  Byzantine fault tolerance: Can tolerate %d faulty nodes\n\n", (pbft.TotalNodes-1)/3)
 
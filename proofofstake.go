@@ -115,6 +115,9 @@ func (bc *Blockchain) CreateBlockWithPoS(transactions []*Transaction, validatorA
 			return err
 		}
 	}
+	if err := ValidateBlockTransactions(transactions); err != nil {
+		return err
+	}
 
 	prevBlock := bc.Blocks[len(bc.Blocks)-1]
 
@@ -141,7 +144,7 @@ func (bc *Blockchain) CreateBlockWithPoS(transactions []*Transaction, validatorA
 	// Calculate hash (PoS doesn't require mining, just hash)
 	newBlock.Hash = newBlock.CalculateHash()
 
-	bc.Blocks = append(bc.Blocks, newBlock)
+	bc.appendBlock(newBlock)
 	fmt.Printf("Block #%d added to the blockchain using Proof of Stake! (Validator: %s)\n\n", newBlock.Index, validatorAddress)
 	return nil
 }
@@ -0,0 +1,308 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// proxyStateKey is the reserved state key holding a proxy's admin data, kept apart from the
+// storage its implementation uses (like an EIP-1967 storage slot). Implementations cannot
+// write it, since caller-chosen keys with the reserved prefix are rejected.
+const proxyStateKey = reservedStatePrefix + "proxy"
+
+// Event signatures emitted by proxy contracts
+const (
+	EventUpgraded         = "Upgraded(address)"
+	EventUpgradeScheduled = "UpgradeScheduled(address,uint256)"
+	EventUpgradeCanceled  = "UpgradeCanceled(address)"
+	EventAdminChanged     = "AdminChanged(address,address)"
+)
+
+// ImplementationRecord is one entry of a proxy's implementation history
+type ImplementationRecord struct {
+	Implementation string `json:"implementation"`
+	BlockNumber    int    `json:"blockNumber"` // Block the implementation took effect at (-1 if unknown)
+}
+
+// executeProxy executes an upgradeable proxy contract.
+// The proxy keeps the storage and delegates every call it does not handle itself to its
+// implementation, whose logic then runs against the proxy's state, address and deployer.
+// The admin (initially the deployer) can upgrade to a new implementation, which takes
+// effect after timelock blocks when the proxy was deployed with a timelock.
+func (sc *SmartContract) executeProxy(function string, ctx *ContractContext) (interface{}, error) {
+	sc.mu.RLock()
+	proxy, initialized := sc.State[proxyStateKey].(map[string]interface{})
+	sc.mu.RUnlock()
+	if !initialized {
+		if function != ContractConstructor {
+			return nil, fmt.Errorf("proxy not initialized")
+		}
+		return sc.initProxy(ctx)
+	}
+
+	sc.mu.RLock()
+	admin, _ := proxy["admin"].(string)
+	implementation, _ := proxy["implementation"].(string)
+	pending, _ := proxy["pendingImplementation"].(string)
+	readyAt, _ := proxy["upgradeReadyAt"].(float64)
+	timelock, _ := proxy["timelock"].(float64)
+	sc.mu.RUnlock()
+
+	switch function {
+	case ContractConstructor:
+		return nil, fmt.Errorf("proxy already initialized")
+
+	case "upgradeTo":
+		if ctx.Caller != admin {
+			return nil, fmt.Errorf("only admin can upgrade the proxy")
+		}
+		if err := validateArgsCount(ctx.Args, 1, "upgradeTo"); err != nil {
+			return nil, err
+		}
+		next := ctx.StringArg(0)
+		if err := sc.checkImplementation(ctx, next); err != nil {
+			return nil, err
+		}
+		if timelock == 0 {
+			return sc.upgradeProxy(ctx, proxy, next), nil
+		}
+
+		if ctx.BlockNumber < 0 {
+			return nil, fmt.Errorf("block height is not available in this context")
+		}
+		readyAt = float64(ctx.BlockNumber) + timelock
+		sc.mu.Lock()
+		proxy["pendingImplementation"] = next
+		proxy["upgradeReadyAt"] = readyAt
+		sc.mu.Unlock()
+
+		sc.emitLog(ctx, EventUpgradeScheduled, []string{next, strconv.Itoa(int(readyAt))}, "")
+		return fmt.Sprintf("Upgrade to %s scheduled for block %d", truncateAddress(next), int(readyAt)), nil
+
+	case "applyUpgrade":
+		if ctx.Caller != admin {
+			return nil, fmt.Errorf("only admin can upgrade the proxy")
+		}
+		if pending == "" {
+			return nil, fmt.Errorf("no upgrade scheduled")
+		}
+		if float64(ctx.BlockNumber) < readyAt {
+			return nil, fmt.Errorf("upgrade is timelocked until block %d", int(readyAt))
+		}
+		if err := sc.checkImplementation(ctx, pending); err != nil {
+			return nil, err
+		}
+		return sc.upgradeProxy(ctx, proxy, pending), nil
+
+	case "cancelUpgrade":
+		if ctx.Caller != admin {
+			return nil, fmt.Errorf("only admin can cancel upgrades")
+		}
+		if pending == "" {
+			return nil, fmt.Errorf("no upgrade scheduled")
+		}
+		sc.mu.Lock()
+		delete(proxy, "pendingImplementation")
+		delete(proxy, "upgradeReadyAt")
+		sc.mu.Unlock()
+
+		sc.emitLog(ctx, EventUpgradeCanceled, []string{pending}, "")
+		return fmt.Sprintf("Upgrade to %s canceled", truncateAddress(pending)), nil
+
+	case "changeAdmin":
+		if ctx.Caller != admin {
+			return nil, fmt.Errorf("only admin can change the admin")
+		}
+		if err := validateArgsCount(ctx.Args, 1, "changeAdmin"); err != nil {
+			return nil, err
+		}
+		next := ctx.StringArg(0)
+		sc.mu.Lock()
+		proxy["admin"] = next
+		sc.mu.Unlock()
+
+		sc.emitLog(ctx, EventAdminChanged, []string{admin, next}, "")
+		return fmt.Sprintf("Admin changed to %s", truncateAddress(next)), nil
+
+	case "implementation":
+		return implementation, nil
+
+	case "admin":
+		return admin, nil
+
+	case "pendingUpgrade":
+		return map[string]interface{}{
+			"implementation": pending,
+			"readyAt":        int(readyAt),
+		}, nil
+
+	case "implementationHistory":
+		return sc.implementationHistory(), nil
+
+	default:
+		return sc.delegate(ctx, implementation, function)
+	}
+}
+
+// initProxy sets the implementation and upgrade timelock; it runs once, at deployment.
+// Any further arguments are passed to the implementation's constructor, which runs against the proxy's storage.
+func (sc *SmartContract) initProxy(ctx *ContractContext) (interface{}, error) {
+	if ctx.Caller != sc.Deployer {
+		return nil, fmt.Errorf("only deployer can initialize the proxy")
+	}
+	if err := validateArgsCount(ctx.Args, 2, ContractConstructor); err != nil {
+		return nil, err
+	}
+	implementation := ctx.StringArg(0)
+	if err := sc.checkImplementation(ctx, implementation); err != nil {
+		return nil, err
+	}
+	timelock, err := ctx.UintArg(1)
+	if err != nil {
+		return nil, err
+	}
+
+	proxy := map[string]interface{}{
+		"admin":    sc.Deployer,
+		"timelock": float64(timelock),
+		"history":  []interface{}{},
+	}
+	sc.setState(proxyStateKey, proxy)
+	message := sc.upgradeProxy(ctx, proxy, implementation)
+
+	impl, err := ctx.registry.GetContract(implementation)
+	if err != nil {
+		return nil, err
+	}
+	if !impl.hasConstructor() {
+		return message, nil
+	}
+	ctx.Args = ctx.Args[2:]
+	if len(ctx.Values) > 2 {
+		ctx.Values = ctx.Values[2:]
+	} else {
+		ctx.Values = nil
+	}
+	return sc.delegate(ctx, implementation, ContractConstructor)
+}
+
+// delegate runs a function of the implementation against the proxy's state
+func (sc *SmartContract) delegate(ctx *ContractContext, implementation, function string) (interface{}, error) {
+	if ctx.registry == nil {
+		return nil, fmt.Errorf("contract calls are not available in this context")
+	}
+	impl, err := ctx.registry.GetContract(implementation)
+	if err != nil {
+		return nil, err
+	}
+	if err := impl.bindArgs(function, ctx); err != nil {
+		return nil, err
+	}
+	ctx.tracer.delegate(ctx, implementation, function)
+	return sc.dispatchAs(impl, function, ctx)
+}
+
+// checkImplementation checks that an address holds a contract a proxy can delegate to
+func (sc *SmartContract) checkImplementation(ctx *ContractContext, implementation string) error {
+	if ctx.registry == nil {
+		return fmt.Errorf("contract calls are not available in this context")
+	}
+	impl, err := ctx.registry.GetContract(implementation)
+	if err != nil {
+		return err
+	}
+	if impl.Type == ContractTypeProxy {
+		return fmt.Errorf("implementation cannot be a proxy")
+	}
+	return nil
+}
+
+// upgradeProxy switches the proxy to a new implementation and records it in the history
+func (sc *SmartContract) upgradeProxy(ctx *ContractContext, proxy map[string]interface{}, implementation string) string {
+	sc.mu.Lock()
+	proxy["implementation"] = implementation
+	delete(proxy, "pendingImplementation")
+	delete(proxy, "upgradeReadyAt")
+	history, _ := proxy["history"].([]interface{})
+	proxy["history"] = append(history, map[string]interface{}{
+		"implementation": implementation,
+		"blockNumber":    float64(ctx.BlockNumber),
+	})
+	sc.mu.Unlock()
+
+	sc.emitLog(ctx, EventUpgraded, []string{implementation}, "")
+	return fmt.Sprintf("Proxy implementation set to %s", truncateAddress(implementation))
+}
+
+// proxyImplementation returns the current implementation of a proxy ("" if not initialized)
+func (sc *SmartContract) proxyImplementation() string {
+	sc.mu.RLock()
+	defer sc.mu.RUnlock()
+	proxy, _ := sc.State[proxyStateKey].(map[string]interface{})
+	implementation, _ := proxy["implementation"].(string)
+	return implementation
+}
+
+// implementationHistory returns every implementation a proxy has used, oldest first
+func (sc *SmartContract) implementationHistory() []ImplementationRecord {
+	sc.mu.RLock()
+	defer sc.mu.RUnlock()
+	proxy, _ := sc.State[proxyStateKey].(map[string]interface{})
+	history, _ := proxy["history"].([]interface{})
+	records := make([]ImplementationRecord, 0, len(history))
+	for _, entry := range history {
+		record, _ := entry.(map[string]interface{})
+		implementation, _ := record["implementation"].(string)
+		blockNumber, _ := record["blockNumber"].(float64)
+		records = append(records, ImplementationRecord{
+			Implementation: implementation,
+			BlockNumber:    int(blockNumber),
+		})
+	}
+	return records
+}
+
+// GetImplementationHistory returns the implementation history of a proxy contract
+func (cr *ContractRegistry) GetImplementationHistory(address string) ([]ImplementationRecord, error) {
+	contract, err := cr.GetContract(address)
+	if err != nil {
+		return nil, err
+	}
+	if contract.Type != ContractTypeProxy {
+		return nil, fmt.Errorf("%s is not a proxy contract", truncateAddress(address))
+	}
+	return contract.implementationHistory(), nil
+}
+
+// proxyABI combines the proxy's own functions with those of its current implementation
+func (cr *ContractRegistry) proxyABI(proxy *SmartContract) (*ContractABI, error) {
+	own, err := GetContractTypeABI(ContractTypeProxy)
+	if err != nil {
+		return nil, err
+	}
+	implementation := proxy.proxyImplementation()
+	if implementation == "" {
+		return own, nil
+	}
+	impl, err := cr.GetContract(implementation)
+	if err != nil {
+		return nil, err
+	}
+	implABI, err := GetContractTypeABI(impl.Type)
+	if err != nil {
+		return nil, err
+	}
+
+	// The proxy's own functions shadow implementation functions of the same name
+	merged := &ContractABI{
+		Type:      ContractTypeProxy,
+		Functions: append([]ABIFunction(nil), own.Functions...),
+		Events:    append(append([]string(nil), own.Events...), implABI.Events...),
+	}
+	for _, fn := range implABI.Functions {
+		if _, err := own.Function(fn.Name); err != nil {
+			merged.Functions = append(merged.Functions, fn)
+		}
+	}
+	return merged, nil
+}
@@ -0,0 +1,202 @@
+package main
+
+import (
+	"crypto/ecdsa"
+	"encoding/hex"
+	"fmt"
+)
+
+// Quorum certificate domains; the domain keeps a vote for one purpose from being replayed as another
+const (
+	QuorumDomainPBFTCommit     = "pbft-commit"     // PBFT commit votes for a block
+	QuorumDomainRaftVote       = "raft-vote"       // Raft votes electing a leader for a term
+	QuorumDomainBridgeApproval = "bridge-approval" // Bridge validator approvals of a transfer
+)
+
+// QuorumCertificate proves that a quorum of validators voted for a subject (such as a block hash):
+// a bitmap of who signed and one MuSig2 signature aggregated from all their votes. It takes
+// MuSigSignatureLength bytes plus one bit per validator, however many validators sign.
+type QuorumCertificate struct {
+	Domain    string `json:"domain"`
+	Subject   string `json:"subject"`
+	Round     int64  `json:"round"`
+	Signers   string `json:"signers"`   // Hex bitmap over the validator list; bit i (LSB first) set if validator i signed
+	Signature string `json:"signature"` // Hex aggregate signature of the signers
+}
+
+// QuorumSize returns the 2f+1 votes needed among n = 3f+1 validators
+func QuorumSize(validators int) int {
+	return 2*((validators-1)/3) + 1
+}
+
+// BuildQuorumCertificate aggregates the votes of the validators at the indices in signers.
+// validatorKeys are the 64-byte secp256k1 keys of the whole validator set, in order. The two
+// MuSig2 rounds run in-process here; across a network the nonces would travel with prepare
+// messages and the partial signatures with commit messages.
+func BuildQuorumCertificate(domain, subject string, round int64, validatorKeys [][]byte, signers map[int]*ecdsa.PrivateKey) (*QuorumCertificate, error) {
+	present := make([]bool, len(validatorKeys))
+	for index := range signers {
+		if index < 0 || index >= len(validatorKeys) {
+			return nil, fmt.Errorf("validator index %d out of range", index)
+		}
+		present[index] = true
+	}
+	indices, keys := quorumSigners(validatorKeys, present)
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("no signers")
+	}
+
+	message := quorumSigningData(domain, subject, round)
+	session, err := NewMuSigSession(keys, message)
+	if err != nil {
+		return nil, err
+	}
+	nonces := make([]*MuSigNonce, len(indices))
+	for i := range indices {
+		if nonces[i], err = NewMuSigNonce(); err != nil {
+			return nil, err
+		}
+		if err := session.AddNonce(i, nonces[i].Public); err != nil {
+			return nil, err
+		}
+	}
+	for i, index := range indices {
+		partial, err := session.Sign(i, signers[index], nonces[i])
+		if err != nil {
+			return nil, fmt.Errorf("validator %d: %v", index, err)
+		}
+		if err := session.AddPartialSignature(i, partial); err != nil {
+			return nil, err
+		}
+	}
+	signature, err := session.Signature()
+	if err != nil {
+		return nil, err
+	}
+
+	return &QuorumCertificate{
+		Domain:    domain,
+		Subject:   subject,
+		Round:     round,
+		Signers:   hex.EncodeToString(NewSignerBitmap(present)),
+		Signature: hex.EncodeToString(signature),
+	}, nil
+}
+
+// ValidatorKeys returns the public keys that verify the quorum certificates of validators
+func ValidatorKeys(validators []*Wallet) ([][]byte, error) {
+	keys := make([][]byte, len(validators))
+	for i, validator := range validators {
+		if validator.PrivateKey == nil || validator.PrivateKey.Curve != secp256k1 {
+			return nil, fmt.Errorf("validator %s must have a secp256k1 key", validator.Address)
+		}
+		keys[i] = validator.Signer.PublicKey()
+	}
+	return keys, nil
+}
+
+// buildValidatorCertificate aggregates the votes of the validator wallets whose addresses voted
+func buildValidatorCertificate(domain, subject string, round int64, validators []*Wallet, voted map[string]bool) (*QuorumCertificate, error) {
+	keys, err := ValidatorKeys(validators)
+	if err != nil {
+		return nil, err
+	}
+	signers := make(map[int]*ecdsa.PrivateKey)
+	for i, validator := range validators {
+		if voted[validator.Address] {
+			signers[i] = validator.PrivateKey
+		}
+	}
+	return BuildQuorumCertificate(domain, subject, round, keys, signers)
+}
+
+// Verify checks that at least quorum validators signed the certificate. Light clients only need
+// the validator keys: the signers' keys are aggregated and the signature checked once.
+func (qc *QuorumCertificate) Verify(validatorKeys [][]byte, quorum int) error {
+	bitmap, err := hex.DecodeString(qc.Signers)
+	if err != nil {
+		return fmt.Errorf("invalid signer bitmap: %v", err)
+	}
+	present, err := ParseSignerBitmap(bitmap, len(validatorKeys))
+	if err != nil {
+		return err
+	}
+	_, keys := quorumSigners(validatorKeys, present)
+	if len(keys) < quorum {
+		return fmt.Errorf("certificate has %d of %d required signers", len(keys), quorum)
+	}
+	signature, err := hex.DecodeString(qc.Signature)
+	if err != nil {
+		return fmt.Errorf("invalid aggregate signature: %v", err)
+	}
+	if !VerifyMuSig(keys, quorumSigningData(qc.Domain, qc.Subject, qc.Round), signature) {
+		return fmt.Errorf("invalid aggregate signature")
+	}
+	return nil
+}
+
+// SignerCount returns the number of validators whose bit is set
+func (qc *QuorumCertificate) SignerCount() int {
+	bitmap, err := hex.DecodeString(qc.Signers)
+	if err != nil {
+		return 0
+	}
+	count := 0
+	for _, b := range bitmap {
+		for ; b != 0; b &= b - 1 {
+			count++
+		}
+	}
+	return count
+}
+
+// Size returns the encoded size of the bitmap and signature in bytes
+func (qc *QuorumCertificate) Size() int {
+	return (len(qc.Signers) + len(qc.Signature)) / 2
+}
+
+// NewSignerBitmap packs one bit per validator, least significant bit first
+func NewSignerBitmap(present []bool) []byte {
+	bitmap := make([]byte, (len(present)+7)/8)
+	for i, signed := range present {
+		if signed {
+			bitmap[i/8] |= 1 << (i % 8)
+		}
+	}
+	return bitmap
+}
+
+// ParseSignerBitmap unpacks a bitmap over validators, rejecting wrong lengths and bits past the end
+func ParseSignerBitmap(bitmap []byte, validators int) ([]bool, error) {
+	if len(bitmap) != (validators+7)/8 {
+		return nil, fmt.Errorf("signer bitmap must be %d bytes for %d validators, got %d", (validators+7)/8, validators, len(bitmap))
+	}
+	for i := validators; i < len(bitmap)*8; i++ {
+		if bitmap[i/8]&(1<<(i%8)) != 0 {
+			return nil, fmt.Errorf("signer bitmap has bits set past validator %d", validators-1)
+		}
+	}
+	present := make([]bool, validators)
+	for i := range present {
+		present[i] = bitmap[i/8]&(1<<(i%8)) != 0
+	}
+	return present, nil
+}
+
+// quorumSigners returns the indices and keys of the validators present, in validator order
+func quorumSigners(validatorKeys [][]byte, present []bool) ([]int, [][]byte) {
+	var indices []int
+	var keys [][]byte
+	for i, signed := range present {
+		if signed {
+			indices = append(indices, i)
+			keys = append(keys, validatorKeys[i])
+		}
+	}
+	return indices, keys
+}
+
+// quorumSigningData returns the message every validator in a certificate signs
+func quorumSigningData(domain, subject string, round int64) []byte {
+	return []byte(fmt.Sprintf("qc:%s:%s:%d", domain, subject, round))
+}
@@ -70,6 +70,7 @@ type RaftNode struct {
 	CommitIndex   int64
 	LastApplied   int64
 	LeaderID      string
+	LeaderCert    *QuorumCertificate // Aggregated votes that elected this node (with validator wallets)
 
 	// Leader state
 	NextIndex  []int64
@@ -82,6 +83,10 @@ type RaftNode struct {
 
 	mu         sync.RWMutex
 	Blockchain *Blockchain
+	signer     Signer // Signs this node's messages; nil keeps the plain hash
+	// peerSigners sign the simulated responses of the peers (nil entries keep the plain hash)
+	peerSigners map[string]Signer
+	voters      map[string]bool // Nodes that voted for this node in its current election, itself included
 }
 
 // NewRaftNode creates a new Raft node
@@ -123,6 +128,8 @@ func (rn *RaftNode) RequestVote() (*RaftMessage, error) {
 	rn.CurrentTerm++
 	rn.VotedFor = rn.ID
 	rn.VotesReceived = 1
+	rn.voters = map[string]bool{rn.ID: true}
+	rn.LeaderCert = nil
 
 	// Get last log index and term
 	lastLogIndex := int64(len(rn.Log))
@@ -138,7 +145,9 @@ func (rn *RaftNode) RequestVote() (*RaftMessage, error) {
 		LastLogIndex: lastLogIndex,
 		LastLogTerm:  lastLogTerm,
 		Timestamp:    time.Now(),
-		Signature:    rn.signMessage(),
+	}
+	if err := rn.signMessage(msg); err != nil {
+		return nil, err
 	}
 
 	return msg, nil
@@ -149,6 +158,10 @@ func (rn *RaftNode) ProcessRequestVote(msg *RaftMessage) (*RaftMessage, error) {
 	rn.mu.Lock()
 	defer rn.mu.Unlock()
 
+	if err := rn.verifyMessage(msg); err != nil {
+		return nil, err
+	}
+
 	voteGranted := false
 
 	// If term is higher, update term and become follower
@@ -185,7 +198,9 @@ func (rn *RaftNode) ProcessRequestVote(msg *RaftMessage) (*RaftMessage, error) {
 		From:        rn.ID,
 		VoteGranted: voteGranted,
 		Timestamp:   time.Now(),
-		Signature:   rn.signMessage(),
+	}
+	if err := rn.signMessage(resp); err != nil {
+		return nil, err
 	}
 
 	return resp, nil
@@ -196,6 +211,10 @@ func (rn *RaftNode) ProcessRequestVoteResponse(msg *RaftMessage) error {
 	rn.mu.Lock()
 	defer rn.mu.Unlock()
 
+	if err := rn.verifyMessage(msg); err != nil {
+		return err
+	}
+
 	// Ignore if term is outdated
 	if msg.Term < rn.CurrentTerm {
 		return nil
@@ -210,8 +229,9 @@ func (rn *RaftNode) ProcessRequestVoteResponse(msg *RaftMessage) error {
 	}
 
 	// Count vote if granted and we're still a candidate
-	if rn.State == RaftCandidate && msg.VoteGranted {
+	if rn.State == RaftCandidate && msg.VoteGranted && !rn.voters[msg.NodeID] {
 		rn.VotesReceived++
+		rn.voters[msg.NodeID] = true
 
 		// Check if we won the election
 		if rn.VotesReceived > len(rn.Peers)/2 {
@@ -255,7 +275,9 @@ func (rn *RaftNode) AppendEntries(entries []*RaftLogEntry, prevLogIndex, prevLog
 		Entries:      entries,
 		LeaderCommit: leaderCommit,
 		Timestamp:    time.Now(),
-		Signature:    rn.signMessage(),
+	}
+	if err := rn.signMessage(msg); err != nil {
+		return nil, err
 	}
 
 	return msg, nil
@@ -266,6 +288,10 @@ func (rn *RaftNode) ProcessAppendEntries(msg *RaftMessage) (*RaftMessage, error)
 	rn.mu.Lock()
 	defer rn.mu.Unlock()
 
+	if err := rn.verifyMessage(msg); err != nil {
+		return nil, err
+	}
+
 	success := false
 
 	// If term is higher, update term and become follower
@@ -328,7 +354,9 @@ func (rn *RaftNode) ProcessAppendEntries(msg *RaftMessage) (*RaftMessage, error)
 		From:      rn.ID,
 		Success:   success,
 		Timestamp: time.Now(),
-		Signature: rn.signMessage(),
+	}
+	if err := rn.signMessage(resp); err != nil {
+		return nil, err
 	}
 
 	return resp, nil
@@ -339,6 +367,10 @@ func (rn *RaftNode) ProcessAppendEntriesResponse(msg *RaftMessage, peerIndex int
 	rn.mu.Lock()
 	defer rn.mu.Unlock()
 
+	if err := rn.verifyMessage(msg); err != nil {
+		return err
+	}
+
 	// If term is higher, update term and become follower
 	if msg.Term > rn.CurrentTerm {
 		rn.CurrentTerm = msg.Term
@@ -406,7 +438,7 @@ func (rn *RaftNode) applyCommittedEntries() {
 			}
 
 			if !blockExists {
-				rn.Blockchain.Blocks = append(rn.Blockchain.Blocks, entry.Command)
+				rn.Blockchain.appendBlock(entry.Command)
 				fmt.Printf("    Applied committed block #%d to blockchain\n", entry.Command.Index)
 			}
 		}
@@ -435,13 +467,16 @@ func (rn *RaftNode) StartElection() error {
 		}
 
 		// Simulate peer response
-		voteMsg := &RaftMessage{
+		voteMsg, err := rn.peerMessage(&RaftMessage{
 			Type:        RaftRequestVoteResp,
 			Term:        rn.CurrentTerm,
 			NodeID:      peer,
 			From:        peer,
 			VoteGranted: true, // Assume peers grant vote for simulation
 			Timestamp:   time.Now(),
+		})
+		if err != nil {
+			return err
 		}
 
 		if err := rn.ProcessRequestVoteResponse(voteMsg); err != nil {
@@ -512,13 +547,16 @@ func (rn *RaftNode) ReplicateLog(block *Block) error {
 		}
 
 		// Simulate follower response
-		resp := &RaftMessage{
+		resp, err := rn.peerMessage(&RaftMessage{
 			Type:      RaftAppendEntriesResp,
 			Term:      rn.CurrentTerm,
 			NodeID:    peer,
 			From:      peer,
 			Success:   true, // Assume success for simulation
 			Timestamp: time.Now(),
+		})
+		if err != nil {
+			return err
 		}
 
 		if err := rn.ProcessAppendEntriesResponse(resp, i); err != nil {
@@ -567,13 +605,16 @@ func (rn *RaftNode) SendHeartbeat() error {
 		}
 
 		// Simulate follower response
-		resp := &RaftMessage{
+		resp, err := rn.peerMessage(&RaftMessage{
 			Type:      RaftAppendEntriesResp,
 			Term:      rn.CurrentTerm,
 			NodeID:    peer,
 			From:      peer,
 			Success:   true,
 			Timestamp: time.Now(),
+		})
+		if err != nil {
+			return err
 		}
 
 		rn.ProcessAppendEntriesResponse(resp, i)
@@ -595,11 +636,111 @@ func (rn *RaftNode) CheckElectionTimeout() bool {
 	return time.Since(rn.LastHeartbeat) > rn.ElectionTimeout
 }
 
-// signMessage creates a simple signature for a message
-func (rn *RaftNode) signMessage() string {
-	data := fmt.Sprintf("%s:%d:%d", rn.ID, rn.CurrentTerm, time.Now().UnixNano())
-	hash := sha256.Sum256([]byte(data))
-	return hex.EncodeToString(hash[:])
+// SetSigner makes the node sign its messages with a key of any registered scheme.
+// The node ID must be the signer's address so other nodes can check who sent a message.
+func (rn *RaftNode) SetSigner(signer Signer) error {
+	rn.mu.Lock()
+	defer rn.mu.Unlock()
+	if signer.Address() != rn.ID {
+		return fmt.Errorf("signer address %s does not match node ID %s", signer.Address(), rn.ID)
+	}
+	rn.signer = signer
+	return nil
+}
+
+// signMessage signs a message with the node's signer, or creates a simple hash signature if it has none
+func (rn *RaftNode) signMessage(msg *RaftMessage) error {
+	if rn.signer != nil {
+		signature, err := SignMessage(rn.signer, msg.signingData())
+		if err != nil {
+			return err
+		}
+		msg.Signature = signature.Encode()
+		return nil
+	}
+	hash := sha256.Sum256(msg.signingData())
+	msg.Signature = hex.EncodeToString(hash[:])
+	return nil
+}
+
+// peerMessage signs a simulated response as the peer named in its node ID would
+func (rn *RaftNode) peerMessage(msg *RaftMessage) (*RaftMessage, error) {
+	if signer := rn.peerSigners[msg.NodeID]; signer != nil {
+		signature, err := SignMessage(signer, msg.signingData())
+		if err != nil {
+			return nil, err
+		}
+		msg.Signature = signature.Encode()
+		return msg, nil
+	}
+	hash := sha256.Sum256(msg.signingData())
+	msg.Signature = hex.EncodeToString(hash[:])
+	return msg, nil
+}
+
+// signingData returns the bytes a Raft message signature covers; entries are covered by their block hashes
+func (msg *RaftMessage) signingData() []byte {
+	data := fmt.Sprintf("%s:%d:%s:%s:%d:%d:%t:%d:%d:%d:%t:%d",
+		msg.Type, msg.Term, msg.NodeID, msg.From, msg.LastLogIndex, msg.LastLogTerm, msg.VoteGranted,
+		msg.PrevLogIndex, msg.PrevLogTerm, msg.LeaderCommit, msg.Success, msg.Timestamp.UnixNano())
+	for _, entry := range msg.Entries {
+		blockHash := ""
+		if entry.Command != nil {
+			blockHash = entry.Command.Hash
+		}
+		data += fmt.Sprintf(":%d/%d/%s", entry.Index, entry.Term, blockHash)
+	}
+	return []byte(data)
+}
+
+// VerifySignature checks the message's signature against its node ID. A node whose ID is an
+// address must send a scheme signature; anyone can compute a plain hash, so it is only accepted
+// from nodes with plain IDs, and only if it matches the message.
+func (msg *RaftMessage) VerifySignature() error {
+	if !IsSchemeSignature(msg.Signature) {
+		if ValidateAddress(msg.NodeID) == nil {
+			return fmt.Errorf("%s message from %s must carry a scheme signature", msg.Type, msg.NodeID)
+		}
+		if hash := sha256.Sum256(msg.signingData()); msg.Signature != hex.EncodeToString(hash[:]) {
+			return fmt.Errorf("invalid %s hash from %s", msg.Type, msg.NodeID)
+		}
+		return nil
+	}
+	if err := VerifyEncodedSignature(msg.Signature, msg.signingData(), msg.NodeID); err != nil {
+		return fmt.Errorf("invalid %s signature from %s: %v", msg.Type, msg.NodeID, err)
+	}
+	return nil
+}
+
+// voteCertificate aggregates the votes that elected this node leader into a quorum certificate
+func (rn *RaftNode) voteCertificate(validators []*Wallet) (*QuorumCertificate, error) {
+	rn.mu.RLock()
+	defer rn.mu.RUnlock()
+	if rn.State != RaftLeader {
+		return nil, fmt.Errorf("only a leader has a vote certificate")
+	}
+	return buildValidatorCertificate(QuorumDomainRaftVote, rn.ID, rn.CurrentTerm, validators, rn.voters)
+}
+
+// VerifyLeaderCertificate checks that a majority of the validators (64-byte secp256k1 keys, in
+// Raft node order) voted for leader in term
+func VerifyLeaderCertificate(qc *QuorumCertificate, leader string, term int64, validatorKeys [][]byte) error {
+	if qc == nil {
+		return fmt.Errorf("no leader certificate")
+	}
+	if qc.Domain != QuorumDomainRaftVote || qc.Subject != leader || qc.Round != term {
+		return fmt.Errorf("quorum certificate is not a vote for %s in term %d", truncateAddress(leader), term)
+	}
+	return qc.Verify(validatorKeys, len(validatorKeys)/2+1)
+}
+
+// verifyMessage checks a received message's signature; once this node signs its own
+// messages it accepts only scheme signatures from the others
+func (rn *RaftNode) verifyMessage(msg *RaftMessage) error {
+	if rn.signer != nil && !IsSchemeSignature(msg.Signature) {
+		return fmt.Errorf("%s message from %s is not signed with a scheme signature", msg.Type, msg.NodeID)
+	}
+	return msg.VerifySignature()
 }
 
 // GetStatus returns the current status of the Raft node
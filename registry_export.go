@@ -0,0 +1,308 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+)
+
+// RegistryExportVersion is the format version written by ContractRegistry.Export
+const RegistryExportVersion = 1
+
+// RegistryExport is a portable snapshot of every contract in a registry.
+// Hash is the SHA-256 of the canonical JSON of Version and Contracts, so tampering or
+// corruption is detected on import.
+type RegistryExport struct {
+	Version   int              `json:"version"`
+	Contracts []ContractExport `json:"contracts"` // Sorted by address
+	Hash      string           `json:"hash"`
+}
+
+// ContractExport is the exported form of one contract: its code, metadata and typed state
+type ContractExport struct {
+	Address        string                `json:"address"`
+	Deployer       string                `json:"deployer"`
+	Type           ContractType          `json:"type"`
+	Bytecode       string                `json:"bytecode"`
+	CreatedAt      int64                 `json:"createdAt"`
+	NonReentrant   bool                  `json:"nonReentrant"`
+	StorageDeposit float64               `json:"storageDeposit"`
+	Archived       bool                  `json:"archived"`
+	State          map[string]TypedValue `json:"state"`
+}
+
+// TypedValue is a state value tagged with its Go type, so that e.g. map[string]int
+// and map[string]bool survive the JSON round trip. Objects and lists hold TypedValues.
+type TypedValue struct {
+	Type  string          `json:"type"`
+	Value json.RawMessage `json:"value"`
+}
+
+// Type tags of values that can be stored in contract state
+const (
+	stateTypeFloat     = "float64"
+	stateTypeInt       = "int"
+	stateTypeUint      = "uint64"
+	stateTypeBool      = "bool"
+	stateTypeString    = "string"
+	stateTypeFloatMap  = "map[string]float64"
+	stateTypeIntMap    = "map[string]int"
+	stateTypeBoolMap   = "map[string]bool"
+	stateTypeStringMap = "map[string]string"
+	stateTypeStrings   = "[]string"
+	stateTypeObject    = "object" // map[string]interface{}
+	stateTypeList      = "list"   // []interface{}
+	stateTypeNull      = "null"
+)
+
+// stateValueTypes maps the plain type tags to the Go type they decode into
+var stateValueTypes = map[string]reflect.Type{
+	stateTypeFloat:     reflect.TypeOf(float64(0)),
+	stateTypeInt:       reflect.TypeOf(int(0)),
+	stateTypeUint:      reflect.TypeOf(uint64(0)),
+	stateTypeBool:      reflect.TypeOf(false),
+	stateTypeString:    reflect.TypeOf(""),
+	stateTypeFloatMap:  reflect.TypeOf(map[string]float64{}),
+	stateTypeIntMap:    reflect.TypeOf(map[string]int{}),
+	stateTypeBoolMap:   reflect.TypeOf(map[string]bool{}),
+	stateTypeStringMap: reflect.TypeOf(map[string]string{}),
+	stateTypeStrings:   reflect.TypeOf([]string{}),
+}
+
+// Export snapshots every contract in the registry with its typed state
+func (cr *ContractRegistry) Export() (*RegistryExport, error) {
+	contracts := cr.GetAllContracts()
+	sort.Slice(contracts, func(i, j int) bool { return contracts[i].Address < contracts[j].Address })
+
+	export := &RegistryExport{
+		Version:   RegistryExportVersion,
+		Contracts: make([]ContractExport, 0, len(contracts)),
+	}
+	for _, contract := range contracts {
+		contract.mu.RLock()
+		state, err := encodeState(contract.State)
+		exported := ContractExport{
+			Address:        contract.Address,
+			Deployer:       contract.Deployer,
+			Type:           contract.Type,
+			Bytecode:       contract.Bytecode,
+			CreatedAt:      contract.CreatedAt,
+			NonReentrant:   contract.NonReentrant,
+			StorageDeposit: contract.StorageDeposit,
+			Archived:       contract.Archived,
+			State:          state,
+		}
+		contract.mu.RUnlock()
+		if err != nil {
+			return nil, fmt.Errorf("contract %s: %v", contract.Address, err)
+		}
+		export.Contracts = append(export.Contracts, exported)
+	}
+
+	hash, err := export.ComputeHash()
+	if err != nil {
+		return nil, err
+	}
+	export.Hash = hash
+	return export, nil
+}
+
+// ExportJSON exports the registry as indented JSON
+func (cr *ContractRegistry) ExportJSON() ([]byte, error) {
+	export, err := cr.Export()
+	if err != nil {
+		return nil, err
+	}
+	return json.MarshalIndent(export, "", "  ")
+}
+
+// ComputeHash returns the verification hash of the export's contents
+func (e *RegistryExport) ComputeHash() (string, error) {
+	// encoding/json sorts map keys, so the encoding is canonical
+	data, err := json.Marshal(struct {
+		Version   int              `json:"version"`
+		Contracts []ContractExport `json:"contracts"`
+	}{e.Version, e.Contracts})
+	if err != nil {
+		return "", fmt.Errorf("failed to encode export: %v", err)
+	}
+	hash := sha256.Sum256(data)
+	return hex.EncodeToString(hash[:]), nil
+}
+
+// Verify checks the export's version and verification hash
+func (e *RegistryExport) Verify() error {
+	if e.Version != RegistryExportVersion {
+		return fmt.Errorf("unsupported export version: %d", e.Version)
+	}
+	hash, err := e.ComputeHash()
+	if err != nil {
+		return err
+	}
+	if hash != e.Hash {
+		return fmt.Errorf("export hash mismatch: expected %s, computed %s", e.Hash, hash)
+	}
+	return nil
+}
+
+// Import verifies an export and adds its contracts to the registry.
+// Nothing is imported if the export is invalid or any of its contracts already exists.
+func (cr *ContractRegistry) Import(export *RegistryExport) error {
+	if err := export.Verify(); err != nil {
+		return err
+	}
+
+	contracts := make([]*SmartContract, 0, len(export.Contracts))
+	for _, exported := range export.Contracts {
+		state, err := decodeState(exported.State)
+		if err != nil {
+			return fmt.Errorf("contract %s: %v", exported.Address, err)
+		}
+		if exported.Type == ContractTypeWASM {
+			if err := validateWasmBytecode(exported.Bytecode); err != nil {
+				return fmt.Errorf("contract %s: %v", exported.Address, err)
+			}
+		}
+		contracts = append(contracts, &SmartContract{
+			Address:        exported.Address,
+			Deployer:       exported.Deployer,
+			Type:           exported.Type,
+			Bytecode:       exported.Bytecode,
+			State:          state,
+			CreatedAt:      exported.CreatedAt,
+			NonReentrant:   exported.NonReentrant,
+			StorageDeposit: exported.StorageDeposit,
+			Archived:       exported.Archived,
+		})
+	}
+
+	cr.mu.Lock()
+	defer cr.mu.Unlock()
+	for _, contract := range contracts {
+		if _, exists := cr.Contracts[contract.Address]; exists {
+			return fmt.Errorf("contract already exists at %s", contract.Address)
+		}
+	}
+	for _, contract := range contracts {
+		cr.Contracts[contract.Address] = contract
+	}
+	return nil
+}
+
+// ImportJSON imports contracts from JSON written by ExportJSON
+func (cr *ContractRegistry) ImportJSON(data []byte) error {
+	var export RegistryExport
+	if err := json.Unmarshal(data, &export); err != nil {
+		return fmt.Errorf("invalid registry export: %v", err)
+	}
+	return cr.Import(&export)
+}
+
+func encodeState(state map[string]interface{}) (map[string]TypedValue, error) {
+	encoded := make(map[string]TypedValue, len(state))
+	for key, value := range state {
+		tv, err := encodeStateValue(value)
+		if err != nil {
+			return nil, fmt.Errorf("state %q: %v", key, err)
+		}
+		encoded[key] = tv
+	}
+	return encoded, nil
+}
+
+// encodeStateValue tags a state value with its type, recursing into objects and lists
+func encodeStateValue(value interface{}) (TypedValue, error) {
+	var tag string
+	var plain interface{} = value
+	switch v := value.(type) {
+	case nil:
+		tag = stateTypeNull
+	case map[string]interface{}:
+		tag = stateTypeObject
+		object, err := encodeState(v)
+		if err != nil {
+			return TypedValue{}, err
+		}
+		plain = object
+	case []interface{}:
+		tag = stateTypeList
+		list := make([]TypedValue, len(v))
+		for i, item := range v {
+			tv, err := encodeStateValue(item)
+			if err != nil {
+				return TypedValue{}, fmt.Errorf("item %d: %v", i, err)
+			}
+			list[i] = tv
+		}
+		plain = list
+	default:
+		for name, t := range stateValueTypes {
+			if reflect.TypeOf(value) == t {
+				tag = name
+				break
+			}
+		}
+		if tag == "" {
+			return TypedValue{}, fmt.Errorf("unsupported state value type %T", value)
+		}
+	}
+
+	data, err := json.Marshal(plain)
+	if err != nil {
+		return TypedValue{}, err
+	}
+	return TypedValue{Type: tag, Value: data}, nil
+}
+
+func decodeState(encoded map[string]TypedValue) (map[string]interface{}, error) {
+	state := make(map[string]interface{}, len(encoded))
+	for key, tv := range encoded {
+		value, err := decodeStateValue(tv)
+		if err != nil {
+			return nil, fmt.Errorf("state %q: %v", key, err)
+		}
+		state[key] = value
+	}
+	return state, nil
+}
+
+// decodeStateValue restores a tagged state value to its original Go type
+func decodeStateValue(tv TypedValue) (interface{}, error) {
+	switch tv.Type {
+	case stateTypeNull:
+		return nil, nil
+	case stateTypeObject:
+		var object map[string]TypedValue
+		if err := json.Unmarshal(tv.Value, &object); err != nil {
+			return nil, err
+		}
+		return decodeState(object)
+	case stateTypeList:
+		var items []TypedValue
+		if err := json.Unmarshal(tv.Value, &items); err != nil {
+			return nil, err
+		}
+		list := make([]interface{}, len(items))
+		for i, item := range items {
+			value, err := decodeStateValue(item)
+			if err != nil {
+				return nil, fmt.Errorf("item %d: %v", i, err)
+			}
+			list[i] = value
+		}
+		return list, nil
+	}
+
+	t, known := stateValueTypes[tv.Type]
+	if !known {
+		return nil, fmt.Errorf("unknown state value type %q", tv.Type)
+	}
+	target := reflect.New(t)
+	if err := json.Unmarshal(tv.Value, target.Interface()); err != nil {
+		return nil, fmt.Errorf("invalid %s value: %v", tv.Type, err)
+	}
+	return target.Elem().Interface(), nil
+}
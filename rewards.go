@@ -20,6 +20,57 @@ func NewBlockRewardTransaction(minerAddress string, isGenesis bool) *Transaction
 	return NewTransaction("", minerAddress, reward)
 }
 
+// MaxCoinbaseAmount returns the most a block's coinbase may create: the block reward plus the fees
+// paid by the block's other transactions
+func MaxCoinbaseAmount(transactions []*Transaction) float64 {
+	fees := 0.0
+	for _, tx := range transactions {
+		if !tx.IsCoinbase() {
+			fees += tx.Fee
+		}
+	}
+	return BlockReward + fees
+}
+
+// ValidateBlockTransactions checks the rules every block's transactions must follow: at most one
+// coinbase, placed first and creating no more than MaxCoinbaseAmount, a known type with a valid
+// payload, and a valid signature by the sender's key on every other transaction. Signatures are
+// checked in parallel and cached (see verification.go). Balances are checked separately by
+// ValidateTransaction.
+func ValidateBlockTransactions(transactions []*Transaction) error {
+	signatureErrs := signatureVerifier.VerifyTransactions(transactions)
+	for i, tx := range transactions {
+		if err := tx.ValidateEnvelope(); err != nil {
+			return fmt.Errorf("transaction #%d: %v", i+1, err)
+		}
+		if err := tx.checkTypeAccepted(); err != nil {
+			return fmt.Errorf("transaction #%d: %v", i+1, err)
+		}
+		if !tx.IsCoinbase() {
+			if err := signatureErrs[i]; err != nil {
+				return fmt.Errorf("transaction #%d: %v", i+1, err)
+			}
+			continue
+		}
+		if i > 0 && transactions[0].IsCoinbase() {
+			return fmt.Errorf("transaction #%d: a block can only have one coinbase", i+1)
+		}
+		if i > 0 {
+			return fmt.Errorf("transaction #%d: coinbase must be the first transaction of a block", i+1)
+		}
+		if tx.Asset != NativeAsset || tx.ContractData != "" || tx.Fee != 0 || (tx.Type != TxTypeLegacy && tx.Type != TxTypeTransfer) {
+			return fmt.Errorf("coinbase may only create native coins")
+		}
+		if tx.Amount < 0 {
+			return fmt.Errorf("coinbase amount cannot be negative")
+		}
+		if maxAmount := MaxCoinbaseAmount(transactions); tx.Amount > maxAmount {
+			return fmt.Errorf("coinbase creates %.2f, more than the block reward plus fees (%.2f)", tx.Amount, maxAmount)
+		}
+	}
+	return nil
+}
+
 // GetMinerRewards calculates total rewards earned by a miner/validator
 func (bc *Blockchain) GetMinerRewards(minerAddress string) float64 {
 	rewards := 0.0
@@ -0,0 +1,423 @@
+package main
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"fmt"
+	"math/big"
+)
+
+// RecoverableSignatureLength is the size of an r || s || v signature
+const RecoverableSignatureLength = 65
+
+// secp256k1Curve is the Koblitz curve y² = x³ + 7 used by Bitcoin and Ethereum.
+// The standard library only implements the NIST curves (a = -3), so the arithmetic lives here.
+type secp256k1Curve struct {
+	params *elliptic.CurveParams
+}
+
+var secp256k1 = func() *secp256k1Curve {
+	hexInt := func(s string) *big.Int {
+		n, _ := new(big.Int).SetString(s, 16)
+		return n
+	}
+	return &secp256k1Curve{params: &elliptic.CurveParams{
+		P:       hexInt("fffffffffffffffffffffffffffffffffffffffffffffffffffffffefffffc2f"),
+		N:       hexInt("fffffffffffffffffffffffffffffffebaaedce6af48a03bbfd25e8cd0364141"),
+		B:       big.NewInt(7),
+		Gx:      hexInt("79be667ef9dcbbac55a06295ce870b07029bfcdb2dce28d959f2815b16f81798"),
+		Gy:      hexInt("483ada7726a3c4655da4fbfc0e1108a8fd17b448a68554199c47d08ffb10d4b8"),
+		BitSize: 256,
+		Name:    "secp256k1",
+	}}
+}()
+
+// Secp256k1 returns the secp256k1 curve
+func Secp256k1() elliptic.Curve {
+	return secp256k1
+}
+
+// Params returns the curve parameters
+func (c *secp256k1Curve) Params() *elliptic.CurveParams {
+	return c.params
+}
+
+// IsOnCurve reports whether (x, y) satisfies y² = x³ + 7
+func (c *secp256k1Curve) IsOnCurve(x, y *big.Int) bool {
+	p := c.params.P
+	if x.Sign() < 0 || x.Cmp(p) >= 0 || y.Sign() < 0 || y.Cmp(p) >= 0 {
+		return false
+	}
+	y2 := new(big.Int).Mul(y, y)
+	y2.Mod(y2, p)
+	return y2.Cmp(c.rhs(x)) == 0
+}
+
+// rhs returns x³ + 7 mod p
+func (c *secp256k1Curve) rhs(x *big.Int) *big.Int {
+	x3 := new(big.Int).Mul(x, x)
+	x3.Mul(x3, x)
+	x3.Add(x3, c.params.B)
+	return x3.Mod(x3, c.params.P)
+}
+
+// Add returns the sum of two affine points
+func (c *secp256k1Curve) Add(x1, y1, x2, y2 *big.Int) (*big.Int, *big.Int) {
+	return c.affine(c.addJacobian(c.jacobian(x1, y1), c.jacobian(x2, y2)))
+}
+
+// Double returns twice an affine point
+func (c *secp256k1Curve) Double(x1, y1 *big.Int) (*big.Int, *big.Int) {
+	return c.affine(c.doubleJacobian(c.jacobian(x1, y1)))
+}
+
+// ScalarMult returns k·(x, y) for a big-endian scalar k
+func (c *secp256k1Curve) ScalarMult(x, y *big.Int, k []byte) (*big.Int, *big.Int) {
+	return c.affine(c.scalarMultJacobian(c.jacobian(x, y), new(big.Int).SetBytes(k)))
+}
+
+// ScalarBaseMult returns k·G for a big-endian scalar k
+func (c *secp256k1Curve) ScalarBaseMult(k []byte) (*big.Int, *big.Int) {
+	return c.ScalarMult(c.params.Gx, c.params.Gy, k)
+}
+
+// jacobianPoint is (X, Y, Z) representing the affine point (X/Z², Y/Z³); Z = 0 is the point at infinity
+type jacobianPoint struct {
+	x, y, z *big.Int
+}
+
+func (c *secp256k1Curve) jacobian(x, y *big.Int) jacobianPoint {
+	if x.Sign() == 0 && y.Sign() == 0 {
+		return jacobianPoint{new(big.Int), new(big.Int), new(big.Int)}
+	}
+	return jacobianPoint{new(big.Int).Set(x), new(big.Int).Set(y), big.NewInt(1)}
+}
+
+func (c *secp256k1Curve) affine(p jacobianPoint) (*big.Int, *big.Int) {
+	if p.z.Sign() == 0 {
+		return new(big.Int), new(big.Int)
+	}
+	P := c.params.P
+	zInv := new(big.Int).ModInverse(p.z, P)
+	zInv2 := new(big.Int).Mul(zInv, zInv)
+	x := new(big.Int).Mul(p.x, zInv2)
+	x.Mod(x, P)
+	y := new(big.Int).Mul(p.y, zInv2.Mul(zInv2, zInv))
+	y.Mod(y, P)
+	return x, y
+}
+
+// doubleJacobian doubles a point (dbl-2009-l, valid for a = 0)
+func (c *secp256k1Curve) doubleJacobian(p jacobianPoint) jacobianPoint {
+	P := c.params.P
+	if p.z.Sign() == 0 || p.y.Sign() == 0 {
+		return jacobianPoint{new(big.Int), new(big.Int), new(big.Int)}
+	}
+	a := new(big.Int).Mul(p.x, p.x)
+	a.Mod(a, P)
+	b := new(big.Int).Mul(p.y, p.y)
+	b.Mod(b, P)
+	cc := new(big.Int).Mul(b, b)
+	cc.Mod(cc, P)
+
+	// D = 2((X+B)² - A - C)
+	d := new(big.Int).Add(p.x, b)
+	d.Mul(d, d)
+	d.Sub(d, a)
+	d.Sub(d, cc)
+	d.Lsh(d, 1)
+	d.Mod(d, P)
+	e := new(big.Int).Mul(a, big.NewInt(3))
+	f := new(big.Int).Mul(e, e)
+
+	x3 := new(big.Int).Sub(f, new(big.Int).Lsh(d, 1))
+	x3.Mod(x3, P)
+	y3 := new(big.Int).Sub(d, x3)
+	y3.Mul(y3, e)
+	y3.Sub(y3, new(big.Int).Lsh(cc, 3))
+	y3.Mod(y3, P)
+	z3 := new(big.Int).Mul(p.y, p.z)
+	z3.Lsh(z3, 1)
+	z3.Mod(z3, P)
+	return jacobianPoint{x3, y3, z3}
+}
+
+// addJacobian adds two points (add-2007-bl)
+func (c *secp256k1Curve) addJacobian(p, q jacobianPoint) jacobianPoint {
+	if p.z.Sign() == 0 {
+		return q
+	}
+	if q.z.Sign() == 0 {
+		return p
+	}
+	P := c.params.P
+	z1z1 := new(big.Int).Mul(p.z, p.z)
+	z1z1.Mod(z1z1, P)
+	z2z2 := new(big.Int).Mul(q.z, q.z)
+	z2z2.Mod(z2z2, P)
+	u1 := new(big.Int).Mul(p.x, z2z2)
+	u1.Mod(u1, P)
+	u2 := new(big.Int).Mul(q.x, z1z1)
+	u2.Mod(u2, P)
+	s1 := new(big.Int).Mul(p.y, q.z)
+	s1.Mul(s1, z2z2)
+	s1.Mod(s1, P)
+	s2 := new(big.Int).Mul(q.y, p.z)
+	s2.Mul(s2, z1z1)
+	s2.Mod(s2, P)
+
+	h := new(big.Int).Sub(u2, u1)
+	h.Mod(h, P)
+	r := new(big.Int).Sub(s2, s1)
+	r.Mod(r, P)
+	if h.Sign() == 0 {
+		if r.Sign() == 0 {
+			return c.doubleJacobian(p)
+		}
+		return jacobianPoint{new(big.Int), new(big.Int), new(big.Int)}
+	}
+	r.Lsh(r, 1)
+
+	i := new(big.Int).Lsh(h, 1)
+	i.Mul(i, i)
+	i.Mod(i, P)
+	j := new(big.Int).Mul(h, i)
+	v := new(big.Int).Mul(u1, i)
+
+	x3 := new(big.Int).Mul(r, r)
+	x3.Sub(x3, j)
+	x3.Sub(x3, new(big.Int).Lsh(v, 1))
+	x3.Mod(x3, P)
+	y3 := new(big.Int).Sub(v, x3)
+	y3.Mul(y3, r)
+	y3.Sub(y3, new(big.Int).Lsh(new(big.Int).Mul(s1, j), 1))
+	y3.Mod(y3, P)
+	z3 := new(big.Int).Add(p.z, q.z)
+	z3.Mul(z3, z3)
+	z3.Sub(z3, z1z1)
+	z3.Sub(z3, z2z2)
+	z3.Mul(z3, h)
+	z3.Mod(z3, P)
+	return jacobianPoint{x3, y3, z3}
+}
+
+// scalarMultJacobian computes k·p by double-and-add
+func (c *secp256k1Curve) scalarMultJacobian(p jacobianPoint, k *big.Int) jacobianPoint {
+	result := jacobianPoint{new(big.Int), new(big.Int), new(big.Int)}
+	for i := k.BitLen() - 1; i >= 0; i-- {
+		result = c.doubleJacobian(result)
+		if k.Bit(i) == 1 {
+			result = c.addJacobian(result, p)
+		}
+	}
+	return result
+}
+
+// GenerateSecp256k1Key creates a random secp256k1 private key
+func GenerateSecp256k1Key() (*ecdsa.PrivateKey, error) {
+	n := secp256k1.params.N
+	for {
+		keyBytes := make([]byte, 32)
+		if _, err := rand.Read(keyBytes); err != nil {
+			return nil, err
+		}
+		if d := new(big.Int).SetBytes(keyBytes); d.Sign() > 0 && d.Cmp(n) < 0 {
+			return secp256k1PrivateKey(d), nil
+		}
+	}
+}
+
+// secp256k1PrivateKey builds a private key from a scalar in [1, n)
+func secp256k1PrivateKey(d *big.Int) *ecdsa.PrivateKey {
+	x, y := secp256k1.ScalarBaseMult(d.Bytes())
+	return &ecdsa.PrivateKey{
+		PublicKey: ecdsa.PublicKey{Curve: secp256k1, X: x, Y: y},
+		D:         new(big.Int).Set(d),
+	}
+}
+
+// SignRecoverable signs a 32-byte hash with a secp256k1 key and returns r || s || v.
+// The nonce is derived deterministically (RFC 6979), s is normalized to the lower half of the
+// curve order, and v (0 or 1) is the parity of R's y coordinate, which lets verifiers recover the key.
+func SignRecoverable(privateKey *ecdsa.PrivateKey, hash []byte) ([]byte, error) {
+	if privateKey.Curve != secp256k1 {
+		return nil, fmt.Errorf("recoverable signatures require a secp256k1 key")
+	}
+	if len(hash) != 32 {
+		return nil, fmt.Errorf("hash must be 32 bytes, got %d", len(hash))
+	}
+	n := secp256k1.params.N
+	e := new(big.Int).SetBytes(hash)
+
+	k := rfc6979Nonce(privateKey.D, hash)
+	rx, ry := secp256k1.ScalarBaseMult(k.Bytes())
+	r := new(big.Int).Mod(rx, n)
+	if r.Sign() == 0 || rx.Cmp(n) >= 0 {
+		// Astronomically unlikely; such R cannot be recovered from r alone
+		return nil, fmt.Errorf("invalid signature nonce")
+	}
+	recoveryID := byte(ry.Bit(0))
+
+	s := new(big.Int).Mul(r, privateKey.D)
+	s.Add(s, e)
+	s.Mul(s, new(big.Int).ModInverse(k, n))
+	s.Mod(s, n)
+	if s.Sign() == 0 {
+		return nil, fmt.Errorf("invalid signature nonce")
+	}
+	// Low-s: (r, n - s) is also valid, so allow only one form to prevent malleability
+	if s.Cmp(new(big.Int).Rsh(n, 1)) > 0 {
+		s.Sub(n, s)
+		recoveryID ^= 1
+	}
+
+	signature := make([]byte, RecoverableSignatureLength)
+	r.FillBytes(signature[:32])
+	s.FillBytes(signature[32:64])
+	signature[64] = recoveryID
+	return signature, nil
+}
+
+// RecoverPublicKey returns the secp256k1 public key that produced a recoverable signature over hash
+func RecoverPublicKey(hash, signature []byte) (*ecdsa.PublicKey, error) {
+	if len(hash) != 32 {
+		return nil, fmt.Errorf("hash must be 32 bytes, got %d", len(hash))
+	}
+	if len(signature) != RecoverableSignatureLength {
+		return nil, fmt.Errorf("signature must be %d bytes, got %d", RecoverableSignatureLength, len(signature))
+	}
+	v := signature[64]
+	if v >= 27 {
+		v -= 27 // Accept Ethereum's 27/28 encoding
+	}
+	if v > 1 {
+		return nil, fmt.Errorf("invalid recovery id: %d", signature[64])
+	}
+
+	n := secp256k1.params.N
+	P := secp256k1.params.P
+	r := new(big.Int).SetBytes(signature[:32])
+	s := new(big.Int).SetBytes(signature[32:64])
+	if r.Sign() == 0 || r.Cmp(n) >= 0 || s.Sign() == 0 || s.Cmp(new(big.Int).Rsh(n, 1)) > 0 {
+		return nil, fmt.Errorf("signature values out of range")
+	}
+
+	// R = (r, y) with y of parity v; p ≡ 3 (mod 4) so y = (x³ + 7)^((p+1)/4)
+	ry := new(big.Int).Exp(secp256k1.rhs(r), new(big.Int).Rsh(new(big.Int).Add(P, big.NewInt(1)), 2), P)
+	if ry.Bit(0) != uint(v) {
+		ry.Sub(P, ry)
+	}
+	if !secp256k1.IsOnCurve(r, ry) {
+		return nil, fmt.Errorf("invalid signature: r is not an x coordinate on the curve")
+	}
+
+	// Q = r⁻¹(s·R - e·G)
+	rInv := new(big.Int).ModInverse(r, n)
+	u1 := new(big.Int).Neg(new(big.Int).SetBytes(hash))
+	u1.Mul(u1, rInv)
+	u1.Mod(u1, n)
+	u2 := new(big.Int).Mul(s, rInv)
+	u2.Mod(u2, n)
+
+	sum := secp256k1.addJacobian(
+		secp256k1.scalarMultJacobian(secp256k1.jacobian(secp256k1.params.Gx, secp256k1.params.Gy), u1),
+		secp256k1.scalarMultJacobian(secp256k1.jacobian(r, ry), u2),
+	)
+	if sum.z.Sign() == 0 {
+		return nil, fmt.Errorf("invalid signature: recovered the point at infinity")
+	}
+	qx, qy := secp256k1.affine(sum)
+	return &ecdsa.PublicKey{Curve: secp256k1, X: qx, Y: qy}, nil
+}
+
+// VerifySecp256k1 checks a signature (r || s, optionally followed by v) against a secp256k1 public key
+func VerifySecp256k1(publicKey *ecdsa.PublicKey, hash, signature []byte) bool {
+	if publicKey.Curve != secp256k1 || len(hash) != 32 || len(signature) < 64 {
+		return false
+	}
+	n := secp256k1.params.N
+	r := new(big.Int).SetBytes(signature[:32])
+	s := new(big.Int).SetBytes(signature[32:64])
+	if r.Sign() == 0 || r.Cmp(n) >= 0 || s.Sign() == 0 || s.Cmp(n) >= 0 {
+		return false
+	}
+
+	sInv := new(big.Int).ModInverse(s, n)
+	u1 := new(big.Int).Mul(new(big.Int).SetBytes(hash), sInv)
+	u1.Mod(u1, n)
+	u2 := new(big.Int).Mul(r, sInv)
+	u2.Mod(u2, n)
+
+	sum := secp256k1.addJacobian(
+		secp256k1.scalarMultJacobian(secp256k1.jacobian(secp256k1.params.Gx, secp256k1.params.Gy), u1),
+		secp256k1.scalarMultJacobian(secp256k1.jacobian(publicKey.X, publicKey.Y), u2),
+	)
+	if sum.z.Sign() == 0 {
+		return false
+	}
+	x, _ := secp256k1.affine(sum)
+	return x.Mod(x, n).Cmp(r) == 0
+}
+
+// rfc6979Nonce derives the signing nonce k from the key and hash with HMAC-SHA256 (RFC 6979, section 3.2),
+// so signing needs no randomness and the same message always gets the same signature
+func rfc6979Nonce(d *big.Int, hash []byte) *big.Int {
+	n := secp256k1.params.N
+	x := make([]byte, 32)
+	d.FillBytes(x)
+	h1 := make([]byte, 32)
+	new(big.Int).Mod(new(big.Int).SetBytes(hash), n).FillBytes(h1)
+
+	hmacSum := func(key []byte, parts ...[]byte) []byte {
+		mac := hmac.New(sha256.New, key)
+		for _, part := range parts {
+			mac.Write(part)
+		}
+		return mac.Sum(nil)
+	}
+
+	v := make([]byte, 32)
+	for i := range v {
+		v[i] = 0x01
+	}
+	k := make([]byte, 32)
+	k = hmacSum(k, v, []byte{0x00}, x, h1)
+	v = hmacSum(k, v)
+	k = hmacSum(k, v, []byte{0x01}, x, h1)
+	v = hmacSum(k, v)
+
+	for {
+		v = hmacSum(k, v)
+		nonce := new(big.Int).SetBytes(v)
+		if nonce.Sign() > 0 && nonce.Cmp(n) < 0 {
+			return nonce
+		}
+		k = hmacSum(k, v, []byte{0x00})
+		v = hmacSum(k, v)
+	}
+}
+
+// PublicKeyToEthAddress derives an Ethereum-style address: the last 20 bytes of the
+// Keccak-256 hash of the uncompressed public key (X || Y)
+func PublicKeyToEthAddress(publicKey *ecdsa.PublicKey) string {
+	return fmt.Sprintf("%x", Keccak256(publicKeyBytes(publicKey))[12:])
+}
+
+// publicKeyBytes encodes a public key as fixed-width X || Y
+func publicKeyBytes(publicKey *ecdsa.PublicKey) []byte {
+	size := (publicKey.Curve.Params().BitSize + 7) / 8
+	encoded := make([]byte, 2*size)
+	publicKey.X.FillBytes(encoded[:size])
+	publicKey.Y.FillBytes(encoded[size:])
+	return encoded
+}
+
+// privateKeyBytes encodes a private key's scalar as a fixed-width big-endian integer
+func privateKeyBytes(privateKey *ecdsa.PrivateKey) []byte {
+	encoded := make([]byte, (privateKey.Curve.Params().BitSize+7)/8)
+	privateKey.D.FillBytes(encoded)
+	return encoded
+}
@@ -0,0 +1,432 @@
+package main
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"math/big"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// SignatureScheme identifies a signature algorithm. Transactions and signed messages carry it,
+// and it is covered by what gets signed.
+type SignatureScheme byte
+
+// Built-in signature schemes
+const (
+	SchemeP256      SignatureScheme = 0 // ECDSA on P-256 (legacy); the public key travels with the signature
+	SchemeSecp256k1 SignatureScheme = 1 // Recoverable ECDSA on secp256k1; the public key is recovered
+	SchemeEd25519   SignatureScheme = 2 // Ed25519; the public key travels with the signature
+)
+
+// Signer signs message hashes with one private key of a registered scheme
+type Signer interface {
+	Scheme() SignatureScheme
+	PublicKey() []byte // Encoded public key, as accepted by the scheme's Verify
+	Address() string
+	Sign(hash []byte) ([]byte, error)
+}
+
+// keyExporter is implemented by signers whose private key can be written to a keystore
+type keyExporter interface {
+	privateKeyBytes() []byte
+}
+
+// SchemeInfo describes a registered signature scheme
+type SchemeInfo struct {
+	Name        string
+	Recoverable bool // Signatures reveal the public key, so it is not transmitted
+
+	Generate        func() (Signer, error)
+	ParsePrivateKey func(data []byte) (Signer, error)
+	Verify          func(publicKey, hash, signature []byte) bool
+	Address         func(publicKey []byte) (string, error)
+	Recover         func(hash, signature []byte) ([]byte, error) // Only for recoverable schemes
+}
+
+var (
+	signatureSchemes   = make(map[SignatureScheme]*SchemeInfo)
+	signatureSchemesMu sync.RWMutex
+)
+
+func init() {
+	mustRegister := func(id SignatureScheme, info *SchemeInfo) {
+		if err := RegisterSignatureScheme(id, info); err != nil {
+			panic(err)
+		}
+	}
+	mustRegister(SchemeP256, &SchemeInfo{
+		Name:            "p256",
+		Generate:        func() (Signer, error) { return generateECDSASigner(elliptic.P256()) },
+		ParsePrivateKey: func(data []byte) (Signer, error) { return parseECDSASigner(elliptic.P256(), data) },
+		Verify:          verifyP256,
+		Address:         func(publicKey []byte) (string, error) { return ecdsaAddress(elliptic.P256(), publicKey) },
+	})
+	mustRegister(SchemeSecp256k1, &SchemeInfo{
+		Name:            "secp256k1",
+		Recoverable:     true,
+		Generate:        func() (Signer, error) { return generateECDSASigner(secp256k1) },
+		ParsePrivateKey: func(data []byte) (Signer, error) { return parseECDSASigner(secp256k1, data) },
+		Verify:          verifySecp256k1Encoded,
+		Address:         func(publicKey []byte) (string, error) { return ecdsaAddress(secp256k1, publicKey) },
+		Recover: func(hash, signature []byte) ([]byte, error) {
+			publicKey, err := RecoverPublicKey(hash, signature)
+			if err != nil {
+				return nil, err
+			}
+			return publicKeyBytes(publicKey), nil
+		},
+	})
+	mustRegister(SchemeEd25519, &SchemeInfo{
+		Name:            "ed25519",
+		Generate:        generateEd25519Signer,
+		ParsePrivateKey: parseEd25519Signer,
+		Verify: func(publicKey, hash, signature []byte) bool {
+			return len(publicKey) == ed25519.PublicKeySize && ed25519.Verify(publicKey, hash, signature)
+		},
+		Address: ed25519Address,
+	})
+}
+
+// RegisterSignatureScheme adds a signature scheme under an unused identifier and name
+func RegisterSignatureScheme(id SignatureScheme, info *SchemeInfo) error {
+	if info.Name == "" || info.Verify == nil || info.Address == nil {
+		return fmt.Errorf("signature scheme %d needs a name, Verify and Address", id)
+	}
+	if info.Recoverable && info.Recover == nil {
+		return fmt.Errorf("recoverable signature scheme %s needs Recover", info.Name)
+	}
+	signatureSchemesMu.Lock()
+	defer signatureSchemesMu.Unlock()
+	if existing, exists := signatureSchemes[id]; exists {
+		return fmt.Errorf("signature scheme %d already registered as %s", id, existing.Name)
+	}
+	for _, existing := range signatureSchemes {
+		if existing.Name == info.Name {
+			return fmt.Errorf("signature scheme name already registered: %s", info.Name)
+		}
+	}
+	signatureSchemes[id] = info
+	return nil
+}
+
+// GetSignatureScheme returns a registered scheme
+func GetSignatureScheme(id SignatureScheme) (*SchemeInfo, error) {
+	signatureSchemesMu.RLock()
+	defer signatureSchemesMu.RUnlock()
+	info, exists := signatureSchemes[id]
+	if !exists {
+		return nil, fmt.Errorf("unknown signature scheme: %d", id)
+	}
+	return info, nil
+}
+
+// SignatureSchemeByName returns the identifier of a registered scheme
+func SignatureSchemeByName(name string) (SignatureScheme, error) {
+	signatureSchemesMu.RLock()
+	defer signatureSchemesMu.RUnlock()
+	for id, info := range signatureSchemes {
+		if info.Name == name {
+			return id, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown signature scheme: %s", name)
+}
+
+// SignatureSchemes returns the identifiers of all registered schemes in order
+func SignatureSchemes() []SignatureScheme {
+	signatureSchemesMu.RLock()
+	defer signatureSchemesMu.RUnlock()
+	ids := make([]SignatureScheme, 0, len(signatureSchemes))
+	for id := range signatureSchemes {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	return ids
+}
+
+// String returns the scheme's registered name
+func (s SignatureScheme) String() string {
+	if info, err := GetSignatureScheme(s); err == nil {
+		return info.Name
+	}
+	return fmt.Sprintf("scheme(%d)", byte(s))
+}
+
+// ECDSA signers
+
+// ecdsaSigner signs with an ECDSA key: P-256 keys sign plain (r, s), secp256k1 keys sign recoverably
+type ecdsaSigner struct {
+	key *ecdsa.PrivateKey
+}
+
+// NewECDSASigner wraps a P-256 or secp256k1 private key as a Signer
+func NewECDSASigner(key *ecdsa.PrivateKey) Signer {
+	return &ecdsaSigner{key: key}
+}
+
+func generateECDSASigner(curve elliptic.Curve) (Signer, error) {
+	if curve == secp256k1 {
+		key, err := GenerateSecp256k1Key()
+		if err != nil {
+			return nil, err
+		}
+		return &ecdsaSigner{key: key}, nil
+	}
+	key, err := ecdsa.GenerateKey(curve, rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	return &ecdsaSigner{key: key}, nil
+}
+
+func parseECDSASigner(curve elliptic.Curve, data []byte) (Signer, error) {
+	if curve != secp256k1 {
+		key, err := ecdsa.ParseRawPrivateKey(curve, data)
+		if err != nil {
+			return nil, err
+		}
+		return &ecdsaSigner{key: key}, nil
+	}
+	d := new(big.Int).SetBytes(data)
+	if len(data) != 32 || d.Sign() == 0 || d.Cmp(secp256k1.params.N) >= 0 {
+		return nil, fmt.Errorf("secp256k1 key out of range")
+	}
+	return &ecdsaSigner{key: secp256k1PrivateKey(d)}, nil
+}
+
+func (s *ecdsaSigner) Scheme() SignatureScheme {
+	if s.key.Curve == secp256k1 {
+		return SchemeSecp256k1
+	}
+	return SchemeP256
+}
+
+func (s *ecdsaSigner) PublicKey() []byte {
+	return publicKeyBytes(&s.key.PublicKey)
+}
+
+func (s *ecdsaSigner) Address() string {
+	return generateAddress(&s.key.PublicKey)
+}
+
+func (s *ecdsaSigner) Sign(hash []byte) ([]byte, error) {
+	if s.key.Curve == secp256k1 {
+		return SignRecoverable(s.key, hash)
+	}
+	r, sv, err := ecdsa.Sign(rand.Reader, s.key, hash)
+	if err != nil {
+		return nil, err
+	}
+	// r + s, each padded to 32 bytes
+	signature := make([]byte, 64)
+	r.FillBytes(signature[:32])
+	sv.FillBytes(signature[32:])
+	return signature, nil
+}
+
+func (s *ecdsaSigner) privateKeyBytes() []byte {
+	return privateKeyBytes(s.key)
+}
+
+// parseECDSAPublicKey decodes a 64-byte X || Y public key on a curve
+func parseECDSAPublicKey(curve elliptic.Curve, data []byte) (*ecdsa.PublicKey, error) {
+	if len(data) != 64 {
+		return nil, fmt.Errorf("public key must be 64 bytes, got %d", len(data))
+	}
+	x := new(big.Int).SetBytes(data[:32])
+	y := new(big.Int).SetBytes(data[32:])
+	if !curve.IsOnCurve(x, y) {
+		return nil, fmt.Errorf("public key is not on the %s curve", curve.Params().Name)
+	}
+	return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+}
+
+func ecdsaAddress(curve elliptic.Curve, data []byte) (string, error) {
+	publicKey, err := parseECDSAPublicKey(curve, data)
+	if err != nil {
+		return "", err
+	}
+	return generateAddress(publicKey), nil
+}
+
+func verifyP256(publicKey, hash, signature []byte) bool {
+	key, err := parseECDSAPublicKey(elliptic.P256(), publicKey)
+	if err != nil || len(signature) != 64 { // 32 bytes for r + 32 bytes for s
+		return false
+	}
+	r := new(big.Int).SetBytes(signature[:32])
+	s := new(big.Int).SetBytes(signature[32:])
+	return ecdsa.Verify(key, hash, r, s)
+}
+
+func verifySecp256k1Encoded(publicKey, hash, signature []byte) bool {
+	key, err := parseECDSAPublicKey(secp256k1, publicKey)
+	if err != nil || len(signature) != RecoverableSignatureLength {
+		return false
+	}
+	return VerifySecp256k1(key, hash, signature)
+}
+
+// Ed25519 signers
+
+// ed25519Signer signs with an Ed25519 key
+type ed25519Signer struct {
+	key ed25519.PrivateKey
+}
+
+func generateEd25519Signer() (Signer, error) {
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	return &ed25519Signer{key: key}, nil
+}
+
+// parseEd25519Signer restores an Ed25519 key from its 32-byte seed
+func parseEd25519Signer(data []byte) (Signer, error) {
+	if len(data) != ed25519.SeedSize {
+		return nil, fmt.Errorf("ed25519 seed must be %d bytes, got %d", ed25519.SeedSize, len(data))
+	}
+	return &ed25519Signer{key: ed25519.NewKeyFromSeed(data)}, nil
+}
+
+func (s *ed25519Signer) Scheme() SignatureScheme {
+	return SchemeEd25519
+}
+
+func (s *ed25519Signer) PublicKey() []byte {
+	return []byte(s.key.Public().(ed25519.PublicKey))
+}
+
+func (s *ed25519Signer) Address() string {
+	address, _ := ed25519Address(s.PublicKey())
+	return address
+}
+
+func (s *ed25519Signer) Sign(hash []byte) ([]byte, error) {
+	return ed25519.Sign(s.key, hash), nil
+}
+
+func (s *ed25519Signer) privateKeyBytes() []byte {
+	return s.key.Seed()
+}
+
+// ed25519Address derives an address from the last 20 bytes of the Keccak-256 hash of the public key
+func ed25519Address(publicKey []byte) (string, error) {
+	if len(publicKey) != ed25519.PublicKeySize {
+		return "", fmt.Errorf("ed25519 public key must be %d bytes, got %d", ed25519.PublicKeySize, len(publicKey))
+	}
+	return hex.EncodeToString(Keccak256(publicKey)[12:]), nil
+}
+
+// Signed messages
+
+// SchemeSignature is a signature over an arbitrary message, tagged with its scheme and,
+// for non-recoverable schemes, the signer's public key
+type SchemeSignature struct {
+	Scheme    SignatureScheme
+	PublicKey []byte
+	Signature []byte
+}
+
+// messageDigest hashes a message together with the scheme that signs it, so a signature
+// cannot be replayed as if it were made under another scheme
+func messageDigest(scheme SignatureScheme, message []byte) []byte {
+	hash := sha256.Sum256(append([]byte{byte(scheme)}, message...))
+	return hash[:]
+}
+
+// SignMessage signs a message (a consensus vote, a channel state, ...) with any registered scheme
+func SignMessage(signer Signer, message []byte) (*SchemeSignature, error) {
+	info, err := GetSignatureScheme(signer.Scheme())
+	if err != nil {
+		return nil, err
+	}
+	signature, err := signer.Sign(messageDigest(signer.Scheme(), message))
+	if err != nil {
+		return nil, err
+	}
+	signed := &SchemeSignature{Scheme: signer.Scheme(), Signature: signature}
+	if !info.Recoverable {
+		signed.PublicKey = signer.PublicKey()
+	}
+	return signed, nil
+}
+
+// Verify checks the signature over a message and returns the signer's address
+func (s *SchemeSignature) Verify(message []byte) (string, error) {
+	info, err := GetSignatureScheme(s.Scheme)
+	if err != nil {
+		return "", err
+	}
+	digest := messageDigest(s.Scheme, message)
+	publicKey := s.PublicKey
+	if info.Recoverable {
+		if publicKey, err = info.Recover(digest, s.Signature); err != nil {
+			return "", err
+		}
+	}
+	if !info.Verify(publicKey, digest, s.Signature) {
+		return "", fmt.Errorf("invalid %s signature", info.Name)
+	}
+	return info.Address(publicKey)
+}
+
+// Encode returns the signature as "<scheme>:<hex>", where the hex holds the public key length,
+// the public key and the signature
+func (s *SchemeSignature) Encode() string {
+	data := append([]byte{byte(len(s.PublicKey))}, s.PublicKey...)
+	data = append(data, s.Signature...)
+	return s.Scheme.String() + ":" + hex.EncodeToString(data)
+}
+
+// DecodeSchemeSignature parses a signature written by Encode
+func DecodeSchemeSignature(encoded string) (*SchemeSignature, error) {
+	name, payload, found := strings.Cut(encoded, ":")
+	if !found {
+		return nil, fmt.Errorf("not a scheme signature")
+	}
+	scheme, err := SignatureSchemeByName(name)
+	if err != nil {
+		return nil, err
+	}
+	data, err := hex.DecodeString(payload)
+	if err != nil || len(data) < 1 || len(data) < 1+int(data[0]) {
+		return nil, fmt.Errorf("invalid %s signature encoding", name)
+	}
+	keyLength := int(data[0])
+	return &SchemeSignature{
+		Scheme:    scheme,
+		PublicKey: data[1 : 1+keyLength],
+		Signature: data[1+keyLength:],
+	}, nil
+}
+
+// IsSchemeSignature reports whether a signature string was written by SchemeSignature.Encode
+// (older components store plain hashes instead)
+func IsSchemeSignature(encoded string) bool {
+	return strings.Contains(encoded, ":")
+}
+
+// VerifyEncodedSignature checks an encoded scheme signature over a message and that it was made by address
+func VerifyEncodedSignature(encoded string, message []byte, address string) error {
+	signature, err := DecodeSchemeSignature(encoded)
+	if err != nil {
+		return err
+	}
+	signer, err := signature.Verify(message)
+	if err != nil {
+		return err
+	}
+	if signer != address {
+		return fmt.Errorf("signature is from %s, not %s", signer, address)
+	}
+	return nil
+}
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+)
+
+// MaxStateHistory is the number of recent blocks whose contract state is kept for historical calls
+const MaxStateHistory = 256
+
+// SimulationResult is the outcome of a read-only contract call; none of its effects are applied
+type SimulationResult struct {
+	Result    interface{}         `json:"result"`
+	Logs      []*Log              `json:"logs"`
+	Transfers []*InternalTransfer `json:"transfers,omitempty"`
+	GasUsed   uint64              `json:"gasUsed"`
+}
+
+// Snapshot returns a deep copy of the registry whose contracts can be modified independently
+func (cr *ContractRegistry) Snapshot() *ContractRegistry {
+	cr.mu.RLock()
+	defer cr.mu.RUnlock()
+	snapshot := NewContractRegistry()
+	for address, contract := range cr.Contracts {
+		snapshot.Contracts[address] = contract.clone()
+	}
+	return snapshot
+}
+
+// clone copies a contract with its own state
+func (sc *SmartContract) clone() *SmartContract {
+	sc.mu.RLock()
+	defer sc.mu.RUnlock()
+	return &SmartContract{
+		Address:        sc.Address,
+		Deployer:       sc.Deployer,
+		Type:           sc.Type,
+		Bytecode:       sc.Bytecode,
+		State:          cloneState(sc.State),
+		CreatedAt:      sc.CreatedAt,
+		NonReentrant:   sc.NonReentrant,
+		StorageDeposit: sc.StorageDeposit,
+		Archived:       sc.Archived,
+		module:         sc.module,
+	}
+}
+
+// recordStateHistory stores the contract state as of a block, pruning entries beyond MaxStateHistory
+func (bc *Blockchain) recordStateHistory(blockNumber int) {
+	bc.StateHistory[blockNumber] = bc.ContractRegistry.Snapshot()
+	delete(bc.StateHistory, blockNumber-MaxStateHistory)
+}
+
+// registryAt returns a disposable copy of the contract registry as of a block (-1 for latest)
+func (bc *Blockchain) registryAt(blockNumber int) (*ContractRegistry, error) {
+	registry, err := bc.stateAt(blockNumber)
+	if err != nil {
+		return nil, err
+	}
+	return registry.Snapshot(), nil
+}
+
+// stateAt returns the contract registry as of a block (-1 for latest) without copying it; callers must only read it
+func (bc *Blockchain) stateAt(blockNumber int) (*ContractRegistry, error) {
+	latest := len(bc.Blocks) - 1
+	if blockNumber < 0 || blockNumber >= latest {
+		return bc.ContractRegistry, nil
+	}
+
+	if latest-blockNumber >= MaxStateHistory {
+		return nil, fmt.Errorf("state for block %d is no longer available", blockNumber)
+	}
+
+	// Use the most recent recorded state at or before the block
+	recorded := make([]int, 0, len(bc.StateHistory))
+	for number := range bc.StateHistory {
+		recorded = append(recorded, number)
+	}
+	sort.Ints(recorded)
+	for i := len(recorded) - 1; i >= 0; i-- {
+		if recorded[i] <= blockNumber {
+			return bc.StateHistory[recorded[i]], nil
+		}
+	}
+	return NewContractRegistry(), nil
+}
+
+// SimulateCall executes a contract call against a copy of contract state at a block (-1 for latest)
+// and discards every state change, log and transfer it makes.
+func (bc *Blockchain) SimulateCall(call *ContractCall, caller string, blockNumber int) (*SimulationResult, error) {
+	if blockNumber >= len(bc.Blocks) {
+		return nil, fmt.Errorf("block %d not found", blockNumber)
+	}
+	registry, err := bc.registryAt(blockNumber)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx := newCallContext(bc, registry, call.ContractAddress, caller, call.Args, call.Value)
+	ctx.Values = call.Values
+	if blockNumber >= 0 {
+		ctx.setBlock(blockNumber)
+	}
+	if call.Value > 0 {
+		if err := ctx.transfer(caller, call.ContractAddress, call.Value); err != nil {
+			return nil, err
+		}
+	}
+
+	result, err := registry.CallContractWithContext(call.ContractAddress, call.Function, ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &SimulationResult{
+		Result:    result,
+		Logs:      ctx.Logs,
+		Transfers: ctx.Transfers,
+		GasUsed:   ctx.GasUsed,
+	}, nil
+}
+
+// ViewContract calls a contract function against the latest state without modifying it
+func (bc *Blockchain) ViewContract(contractAddress, function string, args []string, caller string) (interface{}, error) {
+	simulation, err := bc.SimulateCall(&ContractCall{
+		ContractAddress: contractAddress,
+		Function:        function,
+		Args:            args,
+	}, caller, -1)
+	if err != nil {
+		return nil, err
+	}
+	return simulation.Result, nil
+}
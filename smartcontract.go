@@ -5,6 +5,7 @@ import (
 	"encoding/hex"
 	"encoding/json"
 	"fmt"
+	"math"
 	"strconv"
 	"strings"
 	"sync"
@@ -14,17 +15,59 @@ import (
 type ContractType string
 
 const (
-	ContractTypeSimple ContractType = "simple"
-	ContractTypeToken  ContractType = "token"
-	ContractTypeEscrow ContractType = "escrow"
-	ContractTypeVoting ContractType = "voting"
+	ContractTypeSimple     ContractType = "simple"
+	ContractTypeToken      ContractType = "token"
+	ContractTypeEscrow     ContractType = "escrow"
+	ContractTypeVoting     ContractType = "voting"
+	ContractTypeNFT        ContractType = "nft"
+	ContractTypeMultisig   ContractType = "multisig"
+	ContractTypeAMM        ContractType = "amm"
+	ContractTypeVesting    ContractType = "vesting"
+	ContractTypeGovernance ContractType = "governance"
+	ContractTypeProxy      ContractType = "proxy"
+	ContractTypeWASM       ContractType = "wasm"
+)
+
+// ContractDeployFunction is the call-data function name of a deployment transaction
+// ("deploy:<type>,<bytecode>,<constructor args...>" sent with an empty To address)
+const ContractDeployFunction = "deploy"
+
+// ContractConstructor is the function run once with the constructor arguments when a contract is deployed
+const ContractConstructor = "init"
+
+// Event signatures emitted by the built-in contract types
+const (
+	EventTransfer    = "Transfer(address,address,uint256)"
+	EventDeposit     = "Deposit(address,uint256)"
+	EventRelease     = "Release(address,uint256)"
+	EventRefund      = "Refund(address,uint256)"
+	EventProposal    = "ProposalCreated(address,string)"
+	EventVote        = "VoteCast(address,string)"
+	EventVotingEnded = "VotingEnded(address)"
 )
 
 // ContractContext holds execution context for contract calls
 type ContractContext struct {
-	Caller string
-	Value  float64
-	Args   []string
+	Caller    string
+	Value     float64
+	Args      []string
+	Values    []interface{}       // Typed arguments bound against the contract ABI (nil if untyped)
+	Logs      []*Log              // Logs emitted during execution
+	Address   string              // Address of the executing contract
+	Origin    string              // Account that started the call chain
+	Depth     int                 // Nested call depth (0 for the top-level call)
+	Transfers []*InternalTransfer // Native transfers made during execution
+	GasLimit  uint64              // Gas available to WASM execution (0 uses DefaultWasmGasLimit)
+	GasUsed   uint64              // Gas consumed by WASM execution, including nested calls
+	// BlockNumber is the block whose state the call executes against (-1 if unknown)
+	BlockNumber int
+	Timestamp   int64 // Unix time of that block (0 if unknown)
+
+	chain     *Blockchain       // Used for native balances and transfers (nil if unavailable)
+	registry  *ContractRegistry // Used to resolve nested contract calls (nil if unavailable)
+	parent    *ContractContext  // Calling frame for nested calls
+	snapshots []contractSnapshot
+	tracer    *Tracer // Records execution steps (nil unless tracing)
 }
 
 // SmartContract represents a smart contract deployed on the blockchain
@@ -32,24 +75,32 @@ type SmartContract struct {
 	Address   string                 // Contract address (derived from deployer and nonce)
 	Deployer  string                 // Address of the contract deployer
 	Type      ContractType           // Type of contract
-	Bytecode  string                 // Contract bytecode (simplified as string instructions)
+	Bytecode  string                 // Contract bytecode (string instructions, or hex-encoded module for WASM)
 	State     map[string]interface{} // Contract state storage
 	CreatedAt int64                  // Block index when contract was created
-	mu        sync.RWMutex           // Mutex for thread-safe state access
+	// NonReentrant rejects calls into the contract while it is already executing
+	NonReentrant bool
+	// StorageDeposit is the part of the contract's balance paying for its state (see storage.go)
+	StorageDeposit float64
+	Archived       bool         // Archived for unpaid storage rent: state is kept but calls are rejected
+	mu             sync.RWMutex // Mutex for thread-safe state access
+	module         *WasmModule  // Decoded WASM module (cached on first call)
+	tracer         *Tracer      // Records state reads while a traced call executes the contract
 }
 
 // ContractCall represents a call to a smart contract function
 type ContractCall struct {
-	ContractAddress string   // Address of the contract being called
-	Function        string   // Function name to call
-	Args            []string // Function arguments
-	Value           float64  // Value sent with the call (for payable functions)
+	ContractAddress string        // Address of the contract being called
+	Function        string        // Function name to call
+	Args            []string      // Function arguments
+	Values          []interface{} // Typed arguments from JSON-encoded call data (nil for the legacy format)
+	Value           float64       // Value sent with the call (for payable functions)
 }
 
-// NewSmartContract creates a new smart contract instance
-func NewSmartContract(deployer string, contractType ContractType, bytecode string, blockIndex int64) *SmartContract {
-	// Generate contract address from deployer address and block index
-	addressData := fmt.Sprintf("%s%d", deployer, blockIndex)
+// NewSmartContract creates a new smart contract instance; nonce counts the deployer's earlier contracts
+func NewSmartContract(deployer string, contractType ContractType, bytecode string, blockIndex int64, nonce uint64) *SmartContract {
+	// Generate contract address from deployer address and nonce, so a deployer can create several per block
+	addressData := fmt.Sprintf("%s:%d", deployer, nonce)
 	hash := sha256.Sum256([]byte(addressData))
 	address := "0x" + hex.EncodeToString(hash[:])[:40]
 
@@ -60,18 +111,57 @@ func NewSmartContract(deployer string, contractType ContractType, bytecode strin
 		Bytecode:  bytecode,
 		State:     make(map[string]interface{}),
 		CreatedAt: blockIndex,
+		// Contracts that pay out native coins are guarded against reentrancy by default
+		NonReentrant: contractType == ContractTypeEscrow || contractType == ContractTypeMultisig ||
+			contractType == ContractTypeAMM || contractType == ContractTypeVesting ||
+			contractType == ContractTypeGovernance || contractType == ContractTypeProxy,
 	}
 }
 
 // Execute executes a contract call and returns the result
 func (sc *SmartContract) Execute(function string, args []string, caller string, value float64) (interface{}, error) {
 	ctx := &ContractContext{
-		Caller: caller,
-		Value:  value,
-		Args:   args,
+		Caller:      caller,
+		Value:       value,
+		Args:        args,
+		Address:     sc.Address,
+		Origin:      caller,
+		BlockNumber: -1,
+	}
+	return sc.ExecuteWithContext(function, ctx)
+}
+
+// ExecuteWithContext executes a contract call with a caller-supplied context, collecting emitted logs in ctx.Logs.
+// If the call fails, every state change made in this frame (including nested calls) is reverted.
+func (sc *SmartContract) ExecuteWithContext(function string, ctx *ContractContext) (interface{}, error) {
+	if sc.NonReentrant && ctx.isActive(sc.Address) {
+		return nil, fmt.Errorf("reentrant call to %s", truncateAddress(sc.Address))
 	}
 
-	switch sc.Type {
+	if err := sc.bindArgs(function, ctx); err != nil {
+		return nil, err
+	}
+
+	ctx.snapshot(sc)
+	ctx.tracer.enter(sc, function, ctx)
+	result, err := sc.dispatch(function, ctx)
+	ctx.tracer.exit(ctx, result, err)
+	if err != nil {
+		ctx.revert()
+		return nil, err
+	}
+	return result, nil
+}
+
+// dispatch routes a call to the handler for the contract's type
+func (sc *SmartContract) dispatch(function string, ctx *ContractContext) (interface{}, error) {
+	return sc.dispatchAs(sc, function, ctx)
+}
+
+// dispatchAs runs the logic of a contract (its type, or its WASM module) against sc's state.
+// Proxies pass their implementation so that its logic operates on the proxy's storage.
+func (sc *SmartContract) dispatchAs(logic *SmartContract, function string, ctx *ContractContext) (interface{}, error) {
+	switch logic.Type {
 	case ContractTypeSimple:
 		return sc.executeSimple(function, ctx)
 	case ContractTypeToken:
@@ -80,8 +170,26 @@ func (sc *SmartContract) Execute(function string, args []string, caller string,
 		return sc.executeEscrow(function, ctx)
 	case ContractTypeVoting:
 		return sc.executeVoting(function, ctx)
+	case ContractTypeNFT:
+		return sc.executeNFT(function, ctx)
+	case ContractTypeMultisig:
+		return sc.executeMultisig(function, ctx)
+	case ContractTypeAMM:
+		return sc.executeAMM(function, ctx)
+	case ContractTypeVesting:
+		return sc.executeVesting(function, ctx)
+	case ContractTypeGovernance:
+		return sc.executeGovernance(function, ctx)
+	case ContractTypeProxy:
+		return sc.executeProxy(function, ctx)
+	case ContractTypeWASM:
+		module, err := logic.wasmModule()
+		if err != nil {
+			return nil, err
+		}
+		return sc.executeWasm(module, function, ctx)
 	default:
-		return nil, fmt.Errorf("unknown contract type: %s", sc.Type)
+		return nil, fmt.Errorf("unknown contract type: %s", logic.Type)
 	}
 }
 
@@ -91,6 +199,7 @@ func (sc *SmartContract) getStateString(key string) (string, bool) {
 	sc.mu.RLock()
 	defer sc.mu.RUnlock()
 	val, exists := sc.State[key]
+	sc.traceRead(key, val)
 	if !exists {
 		return "", false
 	}
@@ -102,6 +211,7 @@ func (sc *SmartContract) getStateFloat(key string) (float64, bool) {
 	sc.mu.RLock()
 	defer sc.mu.RUnlock()
 	val, exists := sc.State[key]
+	sc.traceRead(key, val)
 	if !exists {
 		return 0, false
 	}
@@ -113,6 +223,7 @@ func (sc *SmartContract) getStateBool(key string) (bool, bool) {
 	sc.mu.RLock()
 	defer sc.mu.RUnlock()
 	val, exists := sc.State[key]
+	sc.traceRead(key, val)
 	if !exists {
 		return false, false
 	}
@@ -126,6 +237,18 @@ func (sc *SmartContract) setState(key string, value interface{}) {
 	sc.State[key] = value
 }
 
+// reservedStatePrefix starts the state keys a contract keeps for its own bookkeeping, such as a
+// proxy's admin data; functions that write keys chosen by the caller reject them
+const reservedStatePrefix = "__"
+
+// checkStateKey rejects reserved keys in caller-chosen state writes
+func checkStateKey(key string) error {
+	if strings.HasPrefix(key, reservedStatePrefix) {
+		return fmt.Errorf("state key '%s' is reserved", key)
+	}
+	return nil
+}
+
 func (sc *SmartContract) getBalances() map[string]float64 {
 	sc.mu.Lock()
 	defer sc.mu.Unlock()
@@ -135,6 +258,24 @@ func (sc *SmartContract) getBalances() map[string]float64 {
 	return sc.State["balances"].(map[string]float64)
 }
 
+func (sc *SmartContract) getFloatMap(key string) map[string]float64 {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+	if _, exists := sc.State[key]; !exists {
+		sc.State[key] = make(map[string]float64)
+	}
+	return sc.State[key].(map[string]float64)
+}
+
+func (sc *SmartContract) getStringMap(key string) map[string]string {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+	if _, exists := sc.State[key]; !exists {
+		sc.State[key] = make(map[string]string)
+	}
+	return sc.State[key].(map[string]string)
+}
+
 func (sc *SmartContract) getProposals() map[string]int {
 	sc.mu.Lock()
 	defer sc.mu.Unlock()
@@ -153,8 +294,25 @@ func (sc *SmartContract) getVoters() map[string]bool {
 	return sc.State["voters"].(map[string]bool)
 }
 
+// emitLog records an event log on the execution context
+func (sc *SmartContract) emitLog(ctx *ContractContext, signature string, indexed []string, data string) {
+	topics := append([]string{EventTopic(signature)}, indexed...)
+	log := &Log{
+		Address: sc.Address,
+		Topics:  topics,
+		Data:    data,
+	}
+	ctx.Logs = append(ctx.Logs, log)
+	ctx.tracer.log(ctx, log)
+}
+
 // Validation helpers
 
+// allowanceKey is the state key of the amount owner has allowed spender to transfer
+func allowanceKey(owner, spender string) string {
+	return owner + ":" + spender
+}
+
 func validateArgsCount(args []string, required int, funcName string) error {
 	if len(args) < required {
 		return fmt.Errorf("%s requires %d argument(s)", funcName, required)
@@ -167,12 +325,19 @@ func parseAmount(amountStr string) (float64, error) {
 	if err != nil {
 		return 0, fmt.Errorf("invalid amount: %s", amountStr)
 	}
+	if math.IsNaN(amount) || math.IsInf(amount, 0) {
+		return 0, fmt.Errorf("invalid amount: %s", amountStr)
+	}
 	if amount < 0 {
 		return 0, fmt.Errorf("amount cannot be negative: %.2f", amount)
 	}
 	return amount, nil
 }
 
+func formatAmount(amount float64) string {
+	return strconv.FormatFloat(amount, 'f', -1, 64)
+}
+
 func truncateAddress(addr string) string {
 	if len(addr) > 16 {
 		return addr[:16] + "..."
@@ -187,7 +352,10 @@ func (sc *SmartContract) executeSimple(function string, ctx *ContractContext) (i
 		if err := validateArgsCount(ctx.Args, 2, "set"); err != nil {
 			return nil, err
 		}
-		key, val := ctx.Args[0], ctx.Args[1]
+		key, val := ctx.StringArg(0), ctx.StringArg(1)
+		if err := checkStateKey(key); err != nil {
+			return nil, err
+		}
 		sc.setState(key, val)
 		return fmt.Sprintf("Set %s = %s", key, val), nil
 
@@ -195,7 +363,7 @@ func (sc *SmartContract) executeSimple(function string, ctx *ContractContext) (i
 		if err := validateArgsCount(ctx.Args, 1, "get"); err != nil {
 			return nil, err
 		}
-		key := ctx.Args[0]
+		key := ctx.StringArg(0)
 		sc.mu.RLock()
 		val, exists := sc.State[key]
 		sc.mu.RUnlock()
@@ -208,7 +376,10 @@ func (sc *SmartContract) executeSimple(function string, ctx *ContractContext) (i
 		if err := validateArgsCount(ctx.Args, 1, "delete"); err != nil {
 			return nil, err
 		}
-		key := ctx.Args[0]
+		key := ctx.StringArg(0)
+		if err := checkStateKey(key); err != nil {
+			return nil, err
+		}
 		sc.mu.Lock()
 		_, exists := sc.State[key]
 		if exists {
@@ -224,7 +395,7 @@ func (sc *SmartContract) executeSimple(function string, ctx *ContractContext) (i
 		if err := validateArgsCount(ctx.Args, 1, "exists"); err != nil {
 			return nil, err
 		}
-		key := ctx.Args[0]
+		key := ctx.StringArg(0)
 		sc.mu.RLock()
 		_, exists := sc.State[key]
 		sc.mu.RUnlock()
@@ -249,8 +420,8 @@ func (sc *SmartContract) executeToken(function string, ctx *ContractContext) (in
 		if err := validateArgsCount(ctx.Args, 2, "transfer"); err != nil {
 			return nil, err
 		}
-		to := ctx.Args[0]
-		amount, err := parseAmount(ctx.Args[1])
+		to := ctx.StringArg(0)
+		amount, err := ctx.AmountArg(1)
 		if err != nil {
 			return nil, err
 		}
@@ -268,6 +439,7 @@ func (sc *SmartContract) executeToken(function string, ctx *ContractContext) (in
 		balances[to] += amount
 		sc.mu.Unlock()
 
+		sc.emitLog(ctx, EventTransfer, []string{ctx.Caller, to}, formatAmount(amount))
 		return fmt.Sprintf("Transferred %.2f tokens from %s to %s",
 			amount, truncateAddress(ctx.Caller), truncateAddress(to)), nil
 
@@ -275,7 +447,7 @@ func (sc *SmartContract) executeToken(function string, ctx *ContractContext) (in
 		if err := validateArgsCount(ctx.Args, 1, "balanceOf"); err != nil {
 			return nil, err
 		}
-		address := ctx.Args[0]
+		address := ctx.StringArg(0)
 		sc.mu.RLock()
 		balance := balances[address]
 		sc.mu.RUnlock()
@@ -285,6 +457,67 @@ func (sc *SmartContract) executeToken(function string, ctx *ContractContext) (in
 		supply, _ := sc.getStateFloat("totalSupply")
 		return supply, nil
 
+	case "approve":
+		if err := validateArgsCount(ctx.Args, 2, "approve"); err != nil {
+			return nil, err
+		}
+		spender := ctx.StringArg(0)
+		amount, err := ctx.AmountArg(1)
+		if err != nil {
+			return nil, err
+		}
+		allowances := sc.getFloatMap("allowances")
+		sc.mu.Lock()
+		allowances[allowanceKey(ctx.Caller, spender)] = amount
+		sc.mu.Unlock()
+
+		sc.emitLog(ctx, EventApproval, []string{ctx.Caller, spender}, formatAmount(amount))
+		return fmt.Sprintf("Approved %s to spend %.2f tokens", truncateAddress(spender), amount), nil
+
+	case "allowance":
+		if err := validateArgsCount(ctx.Args, 2, "allowance"); err != nil {
+			return nil, err
+		}
+		allowances := sc.getFloatMap("allowances")
+		sc.mu.RLock()
+		defer sc.mu.RUnlock()
+		return allowances[allowanceKey(ctx.StringArg(0), ctx.StringArg(1))], nil
+
+	case "transferFrom":
+		if err := validateArgsCount(ctx.Args, 3, "transferFrom"); err != nil {
+			return nil, err
+		}
+		from, to := ctx.StringArg(0), ctx.StringArg(1)
+		amount, err := ctx.AmountArg(2)
+		if err != nil {
+			return nil, err
+		}
+		if amount == 0 {
+			return nil, fmt.Errorf("transfer amount must be greater than zero")
+		}
+		allowances := sc.getFloatMap("allowances")
+		key := allowanceKey(from, ctx.Caller)
+
+		sc.mu.Lock()
+		if allowances[key] < amount {
+			allowed := allowances[key]
+			sc.mu.Unlock()
+			return nil, fmt.Errorf("insufficient allowance: %.2f < %.2f", allowed, amount)
+		}
+		if balances[from] < amount {
+			balance := balances[from]
+			sc.mu.Unlock()
+			return nil, fmt.Errorf("insufficient balance: %.2f < %.2f", balance, amount)
+		}
+		allowances[key] -= amount
+		balances[from] -= amount
+		balances[to] += amount
+		sc.mu.Unlock()
+
+		sc.emitLog(ctx, EventTransfer, []string{from, to}, formatAmount(amount))
+		return fmt.Sprintf("Transferred %.2f tokens from %s to %s",
+			amount, truncateAddress(from), truncateAddress(to)), nil
+
 	case "mint":
 		if ctx.Caller != sc.Deployer {
 			return nil, fmt.Errorf("only deployer can mint tokens")
@@ -292,8 +525,8 @@ func (sc *SmartContract) executeToken(function string, ctx *ContractContext) (in
 		if err := validateArgsCount(ctx.Args, 2, "mint"); err != nil {
 			return nil, err
 		}
-		to := ctx.Args[0]
-		amount, err := parseAmount(ctx.Args[1])
+		to := ctx.StringArg(0)
+		amount, err := ctx.AmountArg(1)
 		if err != nil {
 			return nil, err
 		}
@@ -305,6 +538,7 @@ func (sc *SmartContract) executeToken(function string, ctx *ContractContext) (in
 		balances[to] += amount
 		sc.mu.Unlock()
 
+		sc.emitLog(ctx, EventTransfer, []string{"", to}, formatAmount(amount))
 		return fmt.Sprintf("Minted %.2f tokens to %s (Total supply: %.2f)",
 			amount, truncateAddress(to), totalSupply), nil
 
@@ -312,7 +546,7 @@ func (sc *SmartContract) executeToken(function string, ctx *ContractContext) (in
 		if err := validateArgsCount(ctx.Args, 1, "burn"); err != nil {
 			return nil, err
 		}
-		amount, err := parseAmount(ctx.Args[0])
+		amount, err := ctx.AmountArg(0)
 		if err != nil {
 			return nil, err
 		}
@@ -329,6 +563,7 @@ func (sc *SmartContract) executeToken(function string, ctx *ContractContext) (in
 		sc.State["totalSupply"] = totalSupply
 		sc.mu.Unlock()
 
+		sc.emitLog(ctx, EventTransfer, []string{ctx.Caller, ""}, formatAmount(amount))
 		return fmt.Sprintf("Burned %.2f tokens from %s (Total supply: %.2f)",
 			amount, truncateAddress(ctx.Caller), totalSupply), nil
 
@@ -349,7 +584,7 @@ func (sc *SmartContract) executeEscrow(function string, ctx *ContractContext) (i
 	}
 	if _, exists := sc.State["beneficiary"]; !exists {
 		if len(ctx.Args) > 0 {
-			sc.State["beneficiary"] = ctx.Args[0]
+			sc.State["beneficiary"] = ctx.StringArg(0)
 		} else {
 			sc.State["beneficiary"] = ctx.Caller
 		}
@@ -374,6 +609,11 @@ func (sc *SmartContract) executeEscrow(function string, ctx *ContractContext) (i
 		}
 		newTotal := deposited + ctx.Value
 		sc.setState("deposited", newTotal)
+		deposits := sc.getFloatMap("deposits")
+		sc.mu.Lock()
+		deposits[ctx.Caller] += ctx.Value
+		sc.mu.Unlock()
+		sc.emitLog(ctx, EventDeposit, []string{ctx.Caller}, formatAmount(ctx.Value))
 		return fmt.Sprintf("Deposited %.2f coins to escrow. Total: %.2f", ctx.Value, newTotal), nil
 
 	case "release":
@@ -386,7 +626,11 @@ func (sc *SmartContract) executeEscrow(function string, ctx *ContractContext) (i
 		if deposited == 0 {
 			return nil, fmt.Errorf("no funds in escrow")
 		}
+		if err := ctx.Transfer(beneficiary, deposited); err != nil {
+			return nil, err
+		}
 		sc.setState("released", true)
+		sc.emitLog(ctx, EventRelease, []string{beneficiary}, formatAmount(deposited))
 		return fmt.Sprintf("Released %.2f coins to beneficiary %s",
 			deposited, truncateAddress(beneficiary)), nil
 
@@ -400,8 +644,21 @@ func (sc *SmartContract) executeEscrow(function string, ctx *ContractContext) (i
 		if deposited == 0 {
 			return nil, fmt.Errorf("no funds in escrow")
 		}
+		deposits := sc.getFloatMap("deposits")
+		sc.mu.RLock()
+		depositors := make(map[string]float64, len(deposits))
+		for depositor, amount := range deposits {
+			depositors[depositor] = amount
+		}
+		sc.mu.RUnlock()
+		for depositor, amount := range depositors {
+			if err := ctx.Transfer(depositor, amount); err != nil {
+				return nil, err
+			}
+		}
 		sc.setState("released", true)
 		sc.setState("refunded", true)
+		sc.emitLog(ctx, EventRefund, []string{ctx.Caller}, formatAmount(deposited))
 		return fmt.Sprintf("Refunded %.2f coins", deposited), nil
 
 	case "getBalance":
@@ -442,7 +699,7 @@ func (sc *SmartContract) executeVoting(function string, ctx *ContractContext) (i
 		if err := validateArgsCount(ctx.Args, 1, "propose"); err != nil {
 			return nil, err
 		}
-		proposal := ctx.Args[0]
+		proposal := ctx.StringArg(0)
 		sc.mu.Lock()
 		if _, exists := proposals[proposal]; exists {
 			sc.mu.Unlock()
@@ -450,6 +707,7 @@ func (sc *SmartContract) executeVoting(function string, ctx *ContractContext) (i
 		}
 		proposals[proposal] = 0
 		sc.mu.Unlock()
+		sc.emitLog(ctx, EventProposal, []string{ctx.Caller}, proposal)
 		return fmt.Sprintf("Proposal '%s' added", proposal), nil
 
 	case "vote":
@@ -459,7 +717,7 @@ func (sc *SmartContract) executeVoting(function string, ctx *ContractContext) (i
 		if err := validateArgsCount(ctx.Args, 1, "vote"); err != nil {
 			return nil, err
 		}
-		proposal := ctx.Args[0]
+		proposal := ctx.StringArg(0)
 
 		sc.mu.Lock()
 		if voters[ctx.Caller] {
@@ -474,6 +732,7 @@ func (sc *SmartContract) executeVoting(function string, ctx *ContractContext) (i
 		voters[ctx.Caller] = true
 		sc.mu.Unlock()
 
+		sc.emitLog(ctx, EventVote, []string{ctx.Caller}, proposal)
 		return fmt.Sprintf("Voted for '%s'", proposal), nil
 
 	case "getResults":
@@ -512,6 +771,7 @@ func (sc *SmartContract) executeVoting(function string, ctx *ContractContext) (i
 			return nil, fmt.Errorf("voting already ended")
 		}
 		sc.setState("votingEnded", true)
+		sc.emitLog(ctx, EventVotingEnded, []string{ctx.Caller}, "")
 		return "Voting ended", nil
 
 	default:
@@ -519,6 +779,24 @@ func (sc *SmartContract) executeVoting(function string, ctx *ContractContext) (i
 	}
 }
 
+// hasConstructor reports whether the contract defines a constructor to run at deployment
+func (sc *SmartContract) hasConstructor() bool {
+	if sc.Type == ContractTypeWASM {
+		module, err := sc.wasmModule()
+		if err != nil {
+			return false
+		}
+		_, exists := module.Exports[ContractConstructor]
+		return exists
+	}
+	abi, err := GetContractTypeABI(sc.Type)
+	if err != nil {
+		return false
+	}
+	_, err = abi.Function(ContractConstructor)
+	return err == nil
+}
+
 // GetAddress returns the contract address
 func (sc *SmartContract) GetAddress() string {
 	return sc.Address
@@ -526,9 +804,13 @@ func (sc *SmartContract) GetAddress() string {
 
 // GetState returns the contract state as JSON
 func (sc *SmartContract) GetState() (string, error) {
+	usage := sc.StorageUsage()
 	sc.mu.RLock()
 	defer sc.mu.RUnlock()
-	stateJSON, err := json.MarshalIndent(sc.State, "", "  ")
+	stateJSON, err := json.MarshalIndent(map[string]interface{}{
+		"state":   sc.State,
+		"storage": usage,
+	}, "", "  ")
 	if err != nil {
 		return "", err
 	}
@@ -549,6 +831,9 @@ func (sc *SmartContract) GetType() ContractType {
 type ContractRegistry struct {
 	Contracts map[string]*SmartContract // Map of contract address to contract
 	mu        sync.RWMutex              // Mutex for thread-safe access
+	// onChain marks the registry of a Blockchain, whose contract state only changes in blocks
+	// (where storage deposits and rent are settled)
+	onChain bool
 }
 
 // NewContractRegistry creates a new contract registry
@@ -563,31 +848,77 @@ func (cr *ContractRegistry) DeployContract(deployer string, contractType Contrac
 	if deployer == "" {
 		return nil, fmt.Errorf("deployer address cannot be empty")
 	}
-	contract := NewSmartContract(deployer, contractType, bytecode, blockIndex)
+	if contractType == ContractTypeWASM {
+		if err := validateWasmBytecode(bytecode); err != nil {
+			return nil, err
+		}
+	}
 	cr.mu.Lock()
+	defer cr.mu.Unlock()
+	// The deployer's nonce is the number of contracts it already has, archived ones included
+	nonce := uint64(0)
+	for _, existing := range cr.Contracts {
+		if existing.Deployer == deployer {
+			nonce++
+		}
+	}
+	contract := NewSmartContract(deployer, contractType, bytecode, blockIndex, nonce)
+	if _, exists := cr.Contracts[contract.Address]; exists {
+		return nil, fmt.Errorf("contract already exists at %s", contract.Address)
+	}
 	cr.Contracts[contract.Address] = contract
-	cr.mu.Unlock()
 	return contract, nil
 }
 
-// GetContract retrieves a contract by address
+// removeContract deletes a contract from the registry (used when deployment fails)
+func (cr *ContractRegistry) removeContract(address string) {
+	cr.mu.Lock()
+	defer cr.mu.Unlock()
+	delete(cr.Contracts, address)
+}
+
+// GetContract retrieves a callable (not archived) contract by address
 func (cr *ContractRegistry) GetContract(address string) (*SmartContract, error) {
+	return cr.getContract(address, false)
+}
+
+// getContract retrieves a contract by address, optionally including archived ones
+func (cr *ContractRegistry) getContract(address string, includeArchived bool) (*SmartContract, error) {
 	cr.mu.RLock()
 	defer cr.mu.RUnlock()
 	contract, exists := cr.Contracts[address]
 	if !exists {
 		return nil, fmt.Errorf("contract not found: %s", address)
 	}
+	contract.mu.RLock()
+	defer contract.mu.RUnlock()
+	if contract.Archived && !includeArchived {
+		return nil, fmt.Errorf("contract %s is archived: storage rent unpaid", truncateAddress(address))
+	}
 	return contract, nil
 }
 
-// CallContract calls a function on a smart contract
+// CallContract calls a function on a smart contract in a standalone registry, which has no native
+// balances. Contracts of a blockchain are called with transactions instead.
 func (cr *ContractRegistry) CallContract(contractAddress, function string, args []string, caller string, value float64) (interface{}, error) {
+	if cr.onChain {
+		return nil, fmt.Errorf("contracts on a blockchain are called with transactions; use ViewContract to read them")
+	}
+	contract, err := cr.GetContract(contractAddress)
+	if err != nil {
+		return nil, err
+	}
+	ctx := newCallContext(nil, cr, contractAddress, caller, args, value)
+	return contract.ExecuteWithContext(function, ctx)
+}
+
+// CallContractWithContext calls a function on a smart contract with an explicit execution context
+func (cr *ContractRegistry) CallContractWithContext(contractAddress, function string, ctx *ContractContext) (interface{}, error) {
 	contract, err := cr.GetContract(contractAddress)
 	if err != nil {
 		return nil, err
 	}
-	return contract.Execute(function, args, caller, value)
+	return contract.ExecuteWithContext(function, ctx)
 }
 
 // GetAllContracts returns all deployed contracts
@@ -616,7 +947,8 @@ func (cr *ContractRegistry) GetContractsByDeployer(deployer string) []*SmartCont
 
 // IsContractAddress checks if an address is a contract address
 func IsContractAddress(address string) bool {
-	return len(address) == 42 && strings.HasPrefix(address, "0x")
+	addressType, err := AddressTypeOf(address)
+	return err == nil && addressType == AddressTypeContract
 }
 
 // ParseContractCall parses contract call data from transaction data
@@ -625,6 +957,29 @@ func ParseContractCall(data string) (*ContractCall, error) {
 		return nil, fmt.Errorf("empty contract call data")
 	}
 
+	// JSON format: {"function": "transfer", "args": ["0xabc...", 20]}
+	if strings.HasPrefix(strings.TrimSpace(data), "{") {
+		var encoded EncodedCall
+		if err := json.Unmarshal([]byte(data), &encoded); err != nil {
+			return nil, fmt.Errorf("invalid contract call encoding: %v", err)
+		}
+		if encoded.Function == "" {
+			return nil, fmt.Errorf("function name cannot be empty")
+		}
+		args := make([]string, len(encoded.Args))
+		for i, value := range encoded.Args {
+			args[i] = abiValueString(value)
+		}
+		if encoded.Args == nil {
+			encoded.Args = []interface{}{}
+		}
+		return &ContractCall{
+			Function: encoded.Function,
+			Args:     args,
+			Values:   encoded.Args,
+		}, nil
+	}
+
 	// Simple format: "function:arg1,arg2,arg3"
 	parts := strings.SplitN(data, ":", 2)
 	if len(parts) != 2 {
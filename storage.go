@@ -0,0 +1,204 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+)
+
+// Storage pricing: contract state is paid for with a refundable deposit, from which rent is deducted every block
+const (
+	StorageByteDeposit = 0.0001      // Deposit locked per byte of contract state
+	StorageRentPerByte = 0.000000001 // Rent charged per byte of contract state per block (a full deposit lasts 100,000 blocks)
+)
+
+// StorageUsage reports how much state a contract stores and how it is paid for
+type StorageUsage struct {
+	Bytes    int     `json:"bytes"`
+	Deposit  float64 `json:"deposit"`  // Storage deposit held by the contract (not spendable by contract logic)
+	Required float64 `json:"required"` // Deposit that covers the current state
+	Archived bool    `json:"archived"` // Archived for unpaid rent
+}
+
+// stateSize returns the number of bytes a state map occupies: its keys plus their JSON-encoded values.
+// Empty collections are free, so lazily initialized maps cost nothing until they hold data.
+func stateSize(state map[string]interface{}) int {
+	size := 0
+	for key, value := range state {
+		encoded, err := json.Marshal(value)
+		if err != nil {
+			continue
+		}
+		switch string(encoded) {
+		case "{}", "[]", "null":
+			continue
+		}
+		size += len(key) + len(encoded)
+	}
+	return size
+}
+
+// StorageUsage returns the contract's state size and storage deposit
+func (sc *SmartContract) StorageUsage() StorageUsage {
+	sc.mu.RLock()
+	defer sc.mu.RUnlock()
+	size := stateSize(sc.State)
+	return StorageUsage{
+		Bytes:    size,
+		Deposit:  sc.StorageDeposit,
+		Required: float64(size) * StorageByteDeposit,
+		Archived: sc.Archived,
+	}
+}
+
+// spendable returns the native balance an address can spend: contracts cannot spend their storage deposit
+func (ctx *ContractContext) spendable(address string) float64 {
+	balance := ctx.BalanceOf(address)
+	if ctx.registry == nil {
+		return balance
+	}
+	contract, err := ctx.registry.getContract(address, true)
+	if err != nil {
+		return balance
+	}
+	contract.mu.RLock()
+	defer contract.mu.RUnlock()
+	return balance - contract.StorageDeposit
+}
+
+// settleStorage charges storage deposits for a successful top-level call: the caller pays for every
+// byte the call added to a contract's state (or the contract does, from its spare balance, if the
+// caller cannot) and is refunded for every byte it freed. If a deposit cannot be paid, the whole
+// call is reverted. Calls without a chain have no native balances and store state for free.
+func (ctx *ContractContext) settleStorage() error {
+	if ctx.chain == nil {
+		return nil
+	}
+
+	deposits := make(map[*SmartContract]float64, len(ctx.snapshots))
+	for _, s := range ctx.snapshots {
+		s.contract.mu.RLock()
+		grown := stateSize(s.contract.State) - stateSize(s.state)
+		deposit := s.contract.StorageDeposit
+		s.contract.mu.RUnlock()
+
+		switch {
+		case grown > 0:
+			amount := float64(grown) * StorageByteDeposit
+			payer := ctx.Caller
+			if ctx.spendable(payer) < amount {
+				payer = s.contract.Address // The contract pays from its spare balance instead
+			}
+			if ctx.spendable(payer) < amount {
+				ctx.revert()
+				return fmt.Errorf("insufficient funds for storage deposit of %d bytes (%.8f coins)", grown, amount)
+			}
+			if payer != s.contract.Address {
+				ctx.Transfers = append(ctx.Transfers, &InternalTransfer{From: payer, To: s.contract.Address, Amount: amount})
+			}
+			deposits[s.contract] = deposit + amount
+
+		case grown < 0:
+			refund := float64(-grown) * StorageByteDeposit
+			if refund > deposit {
+				refund = deposit // Rent has already consumed part of the deposit
+			}
+			if refund > 0 {
+				ctx.Transfers = append(ctx.Transfers, &InternalTransfer{From: s.contract.Address, To: ctx.Caller, Amount: refund})
+			}
+			deposits[s.contract] = deposit - refund
+		}
+	}
+
+	for contract, deposit := range deposits {
+		contract.mu.Lock()
+		contract.StorageDeposit = deposit
+		contract.mu.Unlock()
+	}
+	return nil
+}
+
+// collectStorageRent charges every contract a block's rent, burning it. Rent is paid from the
+// contract's spare balance and, once that is spent, from its storage deposit; contracts that can
+// pay from neither are archived until their deposit is topped up.
+func (bc *Blockchain) collectStorageRent(blockNumber int) {
+	contracts := bc.ContractRegistry.GetAllContracts()
+	sort.Slice(contracts, func(i, j int) bool { return contracts[i].Address < contracts[j].Address })
+
+	transfers := make([]*InternalTransfer, 0)
+	for _, contract := range contracts {
+		usage := contract.StorageUsage()
+		rent := float64(usage.Bytes) * StorageRentPerByte
+		if usage.Archived || rent == 0 {
+			continue
+		}
+		fromDeposit := rent
+		if spare := bc.GetBalance(contract.Address) - usage.Deposit; spare > 0 {
+			fromDeposit = math.Max(rent-spare, 0)
+		}
+		if usage.Deposit < fromDeposit {
+			bc.ContractRegistry.archiveContract(contract.Address)
+			fmt.Printf("Contract %s archived: storage rent unpaid\n", truncateAddress(contract.Address))
+			continue
+		}
+		contract.mu.Lock()
+		contract.StorageDeposit -= fromDeposit
+		contract.mu.Unlock()
+		transfers = append(transfers, &InternalTransfer{From: contract.Address, To: AssetBurnAddress, Amount: rent})
+	}
+	bc.commitTransfers(transfers, "", blockNumber)
+}
+
+// StorageDepositFunction is the call-data function name of a storage deposit top-up: a contract
+// call whose value is added to the contract's storage deposit instead of running its code. It may
+// be sent to an archived contract, which is restored once its deposit covers its state again.
+const StorageDepositFunction = "payStorageDeposit"
+
+// NewStorageDepositTransaction creates a transaction adding amount to a contract's storage deposit
+func NewStorageDepositTransaction(from, contractAddress string, amount, fee float64) *Transaction {
+	return NewContractCallTransaction(from, contractAddress, StorageDepositFunction, nil, amount, fee)
+}
+
+// depositFromTransaction adds the value of a storage deposit transaction (already moved to the
+// contract by the transaction) to the contract's deposit
+func (bc *Blockchain) depositFromTransaction(tx *Transaction) (*ContractContext, interface{}, error) {
+	ctx := newCallContext(bc, bc.ContractRegistry, tx.To, tx.From, nil, tx.Amount)
+	contract, err := bc.ContractRegistry.getContract(tx.To, true)
+	if err != nil {
+		return ctx, nil, err
+	}
+	if tx.Amount <= 0 {
+		return ctx, nil, fmt.Errorf("storage deposit must be greater than zero")
+	}
+	contract.mu.Lock()
+	contract.StorageDeposit += tx.Amount
+	contract.mu.Unlock()
+
+	if usage := contract.StorageUsage(); usage.Archived && usage.Deposit >= usage.Required {
+		bc.ContractRegistry.restoreContract(tx.To)
+		fmt.Printf("Contract %s restored\n", truncateAddress(tx.To))
+	}
+	return ctx, fmt.Sprintf("Storage deposit of %s is %.8f", truncateAddress(tx.To), contract.StorageUsage().Deposit), nil
+}
+
+// archiveContract marks a contract archived; its state is kept but it can no longer be called
+func (cr *ContractRegistry) archiveContract(address string) {
+	cr.setArchived(address, true)
+}
+
+// restoreContract makes an archived contract callable again
+func (cr *ContractRegistry) restoreContract(address string) {
+	cr.setArchived(address, false)
+}
+
+func (cr *ContractRegistry) setArchived(address string, archived bool) {
+	cr.mu.RLock()
+	contract, exists := cr.Contracts[address]
+	cr.mu.RUnlock()
+	if exists {
+		contract.mu.Lock()
+		contract.Archived = archived
+		contract.mu.Unlock()
+	}
+}
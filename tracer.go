@@ -0,0 +1,309 @@
+package main
+
+import (
+	"encoding/hex"
+	"fmt"
+	"reflect"
+	"sort"
+)
+
+// Trace step operations
+const (
+	TraceOpCall         = "CALL"         // A contract function is entered
+	TraceOpDelegateCall = "DELEGATECALL" // A proxy runs its implementation's logic
+	TraceOpRead         = "SLOAD"        // A state value is read
+	TraceOpWrite        = "SSTORE"       // A state value is written or deleted
+	TraceOpTransfer     = "TRANSFER"     // A contract sends native coins
+	TraceOpLog          = "LOG"          // An event is emitted
+	TraceOpReturn       = "RETURN"       // A function returns successfully
+	TraceOpRevert       = "REVERT"       // A function fails and its changes are undone
+)
+
+// TraceStep is one recorded step of contract execution
+type TraceStep struct {
+	Op       string      `json:"op"`
+	Depth    int         `json:"depth"` // Call depth the step happened at (0 for the top-level call)
+	Contract string      `json:"contract"`
+	Function string      `json:"function,omitempty"`
+	Caller   string      `json:"caller,omitempty"`
+	Args     []string    `json:"args,omitempty"`
+	Amount   float64     `json:"amount,omitempty"` // Native value of a call or transfer
+	To       string      `json:"to,omitempty"`     // Recipient of a transfer, or implementation of a delegate call
+	Key      string      `json:"key,omitempty"`    // State path, e.g. "balances[0xabc]"
+	Value    interface{} `json:"value,omitempty"`  // Value read by SLOAD
+	OldValue interface{} `json:"oldValue,omitempty"`
+	NewValue interface{} `json:"newValue,omitempty"` // Absent when SSTORE deletes the key
+	Topics   []string    `json:"topics,omitempty"`
+	Data     string      `json:"data,omitempty"`
+	Result   interface{} `json:"result,omitempty"`
+	GasUsed  uint64      `json:"gasUsed,omitempty"` // Gas used by the frame, including nested calls
+	Error    string      `json:"error,omitempty"`   // Revert reason
+}
+
+// ExecutionTrace is the step-by-step record of a traced contract transaction
+type ExecutionTrace struct {
+	TxHash       string       `json:"transactionHash"`
+	BlockNumber  int          `json:"blockNumber"`
+	From         string       `json:"from"`
+	To           string       `json:"to"`
+	Function     string       `json:"function"`
+	Failed       bool         `json:"failed"`
+	Result       interface{}  `json:"result,omitempty"`
+	RevertReason string       `json:"revertReason,omitempty"`
+	GasUsed      uint64       `json:"gasUsed"`
+	Steps        []*TraceStep `json:"steps"`
+}
+
+// Tracer records contract execution steps. A nil Tracer records nothing, so hooks can call it unconditionally.
+type Tracer struct {
+	Steps  []*TraceStep
+	frames []*traceFrame // Active call frames, innermost last
+}
+
+// traceFrame is an executing call frame and the state its writes are measured against
+type traceFrame struct {
+	contract   *SmartContract
+	depth      int
+	checkpoint map[string]interface{} // Flattened state as of the last recorded write
+}
+
+// record appends a step, first recording the writes the current frame made before it
+func (t *Tracer) record(step *TraceStep) {
+	if len(t.frames) > 0 {
+		t.flushWrites(t.frames[len(t.frames)-1])
+	}
+	t.Steps = append(t.Steps, step)
+}
+
+// enter records a call into a contract and starts tracking its state writes
+func (t *Tracer) enter(sc *SmartContract, function string, ctx *ContractContext) {
+	if t == nil {
+		return
+	}
+	sc.tracer = t
+	t.record(&TraceStep{
+		Op:       TraceOpCall,
+		Depth:    ctx.Depth,
+		Contract: sc.Address,
+		Function: function,
+		Caller:   ctx.Caller,
+		Args:     append([]string(nil), ctx.Args...),
+		Amount:   ctx.Value,
+	})
+	t.frames = append(t.frames, &traceFrame{contract: sc, depth: ctx.Depth, checkpoint: sc.flatState()})
+}
+
+// exit records the outcome of the innermost call frame
+func (t *Tracer) exit(ctx *ContractContext, result interface{}, err error) {
+	if t == nil || len(t.frames) == 0 {
+		return
+	}
+	frame := t.frames[len(t.frames)-1]
+	t.flushWrites(frame)
+	t.frames = t.frames[:len(t.frames)-1]
+
+	step := &TraceStep{
+		Op:       TraceOpReturn,
+		Depth:    frame.depth,
+		Contract: frame.contract.Address,
+		Result:   result,
+		GasUsed:  ctx.GasUsed,
+	}
+	if err != nil {
+		step.Op = TraceOpRevert
+		step.Result = nil
+		step.Error = err.Error()
+	}
+	t.record(step)
+}
+
+// resume continues tracing the caller's writes from the state a nested call left behind (after any revert)
+func (t *Tracer) resume() {
+	if t == nil || len(t.frames) == 0 {
+		return
+	}
+	frame := t.frames[len(t.frames)-1]
+	frame.checkpoint = frame.contract.flatState()
+}
+
+// delegate records a proxy delegating a function to its implementation
+func (t *Tracer) delegate(ctx *ContractContext, implementation, function string) {
+	if t == nil {
+		return
+	}
+	t.record(&TraceStep{
+		Op:       TraceOpDelegateCall,
+		Depth:    ctx.Depth,
+		Contract: ctx.Address,
+		Function: function,
+		To:       implementation,
+	})
+}
+
+// read records a state read in the innermost frame
+func (t *Tracer) read(sc *SmartContract, key string, value interface{}) {
+	if t == nil {
+		return
+	}
+	depth := 0
+	if len(t.frames) > 0 {
+		depth = t.frames[len(t.frames)-1].depth
+	}
+	t.record(&TraceStep{
+		Op:       TraceOpRead,
+		Depth:    depth,
+		Contract: sc.Address,
+		Key:      key,
+		Value:    cloneStateValue(value),
+	})
+}
+
+// transfer records native coins sent by a contract
+func (t *Tracer) transfer(ctx *ContractContext, to string, amount float64) {
+	if t == nil {
+		return
+	}
+	t.record(&TraceStep{
+		Op:       TraceOpTransfer,
+		Depth:    ctx.Depth,
+		Contract: ctx.Address,
+		To:       to,
+		Amount:   amount,
+	})
+}
+
+// log records an emitted event
+func (t *Tracer) log(ctx *ContractContext, log *Log) {
+	if t == nil {
+		return
+	}
+	t.record(&TraceStep{
+		Op:       TraceOpLog,
+		Depth:    ctx.Depth,
+		Contract: log.Address,
+		Topics:   log.Topics,
+		Data:     log.Data,
+	})
+}
+
+// flushWrites records every state value the frame's contract changed since its checkpoint, in key order
+func (t *Tracer) flushWrites(frame *traceFrame) {
+	current := frame.contract.flatState()
+	keys := make([]string, 0, len(current))
+	for key := range current {
+		keys = append(keys, key)
+	}
+	for key := range frame.checkpoint {
+		if _, exists := current[key]; !exists {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		old, existed := frame.checkpoint[key]
+		value, exists := current[key]
+		if existed && exists && reflect.DeepEqual(old, value) {
+			continue
+		}
+		t.Steps = append(t.Steps, &TraceStep{
+			Op:       TraceOpWrite,
+			Depth:    frame.depth,
+			Contract: frame.contract.Address,
+			Key:      key,
+			OldValue: old,
+			NewValue: value,
+		})
+	}
+	frame.checkpoint = current
+}
+
+// traceRead records a state read if the contract is being traced
+func (sc *SmartContract) traceRead(key string, value interface{}) {
+	if sc.tracer != nil {
+		sc.tracer.read(sc, key, value)
+	}
+}
+
+// flatState returns a copy of the contract's state with nested maps flattened to paths like "balances[0xabc]"
+func (sc *SmartContract) flatState() map[string]interface{} {
+	sc.mu.RLock()
+	defer sc.mu.RUnlock()
+	flat := make(map[string]interface{})
+	for key, value := range sc.State {
+		flattenStateValue(key, value, flat)
+	}
+	return flat
+}
+
+func flattenStateValue(path string, value interface{}, flat map[string]interface{}) {
+	v := reflect.ValueOf(value)
+	if v.Kind() != reflect.Map {
+		flat[path] = cloneStateValue(value)
+		return
+	}
+	for _, key := range v.MapKeys() {
+		flattenStateValue(fmt.Sprintf("%s[%v]", path, key.Interface()), v.MapIndex(key).Interface(), flat)
+	}
+}
+
+// TraceTransaction re-executes a mined contract transaction with tracing enabled.
+// The transaction is replayed on a copy of the chain, against the contract state of the block
+// before it and after the contract transactions that precede it in its own block.
+func (bc *Blockchain) TraceTransaction(txHash string) (*ExecutionTrace, error) {
+	receipt, err := bc.GetReceipt(txHash)
+	if err != nil {
+		return nil, err
+	}
+	block := bc.Blocks[receipt.BlockNumber]
+
+	registry, err := bc.registryAt(block.Index - 1)
+	if err != nil {
+		return nil, err
+	}
+	replay := &Blockchain{
+		Blocks:           bc.Blocks[:block.Index+1],
+		ContractRegistry: registry,
+		Receipts:         make(map[string]*Receipt),
+		StateHistory:     bc.StateHistory,
+	}
+	for _, t := range bc.InternalTransfers {
+		if t.BlockNumber < block.Index {
+			replay.InternalTransfers = append(replay.InternalTransfers, t)
+		}
+	}
+
+	for txIndex, tx := range block.Transactions[:receipt.TxIndex] {
+		if call := contractCallOf(tx); call != nil {
+			replay.executeContractTransaction(tx, call, block.Index, txIndex)
+		}
+	}
+
+	tx := block.Transactions[receipt.TxIndex]
+	call := contractCallOf(tx)
+	if call == nil {
+		return nil, fmt.Errorf("transaction %s is not a contract transaction", txHash)
+	}
+	replay.tracer = &Tracer{}
+	replayed := replay.executeContractTransaction(tx, call, block.Index, receipt.TxIndex)
+
+	trace := &ExecutionTrace{
+		TxHash:       hex.EncodeToString(tx.Hash()),
+		BlockNumber:  block.Index,
+		From:         tx.From,
+		To:           tx.To,
+		Function:     call.Function,
+		Failed:       !replayed.Status,
+		Result:       replayed.Result,
+		RevertReason: replayed.Error,
+		GasUsed:      replayed.GasUsed,
+		Steps:        replay.tracer.Steps,
+	}
+	if trace.To == "" {
+		trace.To = replayed.ContractAddress
+	}
+	if trace.Steps == nil {
+		trace.Steps = make([]*TraceStep, 0)
+	}
+	return trace, nil
+}
@@ -2,41 +2,50 @@ package main
 
 import (
 	"crypto/ecdsa"
-	"crypto/elliptic"
-	"crypto/rand"
 	"crypto/sha256"
 	"encoding/hex"
 	"fmt"
-	"math/big"
 	"strings"
 )
 
 // Transaction represents a transaction in the blockchain
 type Transaction struct {
+	Scheme       SignatureScheme // Signature scheme (see signer.go), set when the transaction is signed
 	From         string
 	To           string
 	Amount       float64
 	Fee          float64 // Transaction fee paid by sender
 	Signature    string  // Hex-encoded signature
-	PublicKey    string  // Hex-encoded public key (X + Y coordinates) for verification
-	ContractData string  // Contract call data (format: "function:arg1,arg2,arg3")
+	PublicKey    string  // Hex-encoded public key for verification; empty for recoverable schemes such as secp256k1
+	ContractData string  // Contract call data ("function:arg1,arg2,arg3" or JSON {"function":...,"args":[...]})
+	Asset        string  // Named asset moved by the transaction (NativeAsset for the native coin, see assets.go)
+	// Multisig is the account a multisig transaction spends from, and Signatures holds one signature
+	// per account key in key order ("" for keys that have not signed); see multisig.go
+	Multisig   *MultisigAccount
+	Signatures []string
+	// Type, Version and Payload make a typed transaction (see txtypes.go): Payload is the canonical
+	// encoding of the type's fields. Legacy transactions have all three zero.
+	Type    TxType
+	Version byte
+	Payload []byte
 }
 
-// NewTransaction creates a new transaction
+// NewTransaction creates a new transaction. Addresses may be given encoded (see address.go) and are
+// stored in internal form; invalid ones are kept as given and rejected by ValidateAddresses.
 func NewTransaction(from, to string, amount float64) *Transaction {
 	return &Transaction{
-		From:   from,
-		To:     to,
+		From:   canonicalAddress(from),
+		To:     canonicalAddress(to),
 		Amount: amount,
 		Fee:    0.0, // Default no fee
 	}
 }
 
-// NewTransactionWithFee creates a new transaction with fee
+// NewTransactionWithFee creates a new transaction with fee; addresses are handled as by NewTransaction
 func NewTransactionWithFee(from, to string, amount, fee float64) *Transaction {
 	return &Transaction{
-		From:   from,
-		To:     to,
+		From:   canonicalAddress(from),
+		To:     canonicalAddress(to),
 		Amount: amount,
 		Fee:    fee,
 	}
@@ -60,102 +69,219 @@ func NewContractCallTransaction(from, contractAddress, function string, args []s
 	}
 }
 
-// Sign signs the transaction with a private key and stores the public key
+// NewContractCallTransactionWithData creates a contract call transaction from encoded call data
+// (for example JSON produced by ContractABI.EncodeCall)
+func NewContractCallTransactionWithData(from, contractAddress, callData string, value, fee float64) *Transaction {
+	return &Transaction{
+		From:         from,
+		To:           contractAddress,
+		Amount:       value,
+		Fee:          fee,
+		ContractData: callData,
+	}
+}
+
+// NewContractDeployTransaction creates a transaction that deploys a contract when included in a block.
+// For WASM contracts the bytecode is the hex-encoded module and args are passed to its "init" export.
+func NewContractDeployTransaction(from string, contractType ContractType, bytecode string, args []string, value, fee float64) *Transaction {
+	params := append([]string{string(contractType), bytecode}, args...)
+	return &Transaction{
+		From:         from,
+		To:           "",
+		Amount:       value,
+		Fee:          fee,
+		ContractData: fmt.Sprintf("%s:%s", ContractDeployFunction, strings.Join(params, ",")),
+	}
+}
+
+// Sign signs the transaction with an ECDSA private key: secp256k1 keys sign recoverably,
+// P-256 keys sign with the legacy scheme
 func (tx *Transaction) Sign(privateKey *ecdsa.PrivateKey) error {
-	// Create hash of transaction data
-	hash := tx.Hash()
+	return tx.SignWith(NewECDSASigner(privateKey))
+}
 
-	// Sign the hash
-	r, s, err := ecdsa.Sign(rand.Reader, privateKey, hash)
+// SignWith signs the transaction with any registered signature scheme. The scheme is set
+// before hashing, so the signature covers it.
+func (tx *Transaction) SignWith(signer Signer) error {
+	info, err := GetSignatureScheme(signer.Scheme())
 	if err != nil {
 		return err
 	}
+	tx.Scheme = signer.Scheme()
 
-	// Encode signature as hex string (r + s)
-	signature := append(r.Bytes(), s.Bytes()...)
+	// Sign the hash of the transaction data
+	signature, err := signer.Sign(tx.Hash())
+	if err != nil {
+		return err
+	}
 	tx.Signature = hex.EncodeToString(signature)
 
-	// Store public key for verification
-	publicKey := &privateKey.PublicKey
-	publicKeyBytes := append(publicKey.X.Bytes(), publicKey.Y.Bytes()...)
-	tx.PublicKey = hex.EncodeToString(publicKeyBytes)
-
+	// Store public key for verification unless the scheme recovers it from the signature
+	tx.PublicKey = ""
+	if !info.Recoverable {
+		tx.PublicKey = hex.EncodeToString(signer.PublicKey())
+	}
 	return nil
 }
 
-// Verify verifies the transaction signature using stored public key
+// Verify verifies the transaction signature with its scheme and checks that the signing key
+// belongs to the From address
 func (tx *Transaction) Verify() bool {
-	if tx.Signature == "" || tx.PublicKey == "" {
-		return false
-	}
+	return tx.CheckSignature() == nil
+}
 
-	// Decode public key
-	publicKeyBytes, err := hex.DecodeString(tx.PublicKey)
+// CheckSignature verifies the transaction signature like Verify, explaining why it fails:
+// a missing signature, a key that is not the sender's, or a signature that does not match
+func (tx *Transaction) CheckSignature() error {
+	if tx.Multisig != nil || IsMultisigAddress(tx.From) {
+		return tx.checkMultisigSignatures()
+	}
+	signed, err := tx.decodeSignature()
 	if err != nil {
-		return false
+		return err
 	}
-
-	// P-256 curve: public key is 64 bytes (32 bytes X + 32 bytes Y)
-	if len(publicKeyBytes) != 64 {
-		return false
+	if signed.info.Recoverable {
+		return nil
 	}
-
-	// Reconstruct public key
-	publicKey := &ecdsa.PublicKey{
-		Curve: elliptic.P256(),
-		X:     new(big.Int).SetBytes(publicKeyBytes[:32]),
-		Y:     new(big.Int).SetBytes(publicKeyBytes[32:]),
+	if !signed.info.Verify(signed.publicKey, signed.hash, signed.signature) {
+		return fmt.Errorf("invalid transaction signature")
 	}
+	return nil
+}
 
-	// Decode signature
-	signatureBytes, err := hex.DecodeString(tx.Signature)
+// signedTransaction is a single-key transaction signature decoded for verification
+type signedTransaction struct {
+	info                       *SchemeInfo
+	hash, publicKey, signature []byte
+}
+
+// decodeSignature hashes the transaction and decodes its signature and key once, checking that
+// the key belongs to the sender. Recoverable signatures are fully checked by recovering the key;
+// others still need the scheme's Verify.
+func (tx *Transaction) decodeSignature() (*signedTransaction, error) {
+	if tx.Signature == "" {
+		return nil, fmt.Errorf("transaction from %s is not signed", tx.From)
+	}
+	info, err := GetSignatureScheme(tx.Scheme)
 	if err != nil {
-		return false
+		return nil, err
 	}
-
-	if len(signatureBytes) != 64 { // 32 bytes for r + 32 bytes for s
-		return false
+	signature, err := hex.DecodeString(tx.Signature)
+	if err != nil {
+		return nil, fmt.Errorf("invalid signature encoding")
 	}
-
-	r := new(big.Int).SetBytes(signatureBytes[:32])
-	s := new(big.Int).SetBytes(signatureBytes[32:])
-
-	// Create hash of transaction data
 	hash := tx.Hash()
 
-	// Verify signature
-	return ecdsa.Verify(publicKey, hash, r, s)
+	// Recoverable schemes recover the signer from the signature; others derive it from the stored public key
+	var publicKey []byte
+	if info.Recoverable {
+		if publicKey, err = info.Recover(hash, signature); err != nil {
+			return nil, fmt.Errorf("invalid transaction signature: %v", err)
+		}
+	} else if publicKey, err = hex.DecodeString(tx.PublicKey); err != nil {
+		return nil, fmt.Errorf("invalid public key encoding")
+	}
+	sender, err := info.Address(publicKey)
+	if err != nil {
+		return nil, fmt.Errorf("invalid transaction signature: %v", err)
+	}
+	if sender != tx.From {
+		return nil, fmt.Errorf("transaction from %s is signed by the key of %s", tx.From, sender)
+	}
+	return &signedTransaction{info: info, hash: hash, publicKey: publicKey, signature: signature}, nil
 }
 
-// VerifyWithPublicKey verifies the transaction signature with provided public key
+// VerifyWithPublicKey verifies the transaction signature with provided ECDSA public key,
+// which must be the key of the From address
 func (tx *Transaction) VerifyWithPublicKey(publicKey *ecdsa.PublicKey) bool {
-	if tx.Signature == "" {
+	scheme := SchemeP256
+	if publicKey.Curve == secp256k1 {
+		scheme = SchemeSecp256k1
+	}
+	if tx.Scheme != scheme {
 		return false
 	}
-
-	// Decode signature
-	signatureBytes, err := hex.DecodeString(tx.Signature)
+	info, err := GetSignatureScheme(scheme)
 	if err != nil {
 		return false
 	}
-
-	if len(signatureBytes) != 64 { // 32 bytes for r + 32 bytes for s
+	if address, err := info.Address(publicKeyBytes(publicKey)); err != nil || address != tx.From {
 		return false
 	}
+	signature, err := hex.DecodeString(tx.Signature)
+	if err != nil {
+		return false
+	}
+	return info.Verify(publicKeyBytes(publicKey), tx.Hash(), signature)
+}
 
-	r := new(big.Int).SetBytes(signatureBytes[:32])
-	s := new(big.Int).SetBytes(signatureBytes[32:])
+// IsCoinbase reports whether the transaction creates new coins: block rewards and initial
+// allocations have no sender and carry no signature
+func (tx *Transaction) IsCoinbase() bool {
+	return tx.From == ""
+}
 
-	// Create hash of transaction data
-	hash := tx.Hash()
+// ValidateAddresses checks that the sender and recipient are well-formed addresses. The sender is
+// empty only for coinbases, and the recipient only for contract deployments and asset issues.
+func (tx *Transaction) ValidateAddresses() error {
+	if tx.From != "" {
+		if err := ValidateAddress(tx.From); err != nil {
+			return fmt.Errorf("invalid sender: %v", err)
+		}
+	}
+	if tx.To != "" {
+		if err := ValidateAddress(tx.To); err != nil {
+			return fmt.Errorf("invalid recipient: %v", err)
+		}
+	}
+	return nil
+}
 
-	// Verify signature
-	return ecdsa.Verify(publicKey, hash, r, s)
+// Sender returns the address of the key that signed the transaction: recovered from the
+// signature for recoverable schemes, derived from the stored public key otherwise, or the
+// address of the multisig account.
+// It does not check the signature of non-recoverable schemes; use Verify for that.
+func (tx *Transaction) Sender() (string, error) {
+	if tx.Multisig != nil {
+		return tx.Multisig.Address(), nil
+	}
+	info, err := GetSignatureScheme(tx.Scheme)
+	if err != nil {
+		return "", err
+	}
+	if !info.Recoverable {
+		publicKey, err := hex.DecodeString(tx.PublicKey)
+		if err != nil {
+			return "", fmt.Errorf("invalid public key encoding")
+		}
+		return info.Address(publicKey)
+	}
+	signature, err := hex.DecodeString(tx.Signature)
+	if err != nil {
+		return "", fmt.Errorf("invalid signature encoding")
+	}
+	publicKey, err := info.Recover(tx.Hash(), signature)
+	if err != nil {
+		return "", err
+	}
+	return info.Address(publicKey)
 }
 
-// Hash returns the SHA-256 hash of the transaction
+// Hash returns the SHA-256 hash of the transaction. Typed transactions hash their canonical
+// binary encoding (see txencoding.go); legacy ones keep the original text format.
 func (tx *Transaction) Hash() []byte {
+	if tx.Type != TxTypeLegacy {
+		return tx.typedHash()
+	}
 	data := fmt.Sprintf("%s%s%.8f%.8f%s", tx.From, tx.To, tx.Amount, tx.Fee, tx.ContractData)
+	// Native coin transactions keep the hash they had before named assets existed
+	if tx.Asset != NativeAsset {
+		data += "\x00asset:" + tx.Asset
+	}
+	// Legacy P-256 transactions keep their hash; other schemes are committed to by the hash
+	if tx.Scheme != SchemeP256 {
+		data += fmt.Sprintf("\x00scheme:%d", tx.Scheme)
+	}
 	hash := sha256.Sum256([]byte(data))
 	return hash[:]
 }
@@ -163,6 +289,12 @@ func (tx *Transaction) Hash() []byte {
 // String returns a string representation of the transaction
 func (tx *Transaction) String() string {
 	result := fmt.Sprintf("From: %s, To: %s, Amount: %.2f", tx.From, tx.To, tx.Amount)
+	if tx.Type != TxTypeLegacy {
+		result = fmt.Sprintf("[%s v%d] %s", tx.Type, tx.Version, result)
+	}
+	if tx.Asset != NativeAsset {
+		result += fmt.Sprintf(" %s", tx.Asset)
+	}
 	if tx.Fee > 0 {
 		result += fmt.Sprintf(", Fee: %.2f", tx.Fee)
 	}
@@ -172,7 +304,10 @@ func (tx *Transaction) String() string {
 	return result
 }
 
-// TotalCost returns the total cost for the sender (amount + fee)
+// TotalCost returns the native coin cost for the sender (amount + fee, or only the fee for named assets)
 func (tx *Transaction) TotalCost() float64 {
+	if tx.Asset != NativeAsset {
+		return tx.Fee
+	}
 	return tx.Amount + tx.Fee
 }
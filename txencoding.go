@@ -0,0 +1,237 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"math"
+)
+
+// amountScale is the fixed-point scale of encoded amounts: 8 decimal places, as in the legacy hash
+const amountScale = 1e8
+
+// txEncoder writes the canonical binary encoding: fixed-size integers are big-endian, strings and
+// byte slices are prefixed with their length as a uvarint, and amounts are 8-decimal fixed point
+type txEncoder struct {
+	buf []byte
+}
+
+func (e *txEncoder) byte(b byte) {
+	e.buf = append(e.buf, b)
+}
+
+func (e *txEncoder) uvarint(v uint64) {
+	e.buf = binary.AppendUvarint(e.buf, v)
+}
+
+func (e *txEncoder) int64(v int64) {
+	e.buf = binary.BigEndian.AppendUint64(e.buf, uint64(v))
+}
+
+func (e *txEncoder) bytes(b []byte) {
+	e.uvarint(uint64(len(b)))
+	e.buf = append(e.buf, b...)
+}
+
+func (e *txEncoder) string(s string) {
+	e.bytes([]byte(s))
+}
+
+func (e *txEncoder) strings(list []string) {
+	e.uvarint(uint64(len(list)))
+	for _, s := range list {
+		e.string(s)
+	}
+}
+
+func (e *txEncoder) amount(v float64) {
+	e.int64(int64(math.Round(v * amountScale)))
+}
+
+// txDecoder reads what txEncoder writes. The first error sticks and later reads return zero values,
+// so decoders can read every field and check err once.
+type txDecoder struct {
+	data []byte
+	err  error
+}
+
+func (d *txDecoder) fail(format string, args ...interface{}) {
+	if d.err == nil {
+		d.err = fmt.Errorf(format, args...)
+	}
+}
+
+func (d *txDecoder) byte() byte {
+	if d.err != nil || len(d.data) < 1 {
+		d.fail("unexpected end of data")
+		return 0
+	}
+	b := d.data[0]
+	d.data = d.data[1:]
+	return b
+}
+
+func (d *txDecoder) uvarint() uint64 {
+	if d.err != nil {
+		return 0
+	}
+	v, n := binary.Uvarint(d.data)
+	if n <= 0 {
+		d.fail("invalid varint")
+		return 0
+	}
+	// Canonical encodings use the shortest form
+	if n != len(binary.AppendUvarint(nil, v)) {
+		d.fail("non-canonical varint")
+		return 0
+	}
+	d.data = d.data[n:]
+	return v
+}
+
+func (d *txDecoder) int64() int64 {
+	if d.err != nil || len(d.data) < 8 {
+		d.fail("unexpected end of data")
+		return 0
+	}
+	v := int64(binary.BigEndian.Uint64(d.data))
+	d.data = d.data[8:]
+	return v
+}
+
+func (d *txDecoder) bytes() []byte {
+	n := d.uvarint()
+	if d.err != nil || uint64(len(d.data)) < n {
+		d.fail("unexpected end of data")
+		return nil
+	}
+	b := append([]byte(nil), d.data[:n]...)
+	d.data = d.data[n:]
+	return b
+}
+
+func (d *txDecoder) string() string {
+	return string(d.bytes())
+}
+
+func (d *txDecoder) strings() []string {
+	n := d.uvarint()
+	if d.err != nil || n > uint64(len(d.data)) {
+		d.fail("invalid list length")
+		return nil
+	}
+	list := make([]string, n)
+	for i := range list {
+		list[i] = d.string()
+	}
+	return list
+}
+
+func (d *txDecoder) amount() float64 {
+	return float64(d.int64()) / amountScale
+}
+
+// finish returns the first error, or an error if bytes are left over
+func (d *txDecoder) finish() error {
+	if d.err == nil && len(d.data) > 0 {
+		d.fail("%d unexpected trailing bytes", len(d.data))
+	}
+	return d.err
+}
+
+// encodeBody writes the signed part of a transaction: everything except its signatures
+func (tx *Transaction) encodeBody(e *txEncoder) {
+	e.byte(byte(tx.Type))
+	e.byte(tx.Version)
+	e.byte(byte(tx.Scheme))
+	e.string(tx.From)
+	e.string(tx.To)
+	e.amount(tx.Amount)
+	e.amount(tx.Fee)
+	e.string(tx.Asset)
+	if tx.Type == TxTypeLegacy {
+		e.string(tx.ContractData)
+	} else {
+		e.bytes(tx.Payload)
+	}
+}
+
+// typedHash hashes the canonical encoding of a typed transaction's body; the type and version
+// come first, so transactions of different types or versions never share a hash
+func (tx *Transaction) typedHash() []byte {
+	e := &txEncoder{}
+	tx.encodeBody(e)
+	hash := sha256.Sum256(e.buf)
+	return hash[:]
+}
+
+// MarshalBinary encodes the transaction, signatures included, in the canonical binary format
+func (tx *Transaction) MarshalBinary() ([]byte, error) {
+	e := &txEncoder{}
+	tx.encodeBody(e)
+	e.string(tx.Signature)
+	e.string(tx.PublicKey)
+	if tx.Multisig == nil {
+		e.byte(0)
+	} else {
+		e.byte(1)
+		e.uvarint(uint64(tx.Multisig.Threshold))
+		e.uvarint(uint64(len(tx.Multisig.Keys)))
+		for _, key := range tx.Multisig.Keys {
+			e.byte(byte(key.Scheme))
+			e.string(key.PublicKey)
+		}
+	}
+	e.strings(tx.Signatures)
+	return e.buf, nil
+}
+
+// UnmarshalBinary decodes a transaction written by MarshalBinary. Legacy transactions decode
+// to their original form and keep their hash; typed ones must have a registered type and a
+// canonically encoded payload.
+func (tx *Transaction) UnmarshalBinary(data []byte) error {
+	d := &txDecoder{data: data}
+	decoded := Transaction{
+		Type:    TxType(d.byte()),
+		Version: d.byte(),
+		Scheme:  SignatureScheme(d.byte()),
+		From:    d.string(),
+		To:      d.string(),
+		Amount:  d.amount(),
+		Fee:     d.amount(),
+		Asset:   d.string(),
+	}
+	if decoded.Type == TxTypeLegacy {
+		decoded.ContractData = d.string()
+	} else {
+		decoded.Payload = d.bytes()
+	}
+	decoded.Signature = d.string()
+	decoded.PublicKey = d.string()
+	switch d.byte() {
+	case 0:
+	case 1:
+		account := &MultisigAccount{Threshold: int(d.uvarint())}
+		count := d.uvarint()
+		if count > MaxMultisigKeys {
+			d.fail("multisig account has too many keys")
+		}
+		for i := uint64(0); i < count && d.err == nil; i++ {
+			account.Keys = append(account.Keys, MultisigKey{Scheme: SignatureScheme(d.byte()), PublicKey: d.string()})
+		}
+		decoded.Multisig = account
+	default:
+		d.fail("invalid multisig flag")
+	}
+	if signatures := d.strings(); len(signatures) > 0 {
+		decoded.Signatures = signatures
+	}
+	if err := d.finish(); err != nil {
+		return fmt.Errorf("invalid transaction encoding: %v", err)
+	}
+	if err := decoded.ValidateEnvelope(); err != nil {
+		return err
+	}
+	*tx = decoded
+	return nil
+}
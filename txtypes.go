@@ -0,0 +1,505 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// TxType identifies the kind of a transaction. Typed transactions keep their common fields (sender,
+// recipient, amount, fee, asset) in the envelope and the fields of their kind in a payload with its
+// own canonical binary encoding; the type and version are covered by the hash.
+type TxType byte
+
+// Built-in transaction types
+const (
+	TxTypeLegacy         TxType = 0 // Original flat transaction; keeps its hash and uses ContractData for contracts
+	TxTypeTransfer       TxType = 1
+	TxTypeContractDeploy TxType = 2
+	TxTypeContractCall   TxType = 3
+	TxTypeStake          TxType = 4
+	TxTypeUnstake        TxType = 5
+	TxTypeVote           TxType = 6
+	TxTypeChannelOpen    TxType = 7
+	TxTypeChannelClose   TxType = 8
+	TxTypeBridgeLock     TxType = 9
+)
+
+// TxPayload holds the type-specific fields of a typed transaction
+type TxPayload interface {
+	Type() TxType
+	MarshalBinary() ([]byte, error) // Canonical encoding: one byte sequence per payload
+	Validate(tx *Transaction) error // Checks the payload and the envelope fields its type constrains
+}
+
+// TxTypeInfo describes a registered transaction type
+type TxTypeInfo struct {
+	Name    string
+	Version byte // Encoding version written into new transactions of the type
+	// Decode reads a payload of any version the type still accepts
+	Decode func(version byte, data []byte) (TxPayload, error)
+	// Pending marks a type whose effects are not applied to chain state yet: its transactions
+	// encode and decode, but blocks reject them
+	Pending bool
+}
+
+var (
+	txTypes   = make(map[TxType]*TxTypeInfo)
+	txTypesMu sync.RWMutex
+)
+
+func init() {
+	mustRegister := func(t TxType, name string, decode func(d *txDecoder) TxPayload) {
+		info := &TxTypeInfo{Name: name, Version: 1, Decode: func(version byte, data []byte) (TxPayload, error) {
+			if version != 1 {
+				return nil, fmt.Errorf("unsupported %s transaction version %d", name, version)
+			}
+			d := &txDecoder{data: data}
+			payload := decode(d)
+			if err := d.finish(); err != nil {
+				return nil, fmt.Errorf("invalid %s payload: %v", name, err)
+			}
+			return payload, nil
+		}}
+		if err := RegisterTxType(t, info); err != nil {
+			panic(err)
+		}
+	}
+	mustRegister(TxTypeTransfer, "transfer", func(d *txDecoder) TxPayload {
+		return &TransferPayload{Memo: d.string()}
+	})
+	mustRegister(TxTypeContractDeploy, "contract-deploy", func(d *txDecoder) TxPayload {
+		return &ContractDeployPayload{ContractType: ContractType(d.string()), Bytecode: d.string(), Args: d.strings()}
+	})
+	mustRegister(TxTypeContractCall, "contract-call", func(d *txDecoder) TxPayload {
+		return &ContractCallPayload{CallData: d.string()}
+	})
+	mustRegister(TxTypeStake, "stake", func(d *txDecoder) TxPayload {
+		return &StakePayload{Validator: d.string()}
+	})
+	mustRegister(TxTypeUnstake, "unstake", func(d *txDecoder) TxPayload {
+		return &UnstakePayload{Validator: d.string(), Amount: d.amount()}
+	})
+	mustRegister(TxTypeVote, "vote", func(d *txDecoder) TxPayload {
+		return &VotePayload{Delegate: d.string(), Weight: d.amount()}
+	})
+	mustRegister(TxTypeChannelOpen, "channel-open", func(d *txDecoder) TxPayload {
+		return &ChannelOpenPayload{Counterparty: d.string(), CounterpartyDeposit: d.amount(), TimeoutSeconds: d.int64()}
+	})
+	mustRegister(TxTypeChannelClose, "channel-close", func(d *txDecoder) TxPayload {
+		return &ChannelClosePayload{ChannelID: d.string(), SequenceNumber: d.int64(), Balance1: d.amount(), Balance2: d.amount()}
+	})
+	mustRegister(TxTypeBridgeLock, "bridge-lock", func(d *txDecoder) TxPayload {
+		return &BridgeLockPayload{TargetChain: d.string(), Recipient: d.string()}
+	})
+
+	// Proof of stake still takes stake from balances, and DPoS votes and payment channels live in
+	// memory, so nothing would act on these yet
+	for _, t := range []TxType{TxTypeStake, TxTypeUnstake, TxTypeVote, TxTypeChannelOpen, TxTypeChannelClose} {
+		txTypes[t].Pending = true
+	}
+}
+
+// RegisterTxType adds a transaction type under an unused identifier and name. Blocks holding
+// transactions of existing types are unaffected, since each type hashes and decodes on its own.
+func RegisterTxType(t TxType, info *TxTypeInfo) error {
+	if t == TxTypeLegacy {
+		return fmt.Errorf("transaction type %d is reserved for legacy transactions", t)
+	}
+	if info.Name == "" || info.Decode == nil {
+		return fmt.Errorf("transaction type %d needs a name and Decode", t)
+	}
+	txTypesMu.Lock()
+	defer txTypesMu.Unlock()
+	if existing, exists := txTypes[t]; exists {
+		return fmt.Errorf("transaction type %d is already registered as %s", t, existing.Name)
+	}
+	for _, existing := range txTypes {
+		if existing.Name == info.Name {
+			return fmt.Errorf("transaction type name %s is already registered", info.Name)
+		}
+	}
+	txTypes[t] = info
+	return nil
+}
+
+// GetTxType returns a registered transaction type
+func GetTxType(t TxType) (*TxTypeInfo, error) {
+	txTypesMu.RLock()
+	defer txTypesMu.RUnlock()
+	info, exists := txTypes[t]
+	if !exists {
+		return nil, fmt.Errorf("unknown transaction type %d", t)
+	}
+	return info, nil
+}
+
+// TxTypes returns the identifiers of all registered types in order
+func TxTypes() []TxType {
+	txTypesMu.RLock()
+	defer txTypesMu.RUnlock()
+	types := make([]TxType, 0, len(txTypes))
+	for t := range txTypes {
+		types = append(types, t)
+	}
+	sort.Slice(types, func(i, j int) bool { return types[i] < types[j] })
+	return types
+}
+
+// String returns the name of the transaction type
+func (t TxType) String() string {
+	if t == TxTypeLegacy {
+		return "legacy"
+	}
+	if info, err := GetTxType(t); err == nil {
+		return info.Name
+	}
+	return fmt.Sprintf("unknown(%d)", byte(t))
+}
+
+// NewTypedTransaction creates a transaction of the payload's type at its current version.
+// Addresses may be encoded, as for NewTransaction.
+func NewTypedTransaction(from, to string, amount, fee float64, payload TxPayload) (*Transaction, error) {
+	info, err := GetTxType(payload.Type())
+	if err != nil {
+		return nil, err
+	}
+	encoded, err := payload.MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+	tx := NewTransactionWithFee(from, to, amount, fee)
+	tx.Type = payload.Type()
+	tx.Version = info.Version
+	tx.Payload = encoded
+	if err := tx.ValidateEnvelope(); err != nil {
+		return nil, err
+	}
+	return tx, nil
+}
+
+// DecodePayload decodes the payload of a typed transaction
+func (tx *Transaction) DecodePayload() (TxPayload, error) {
+	if tx.Type == TxTypeLegacy {
+		return nil, fmt.Errorf("legacy transactions have no payload")
+	}
+	info, err := GetTxType(tx.Type)
+	if err != nil {
+		return nil, err
+	}
+	return info.Decode(tx.Version, tx.Payload)
+}
+
+// ValidateEnvelope checks a transaction's type, version and payload: the payload must decode,
+// be in canonical form (so one transaction cannot have two hashes) and suit the envelope
+func (tx *Transaction) ValidateEnvelope() error {
+	if tx.Type == TxTypeLegacy {
+		if tx.Version != 0 || len(tx.Payload) > 0 {
+			return fmt.Errorf("legacy transactions have no version or payload")
+		}
+		return nil
+	}
+	if tx.ContractData != "" {
+		return fmt.Errorf("%s transactions carry contract data in their payload", tx.Type)
+	}
+	payload, err := tx.DecodePayload()
+	if err != nil {
+		return err
+	}
+	if payload.Type() != tx.Type {
+		return fmt.Errorf("%s payload in a %s transaction", payload.Type(), tx.Type)
+	}
+	encoded, err := payload.MarshalBinary()
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(encoded, tx.Payload) {
+		return fmt.Errorf("%s payload is not canonically encoded", tx.Type)
+	}
+	if err := payload.Validate(tx); err != nil {
+		return fmt.Errorf("invalid %s transaction: %v", tx.Type, err)
+	}
+	return nil
+}
+
+// checkTypeAccepted rejects transactions of a pending type from blocks
+func (tx *Transaction) checkTypeAccepted() error {
+	if tx.Type == TxTypeLegacy {
+		return nil
+	}
+	info, err := GetTxType(tx.Type)
+	if err != nil {
+		return err
+	}
+	if info.Pending {
+		return fmt.Errorf("%s transactions are not accepted into blocks yet", tx.Type)
+	}
+	return nil
+}
+
+// typedContractCall returns the contract call or deployment of a typed transaction (nil if it has none)
+func (tx *Transaction) typedContractCall() *ContractCall {
+	payload, err := tx.DecodePayload()
+	if err != nil {
+		return nil
+	}
+	switch p := payload.(type) {
+	case *ContractDeployPayload:
+		args := append([]string{string(p.ContractType), p.Bytecode}, p.Args...)
+		return &ContractCall{Function: ContractDeployFunction, Args: args}
+	case *ContractCallPayload:
+		call, err := ParseContractCall(p.CallData)
+		if err != nil {
+			return nil
+		}
+		return call
+	}
+	return nil
+}
+
+// requireNoValue checks that a transaction moves no coins, only paying its fee
+func requireNoValue(tx *Transaction) error {
+	if tx.To != "" || tx.Amount != 0 {
+		return fmt.Errorf("must not have a recipient or amount")
+	}
+	return nil
+}
+
+// requireAddress checks an address field of a payload
+func requireAddress(field, address string) error {
+	if err := ValidateAddress(address); err != nil {
+		return fmt.Errorf("%s: %v", field, err)
+	}
+	return nil
+}
+
+// TransferPayload moves the envelope amount of its asset to the recipient, with an optional memo
+type TransferPayload struct {
+	Memo string
+}
+
+func (p *TransferPayload) Type() TxType { return TxTypeTransfer }
+
+func (p *TransferPayload) MarshalBinary() ([]byte, error) {
+	e := &txEncoder{}
+	e.string(p.Memo)
+	return e.buf, nil
+}
+
+func (p *TransferPayload) Validate(tx *Transaction) error {
+	if tx.To == "" {
+		return fmt.Errorf("missing recipient")
+	}
+	return nil
+}
+
+// ContractDeployPayload deploys a contract; the envelope amount is sent to it and it has no recipient
+type ContractDeployPayload struct {
+	ContractType ContractType
+	Bytecode     string
+	Args         []string // Constructor arguments
+}
+
+func (p *ContractDeployPayload) Type() TxType { return TxTypeContractDeploy }
+
+func (p *ContractDeployPayload) MarshalBinary() ([]byte, error) {
+	e := &txEncoder{}
+	e.string(string(p.ContractType))
+	e.string(p.Bytecode)
+	e.strings(p.Args)
+	return e.buf, nil
+}
+
+func (p *ContractDeployPayload) Validate(tx *Transaction) error {
+	if tx.To != "" || tx.Asset != NativeAsset {
+		return fmt.Errorf("deployments have no recipient and send only native coins")
+	}
+	if p.ContractType == "" {
+		return fmt.Errorf("missing contract type")
+	}
+	return nil
+}
+
+// ContractCallPayload calls a contract at the recipient address with the envelope amount attached
+type ContractCallPayload struct {
+	CallData string // Encoded call, as accepted by ParseContractCall
+}
+
+func (p *ContractCallPayload) Type() TxType { return TxTypeContractCall }
+
+func (p *ContractCallPayload) MarshalBinary() ([]byte, error) {
+	e := &txEncoder{}
+	e.string(p.CallData)
+	return e.buf, nil
+}
+
+func (p *ContractCallPayload) Validate(tx *Transaction) error {
+	if !IsContractAddress(tx.To) || tx.Asset != NativeAsset {
+		return fmt.Errorf("calls go to a contract address and send only native coins")
+	}
+	call, err := ParseContractCall(p.CallData)
+	if err != nil {
+		return err
+	}
+	if call.Function == ContractDeployFunction {
+		return fmt.Errorf("use a contract-deploy transaction to deploy")
+	}
+	return nil
+}
+
+// StakePayload bonds the envelope amount to a validator. The coins stay with the sender, which
+// is also the recipient, so only the fee leaves the account.
+type StakePayload struct {
+	Validator string
+}
+
+func (p *StakePayload) Type() TxType { return TxTypeStake }
+
+func (p *StakePayload) MarshalBinary() ([]byte, error) {
+	e := &txEncoder{}
+	e.string(p.Validator)
+	return e.buf, nil
+}
+
+func (p *StakePayload) Validate(tx *Transaction) error {
+	if tx.To != tx.From || tx.Amount <= 0 || tx.Asset != NativeAsset {
+		return fmt.Errorf("stakes send a positive native amount back to the sender")
+	}
+	return requireAddress("validator", p.Validator)
+}
+
+// UnstakePayload releases an amount bonded to a validator
+type UnstakePayload struct {
+	Validator string
+	Amount    float64
+}
+
+func (p *UnstakePayload) Type() TxType { return TxTypeUnstake }
+
+func (p *UnstakePayload) MarshalBinary() ([]byte, error) {
+	e := &txEncoder{}
+	e.string(p.Validator)
+	e.amount(p.Amount)
+	return e.buf, nil
+}
+
+func (p *UnstakePayload) Validate(tx *Transaction) error {
+	if err := requireNoValue(tx); err != nil {
+		return err
+	}
+	if p.Amount <= 0 {
+		return fmt.Errorf("unstake amount must be greater than zero")
+	}
+	return requireAddress("validator", p.Validator)
+}
+
+// VotePayload gives a DPoS delegate a vote of some weight
+type VotePayload struct {
+	Delegate string
+	Weight   float64
+}
+
+func (p *VotePayload) Type() TxType { return TxTypeVote }
+
+func (p *VotePayload) MarshalBinary() ([]byte, error) {
+	e := &txEncoder{}
+	e.string(p.Delegate)
+	e.amount(p.Weight)
+	return e.buf, nil
+}
+
+func (p *VotePayload) Validate(tx *Transaction) error {
+	if err := requireNoValue(tx); err != nil {
+		return err
+	}
+	if p.Weight <= 0 {
+		return fmt.Errorf("vote weight must be greater than zero")
+	}
+	return requireAddress("delegate", p.Delegate)
+}
+
+// ChannelOpenPayload opens a payment channel: the envelope sends the sender's deposit to the
+// channel address, and the payload names the counterparty and its deposit
+type ChannelOpenPayload struct {
+	Counterparty        string
+	CounterpartyDeposit float64
+	TimeoutSeconds      int64
+}
+
+func (p *ChannelOpenPayload) Type() TxType { return TxTypeChannelOpen }
+
+func (p *ChannelOpenPayload) MarshalBinary() ([]byte, error) {
+	e := &txEncoder{}
+	e.string(p.Counterparty)
+	e.amount(p.CounterpartyDeposit)
+	e.int64(p.TimeoutSeconds)
+	return e.buf, nil
+}
+
+func (p *ChannelOpenPayload) Validate(tx *Transaction) error {
+	if tx.To == "" || tx.Amount <= 0 || tx.Asset != NativeAsset {
+		return fmt.Errorf("channel opens send a positive native deposit to the channel address")
+	}
+	if p.Counterparty == tx.From {
+		return fmt.Errorf("counterparty must differ from the sender")
+	}
+	if p.CounterpartyDeposit < 0 || p.TimeoutSeconds <= 0 {
+		return fmt.Errorf("counterparty deposit cannot be negative and timeout must be positive")
+	}
+	return requireAddress("counterparty", p.Counterparty)
+}
+
+// ChannelClosePayload submits the final state of a payment channel
+type ChannelClosePayload struct {
+	ChannelID          string
+	SequenceNumber     int64
+	Balance1, Balance2 float64
+}
+
+func (p *ChannelClosePayload) Type() TxType { return TxTypeChannelClose }
+
+func (p *ChannelClosePayload) MarshalBinary() ([]byte, error) {
+	e := &txEncoder{}
+	e.string(p.ChannelID)
+	e.int64(p.SequenceNumber)
+	e.amount(p.Balance1)
+	e.amount(p.Balance2)
+	return e.buf, nil
+}
+
+func (p *ChannelClosePayload) Validate(tx *Transaction) error {
+	if err := requireNoValue(tx); err != nil {
+		return err
+	}
+	if p.ChannelID == "" || p.SequenceNumber < 0 || p.Balance1 < 0 || p.Balance2 < 0 {
+		return fmt.Errorf("close needs a channel ID, a sequence number and non-negative balances")
+	}
+	return nil
+}
+
+// BridgeLockPayload locks the envelope amount in a bridge's escrow (the recipient) for release to
+// a recipient on another chain
+type BridgeLockPayload struct {
+	TargetChain string
+	Recipient   string
+}
+
+func (p *BridgeLockPayload) Type() TxType { return TxTypeBridgeLock }
+
+func (p *BridgeLockPayload) MarshalBinary() ([]byte, error) {
+	e := &txEncoder{}
+	e.string(p.TargetChain)
+	e.string(p.Recipient)
+	return e.buf, nil
+}
+
+func (p *BridgeLockPayload) Validate(tx *Transaction) error {
+	if tx.To == "" || tx.Amount <= 0 {
+		return fmt.Errorf("bridge locks send a positive amount to the bridge escrow")
+	}
+	if p.TargetChain == "" {
+		return fmt.Errorf("missing target chain")
+	}
+	return requireAddress("recipient", p.Recipient)
+}
@@ -0,0 +1,226 @@
+package main
+
+import (
+	"crypto/sha256"
+	"runtime"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// DefaultSignatureCacheSize is how many verified transaction signatures are remembered
+const DefaultSignatureCacheSize = 100000
+
+// signatureVerifier checks transaction signatures for every blockchain, mempool and peer in the
+// process. Validity does not depend on chain state, so one cache serves them all: a transaction
+// verified on entering the mempool is not verified again when its block is validated.
+var signatureVerifier = NewSignatureVerifier(0, DefaultSignatureCacheSize)
+
+// SignatureCache remembers the transactions whose signatures were found valid, evicting the
+// oldest entries first once full
+type SignatureCache struct {
+	entries  map[[32]byte]struct{}
+	order    [][32]byte // Ring buffer of keys in insertion order
+	next     int
+	capacity int
+	mu       sync.RWMutex
+}
+
+// NewSignatureCache creates a cache holding up to capacity verified signatures
+func NewSignatureCache(capacity int) *SignatureCache {
+	return &SignatureCache{
+		entries:  make(map[[32]byte]struct{}, capacity),
+		order:    make([][32]byte, 0, capacity),
+		capacity: capacity,
+	}
+}
+
+// Contains reports whether the transaction's signature was already verified
+func (c *SignatureCache) Contains(tx *Transaction) bool {
+	key := signatureCacheKey(tx)
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	_, exists := c.entries[key]
+	return exists
+}
+
+// Add records the transaction's signature as verified
+func (c *SignatureCache) Add(tx *Transaction) {
+	if c.capacity <= 0 {
+		return
+	}
+	key := signatureCacheKey(tx)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, exists := c.entries[key]; exists {
+		return
+	}
+	if len(c.order) < c.capacity {
+		c.order = append(c.order, key)
+	} else {
+		delete(c.entries, c.order[c.next])
+		c.order[c.next] = key
+		c.next = (c.next + 1) % c.capacity
+	}
+	c.entries[key] = struct{}{}
+}
+
+// Len returns the number of cached signatures
+func (c *SignatureCache) Len() int {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return len(c.entries)
+}
+
+// signatureCacheKey commits to everything a signature check reads: the transaction hash (which
+// covers the sender and scheme), the signatures and keys, and the multisig account if present
+func signatureCacheKey(tx *Transaction) [32]byte {
+	h := sha256.New()
+	h.Write(tx.Hash())
+	h.Write([]byte{0})
+	h.Write([]byte(tx.Signature))
+	h.Write([]byte{0})
+	h.Write([]byte(tx.PublicKey))
+	h.Write([]byte{0})
+	h.Write([]byte(strings.Join(tx.Signatures, ",")))
+	if tx.Multisig != nil {
+		h.Write([]byte{0})
+		h.Write([]byte(tx.Multisig.Address()))
+	}
+	var key [32]byte
+	copy(key[:], h.Sum(nil))
+	return key
+}
+
+// VerificationStats are running totals of a SignatureVerifier's work
+type VerificationStats struct {
+	Transactions int64         `json:"transactions"` // Transactions checked, including cache hits
+	Verified     int64         `json:"verified"`     // Signatures verified cryptographically
+	CacheHits    int64         `json:"cacheHits"`    // Transactions skipped because they were verified before
+	Failures     int64         `json:"failures"`     // Invalid signatures found
+	Elapsed      time.Duration `json:"elapsed"`      // Wall time spent verifying
+	Workers      int           `json:"workers"`
+	CacheSize    int           `json:"cacheSize"`
+}
+
+// Throughput returns the transactions checked per second of verification time
+func (s VerificationStats) Throughput() float64 {
+	if s.Elapsed <= 0 {
+		return 0
+	}
+	return float64(s.Transactions) / s.Elapsed.Seconds()
+}
+
+// SignatureVerifier checks transaction signatures on a pool of workers, skipping transactions
+// in its cache
+type SignatureVerifier struct {
+	Cache   *SignatureCache
+	workers int
+
+	transactions, verified, cacheHits, failures, elapsed atomic.Int64
+}
+
+// NewSignatureVerifier creates a verifier with the given number of workers (0 for one per CPU)
+// and cache capacity
+func NewSignatureVerifier(workers, cacheSize int) *SignatureVerifier {
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+	return &SignatureVerifier{Cache: NewSignatureCache(cacheSize), workers: workers}
+}
+
+// Verify checks one transaction's signature, using and filling the cache
+func (v *SignatureVerifier) Verify(tx *Transaction) error {
+	return v.VerifyTransactions([]*Transaction{tx})[0]
+}
+
+// VerifyTransactions checks the signatures of the transactions in parallel and returns one
+// error per transaction (nil if valid). Coinbases have no signature and are skipped.
+func (v *SignatureVerifier) VerifyTransactions(transactions []*Transaction) []error {
+	start := time.Now()
+	errs := make([]error, len(transactions))
+
+	// Each worker takes an equal share, so even a small block is spread over every worker
+	workers := v.workers
+	if workers > len(transactions) {
+		workers = len(transactions)
+	}
+	if workers <= 1 {
+		v.verifyChunk(transactions, errs, 0, len(transactions))
+	} else {
+		chunkSize := (len(transactions) + workers - 1) / workers
+		var wg sync.WaitGroup
+		for offset := 0; offset < len(transactions); offset += chunkSize {
+			end := offset + chunkSize
+			if end > len(transactions) {
+				end = len(transactions)
+			}
+			wg.Add(1)
+			go func(offset, end int) {
+				defer wg.Done()
+				v.verifyChunk(transactions, errs, offset, end)
+			}(offset, end)
+		}
+		wg.Wait()
+	}
+
+	v.elapsed.Add(int64(time.Since(start)))
+	return errs
+}
+
+// VerifyBlocks checks the signatures of every transaction in the blocks as one parallel job,
+// so chain validation does not wait on each block in turn. Valid signatures land in the cache,
+// where the per-block checks find them.
+func (v *SignatureVerifier) VerifyBlocks(blocks []*Block) []error {
+	var transactions []*Transaction
+	for _, block := range blocks {
+		transactions = append(transactions, block.Transactions...)
+	}
+	return v.VerifyTransactions(transactions)
+}
+
+// verifyChunk checks the transactions from offset up to end, writing their results into errs
+func (v *SignatureVerifier) verifyChunk(transactions []*Transaction, errs []error, offset, end int) {
+	for i := offset; i < end; i++ {
+		tx := transactions[i]
+		if tx.IsCoinbase() {
+			continue
+		}
+		v.transactions.Add(1)
+		if v.Cache.Contains(tx) {
+			v.cacheHits.Add(1)
+			continue
+		}
+		v.record(tx, tx.CheckSignature(), errs, i)
+	}
+}
+
+// record stores the result of a cryptographic check and caches valid signatures
+func (v *SignatureVerifier) record(tx *Transaction, err error, errs []error, i int) {
+	v.verified.Add(1)
+	errs[i] = err
+	if err != nil {
+		v.failures.Add(1)
+		return
+	}
+	v.Cache.Add(tx)
+}
+
+// Stats returns the verifier's running totals
+func (v *SignatureVerifier) Stats() VerificationStats {
+	return VerificationStats{
+		Transactions: v.transactions.Load(),
+		Verified:     v.verified.Load(),
+		CacheHits:    v.cacheHits.Load(),
+		Failures:     v.failures.Load(),
+		Elapsed:      time.Duration(v.elapsed.Load()),
+		Workers:      v.workers,
+		CacheSize:    v.Cache.Len(),
+	}
+}
+
+// SignatureVerificationStats returns the totals of the verifier shared by chains and mempools
+func SignatureVerificationStats() VerificationStats {
+	return signatureVerifier.Stats()
+}
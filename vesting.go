@@ -0,0 +1,195 @@
+package main
+
+import "fmt"
+
+// Clocks a vesting schedule can be measured in
+const (
+	VestingClockBlock = "block" // Schedule points are block heights
+	VestingClockTime  = "time"  // Schedule points are Unix timestamps
+)
+
+// EventVestingRevoked is emitted when the deployer revokes a vesting schedule
+const EventVestingRevoked = "VestingRevoked(address,uint256)"
+
+// executeVesting executes a vesting (time lock) contract.
+// Deposited coins vest to a beneficiary on a schedule: nothing before start+cliff, then linearly
+// until start+duration. A zero duration makes it a plain time lock releasing everything at start.
+func (sc *SmartContract) executeVesting(function string, ctx *ContractContext) (interface{}, error) {
+	if function == ContractConstructor {
+		return sc.initVesting(ctx)
+	}
+
+	beneficiary, initialized := sc.getStateString("beneficiary")
+	revoked, _ := sc.getStateBool("revoked")
+	if !initialized {
+		return nil, fmt.Errorf("vesting schedule not initialized")
+	}
+	released, _ := sc.getStateFloat("released")
+
+	switch function {
+	case "deposit":
+		if ctx.Value <= 0 {
+			return nil, fmt.Errorf("deposit value must be greater than zero")
+		}
+		if revoked {
+			return nil, fmt.Errorf("vesting schedule has been revoked")
+		}
+		total, _ := sc.getStateFloat("total")
+		sc.setState("total", total+ctx.Value)
+
+		sc.emitLog(ctx, EventDeposit, []string{ctx.Caller}, formatAmount(ctx.Value))
+		return fmt.Sprintf("Deposited %.2f coins for vesting", ctx.Value), nil
+
+	case "release":
+		vested, err := sc.vestedAmount(ctx)
+		if err != nil {
+			return nil, err
+		}
+		amount := vested - released
+		if amount <= 0 {
+			return nil, fmt.Errorf("no vested coins to release")
+		}
+		sc.setState("released", vested)
+
+		if err := ctx.Transfer(beneficiary, amount); err != nil {
+			return nil, err
+		}
+		sc.emitLog(ctx, EventRelease, []string{beneficiary}, formatAmount(amount))
+		return fmt.Sprintf("Released %.2f coins to %s", amount, truncateAddress(beneficiary)), nil
+
+	case "revoke":
+		if ctx.Caller != sc.Deployer {
+			return nil, fmt.Errorf("only deployer can revoke the vesting schedule")
+		}
+		if revocable, _ := sc.getStateBool("revocable"); !revocable {
+			return nil, fmt.Errorf("vesting schedule is not revocable")
+		}
+		if revoked {
+			return nil, fmt.Errorf("vesting schedule already revoked")
+		}
+		vested, err := sc.vestedAmount(ctx)
+		if err != nil {
+			return nil, err
+		}
+		total, _ := sc.getStateFloat("total")
+		refund := total - vested
+
+		// Freeze the schedule: what has vested stays claimable by the beneficiary
+		sc.mu.Lock()
+		sc.State["revoked"] = true
+		sc.State["total"] = vested
+		sc.mu.Unlock()
+
+		if refund > 0 {
+			if err := ctx.Transfer(sc.Deployer, refund); err != nil {
+				return nil, err
+			}
+		}
+		sc.emitLog(ctx, EventVestingRevoked, []string{beneficiary}, formatAmount(refund))
+		return fmt.Sprintf("Vesting revoked: %.2f unvested coins returned", refund), nil
+
+	case "vestedAmount":
+		return sc.vestedAmount(ctx)
+
+	case "releasable":
+		vested, err := sc.vestedAmount(ctx)
+		if err != nil {
+			return nil, err
+		}
+		return vested - released, nil
+
+	case "getSchedule":
+		sc.mu.RLock()
+		defer sc.mu.RUnlock()
+		return cloneState(sc.State), nil
+
+	default:
+		return nil, fmt.Errorf("unknown function: %s", function)
+	}
+}
+
+// initVesting sets the beneficiary and schedule; it runs once, at deployment, and vests any value sent
+func (sc *SmartContract) initVesting(ctx *ContractContext) (interface{}, error) {
+	if ctx.Caller != sc.Deployer {
+		return nil, fmt.Errorf("only deployer can initialize the vesting schedule")
+	}
+	if _, initialized := sc.getStateString("beneficiary"); initialized {
+		return nil, fmt.Errorf("vesting schedule already initialized")
+	}
+	if err := validateArgsCount(ctx.Args, 6, ContractConstructor); err != nil {
+		return nil, err
+	}
+
+	beneficiary, clock := ctx.StringArg(0), ctx.StringArg(1)
+	if clock != VestingClockBlock && clock != VestingClockTime {
+		return nil, fmt.Errorf("clock must be %q or %q", VestingClockBlock, VestingClockTime)
+	}
+	var schedule [3]uint64 // start, cliff, duration
+	for i := range schedule {
+		n, err := ctx.UintArg(2 + i)
+		if err != nil {
+			return nil, err
+		}
+		schedule[i] = n
+	}
+	if schedule[1] > schedule[2] {
+		return nil, fmt.Errorf("cliff cannot be longer than the vesting duration")
+	}
+	revocable, err := ctx.BoolArg(5)
+	if err != nil {
+		return nil, err
+	}
+
+	sc.mu.Lock()
+	sc.State["beneficiary"] = beneficiary
+	sc.State["clock"] = clock
+	sc.State["start"] = float64(schedule[0])
+	sc.State["cliff"] = float64(schedule[1])
+	sc.State["duration"] = float64(schedule[2])
+	sc.State["revocable"] = revocable
+	sc.State["revoked"] = false
+	sc.State["total"] = ctx.Value
+	sc.State["released"] = 0.0
+	sc.mu.Unlock()
+
+	if ctx.Value > 0 {
+		sc.emitLog(ctx, EventDeposit, []string{ctx.Caller}, formatAmount(ctx.Value))
+	}
+	return fmt.Sprintf("Vesting initialized for %s", truncateAddress(beneficiary)), nil
+}
+
+// vestedAmount returns how much of the deposited coins have vested at the context's block
+func (sc *SmartContract) vestedAmount(ctx *ContractContext) (float64, error) {
+	clock, _ := sc.getStateString("clock")
+	revoked, _ := sc.getStateBool("revoked")
+	total, _ := sc.getStateFloat("total")
+	if revoked {
+		return total, nil
+	}
+
+	var now float64
+	switch clock {
+	case VestingClockBlock:
+		if ctx.BlockNumber < 0 {
+			return 0, fmt.Errorf("block height is not available in this context")
+		}
+		now = float64(ctx.BlockNumber)
+	default:
+		if ctx.Timestamp == 0 {
+			return 0, fmt.Errorf("block time is not available in this context")
+		}
+		now = float64(ctx.Timestamp)
+	}
+
+	start, _ := sc.getStateFloat("start")
+	cliff, _ := sc.getStateFloat("cliff")
+	duration, _ := sc.getStateFloat("duration")
+	switch {
+	case now < start+cliff:
+		return 0, nil
+	case now >= start+duration:
+		return total, nil
+	default:
+		return total * (now - start) / duration, nil
+	}
+}
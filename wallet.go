@@ -11,33 +11,80 @@ import (
 
 // Wallet represents a wallet with public and private keys
 type Wallet struct {
-	PrivateKey *ecdsa.PrivateKey
-	PublicKey  *ecdsa.PublicKey
+	PrivateKey *ecdsa.PrivateKey // ECDSA key (nil for other schemes)
+	PublicKey  *ecdsa.PublicKey  // ECDSA public key (nil for other schemes)
 	Address    string
+	Signer     Signer // Signs with the wallet's key in its scheme
 }
 
-// NewWallet creates a new wallet with a key pair
+// NewWallet creates a new wallet with a secp256k1 key pair and an Ethereum-style address
 func NewWallet() (*Wallet, error) {
+	privateKey, err := GenerateSecp256k1Key()
+	if err != nil {
+		return nil, err
+	}
+	return newWalletFromKey(privateKey), nil
+}
+
+// NewLegacyWallet creates a new wallet with a P-256 key pair, which signs legacy transactions
+func NewLegacyWallet() (*Wallet, error) {
 	// Generate ECDSA key pair using P-256 curve
 	privateKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
 	if err != nil {
 		return nil, err
 	}
+	return newWalletFromKey(privateKey), nil
+}
 
+// NewWalletWithScheme creates a new wallet for any registered signature scheme
+func NewWalletWithScheme(scheme SignatureScheme) (*Wallet, error) {
+	info, err := GetSignatureScheme(scheme)
+	if err != nil {
+		return nil, err
+	}
+	if info.Generate == nil {
+		return nil, fmt.Errorf("signature scheme %s cannot generate keys", info.Name)
+	}
+	signer, err := info.Generate()
+	if err != nil {
+		return nil, err
+	}
+	return NewWalletFromSigner(signer), nil
+}
+
+// NewWalletFromSigner creates a wallet around an existing signer
+func NewWalletFromSigner(signer Signer) *Wallet {
+	if s, ok := signer.(*ecdsaSigner); ok {
+		return newWalletFromKey(s.key)
+	}
+	return &Wallet{
+		Address: signer.Address(),
+		Signer:  signer,
+	}
+}
+
+// newWalletFromKey creates a wallet for an existing private key
+func newWalletFromKey(privateKey *ecdsa.PrivateKey) *Wallet {
 	publicKey := &privateKey.PublicKey
 
-	// Generate address from public key (simplified: hash of public key)
+	// Generate address from public key
 	address := generateAddress(publicKey)
 
 	return &Wallet{
 		PrivateKey: privateKey,
 		PublicKey:  publicKey,
 		Address:    address,
-	}, nil
+		Signer:     NewECDSASigner(privateKey),
+	}
 }
 
-// generateAddress generates an address from a public key
+// generateAddress generates an address from a public key: Keccak-256 based for secp256k1,
+// SHA-256 based for legacy P-256 keys
 func generateAddress(publicKey *ecdsa.PublicKey) string {
+	if publicKey.Curve == secp256k1 {
+		return PublicKeyToEthAddress(publicKey)
+	}
+
 	// Encode public key as bytes
 	publicKeyBytes := append(
 		publicKey.X.Bytes(),
@@ -55,12 +102,36 @@ func generateAddress(publicKey *ecdsa.PublicKey) string {
 
 // SignTransaction signs a transaction with the wallet's private key
 func (w *Wallet) SignTransaction(tx *Transaction) error {
-	return tx.Sign(w.PrivateKey)
+	return tx.SignWith(w.Signer)
+}
+
+// MultisigKey returns the wallet's key for use in multisig accounts
+func (w *Wallet) MultisigKey() MultisigKey {
+	return MultisigKeyOf(w.Signer)
+}
+
+// SignMultisigTransaction adds the wallet's signature to a transaction from a multisig account it is a key of
+func (w *Wallet) SignMultisigTransaction(tx *Transaction) error {
+	return tx.SignMultisig(w.Signer)
+}
+
+// SignMessage signs an arbitrary message (a consensus vote, a channel state, ...) with the wallet's key
+func (w *Wallet) SignMessage(message []byte) (string, error) {
+	signature, err := SignMessage(w.Signer, message)
+	if err != nil {
+		return "", err
+	}
+	return signature.Encode(), nil
+}
+
+// Scheme returns the wallet's signature scheme
+func (w *Wallet) Scheme() SignatureScheme {
+	return w.Signer.Scheme()
 }
 
 // GetPublicKeyHex returns the public key as a hex string
 func (w *Wallet) GetPublicKeyHex() string {
-	return fmt.Sprintf("%x%x", w.PublicKey.X.Bytes(), w.PublicKey.Y.Bytes())
+	return fmt.Sprintf("%x", w.Signer.PublicKey())
 }
 
 // String returns a string representation of the wallet
@@ -0,0 +1,1326 @@
+package main
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math/bits"
+)
+
+// WebAssembly value types supported by the interpreter (integers only, for deterministic execution)
+const (
+	WasmI32 byte = 0x7f
+	WasmI64 byte = 0x7e
+)
+
+const (
+	wasmPageSize      = 65536 // Bytes per linear memory page
+	wasmMaxPages      = 16    // Sandbox limit on linear memory (1 MiB)
+	wasmMaxCallDepth  = 256   // Limit on nested wasm function calls
+	wasmMaxLocals     = 50000 // Limit on locals declared across all of a module's functions
+	wasmMemoryGrowGas = 1000  // Extra gas charged per page of memory.grow
+)
+
+// WasmFuncType is a function signature
+type WasmFuncType struct {
+	Params  []byte
+	Results []byte
+}
+
+// WasmImport is a function imported from the host
+type WasmImport struct {
+	Module string
+	Name   string
+	Type   uint32 // Index into Types
+}
+
+// WasmGlobal is a module-defined global variable
+type WasmGlobal struct {
+	Type    byte
+	Mutable bool
+	Init    uint64
+}
+
+// WasmDataSegment initializes a range of linear memory
+type WasmDataSegment struct {
+	Offset uint32
+	Data   []byte
+}
+
+// wasmInstr is a decoded instruction with its immediates and resolved jump targets
+type wasmInstr struct {
+	op    byte
+	imm   uint64   // Primary immediate (index, constant, label depth or memory offset)
+	table []uint32 // br_table targets (last entry is the default)
+	arity int      // Result count of a block, loop or if
+	param int      // Parameter count of a block, loop or if
+	end   int      // Index of the matching end for block, loop and if
+	els   int      // Index of the matching else for if (-1 if none)
+}
+
+// WasmFunction is a function defined inside the module
+type WasmFunction struct {
+	Type   uint32
+	Locals []byte
+	Body   []wasmInstr
+}
+
+// WasmModule is a decoded WebAssembly module
+type WasmModule struct {
+	Types     []WasmFuncType
+	Imports   []WasmImport
+	Functions []WasmFunction
+	Globals   []WasmGlobal
+	Exports   map[string]uint32 // Exported function name -> function index
+	MemoryMin uint32
+	MemoryMax uint32
+	HasMemory bool
+	Data      []WasmDataSegment
+}
+
+// wasmReader reads LEB128-encoded values from a byte slice
+type wasmReader struct {
+	data []byte
+	pos  int
+}
+
+func (r *wasmReader) eof() bool {
+	return r.pos >= len(r.data)
+}
+
+func (r *wasmReader) byte() (byte, error) {
+	if r.pos >= len(r.data) {
+		return 0, fmt.Errorf("unexpected end of wasm data")
+	}
+	b := r.data[r.pos]
+	r.pos++
+	return b, nil
+}
+
+func (r *wasmReader) bytes(n int) ([]byte, error) {
+	if n < 0 || r.pos+n > len(r.data) {
+		return nil, fmt.Errorf("unexpected end of wasm data")
+	}
+	b := r.data[r.pos : r.pos+n]
+	r.pos += n
+	return b, nil
+}
+
+func (r *wasmReader) uleb() (uint64, error) {
+	var result uint64
+	var shift uint
+	for {
+		b, err := r.byte()
+		if err != nil {
+			return 0, err
+		}
+		if shift >= 64 {
+			return 0, fmt.Errorf("invalid LEB128 encoding")
+		}
+		result |= uint64(b&0x7f) << shift
+		if b&0x80 == 0 {
+			return result, nil
+		}
+		shift += 7
+	}
+}
+
+func (r *wasmReader) u32() (uint32, error) {
+	v, err := r.uleb()
+	if err != nil {
+		return 0, err
+	}
+	if v > 0xffffffff {
+		return 0, fmt.Errorf("u32 out of range")
+	}
+	return uint32(v), nil
+}
+
+func (r *wasmReader) sleb(size uint) (int64, error) {
+	var result int64
+	var shift uint
+	var b byte
+	var err error
+	for {
+		b, err = r.byte()
+		if err != nil {
+			return 0, err
+		}
+		if shift >= 64 {
+			return 0, fmt.Errorf("invalid LEB128 encoding")
+		}
+		result |= int64(b&0x7f) << shift
+		shift += 7
+		if b&0x80 == 0 {
+			break
+		}
+	}
+	if shift < size && b&0x40 != 0 {
+		result |= -1 << shift
+	}
+	return result, nil
+}
+
+func (r *wasmReader) name() (string, error) {
+	n, err := r.u32()
+	if err != nil {
+		return "", err
+	}
+	b, err := r.bytes(int(n))
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
+
+// DecodeWasmModule parses a WebAssembly binary module
+func DecodeWasmModule(data []byte) (*WasmModule, error) {
+	if len(data) < 8 || string(data[:4]) != "\x00asm" {
+		return nil, fmt.Errorf("invalid wasm magic number")
+	}
+	if binary.LittleEndian.Uint32(data[4:8]) != 1 {
+		return nil, fmt.Errorf("unsupported wasm version")
+	}
+
+	module := &WasmModule{Exports: make(map[string]uint32)}
+	var funcTypes []uint32
+	r := &wasmReader{data: data, pos: 8}
+
+	for !r.eof() {
+		id, err := r.byte()
+		if err != nil {
+			return nil, err
+		}
+		size, err := r.u32()
+		if err != nil {
+			return nil, err
+		}
+		payload, err := r.bytes(int(size))
+		if err != nil {
+			return nil, err
+		}
+		sr := &wasmReader{data: payload}
+
+		switch id {
+		case 0: // custom section
+		case 1:
+			err = module.decodeTypes(sr)
+		case 2:
+			err = module.decodeImports(sr)
+		case 3:
+			funcTypes, err = decodeFunctionSection(sr)
+		case 5:
+			err = module.decodeMemory(sr)
+		case 6:
+			err = module.decodeGlobals(sr)
+		case 7:
+			err = module.decodeExports(sr)
+		case 10:
+			err = module.decodeCode(sr, funcTypes)
+		case 11:
+			err = module.decodeData(sr)
+		default:
+			err = fmt.Errorf("unsupported wasm section: %d", id)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if len(module.Functions) != len(funcTypes) {
+		return nil, fmt.Errorf("function and code section counts do not match")
+	}
+	for name, idx := range module.Exports {
+		if int(idx) >= len(module.Imports)+len(module.Functions) {
+			return nil, fmt.Errorf("export %s refers to unknown function", name)
+		}
+	}
+	return module, nil
+}
+
+func decodeValueType(r *wasmReader) (byte, error) {
+	t, err := r.byte()
+	if err != nil {
+		return 0, err
+	}
+	if t != WasmI32 && t != WasmI64 {
+		return 0, fmt.Errorf("unsupported value type 0x%x (only i32 and i64 are allowed)", t)
+	}
+	return t, nil
+}
+
+func (m *WasmModule) decodeTypes(r *wasmReader) error {
+	count, err := r.u32()
+	if err != nil {
+		return err
+	}
+	for i := uint32(0); i < count; i++ {
+		form, err := r.byte()
+		if err != nil {
+			return err
+		}
+		if form != 0x60 {
+			return fmt.Errorf("invalid function type form")
+		}
+		var ft WasmFuncType
+		for _, target := range []*[]byte{&ft.Params, &ft.Results} {
+			n, err := r.u32()
+			if err != nil {
+				return err
+			}
+			for j := uint32(0); j < n; j++ {
+				t, err := decodeValueType(r)
+				if err != nil {
+					return err
+				}
+				*target = append(*target, t)
+			}
+		}
+		m.Types = append(m.Types, ft)
+	}
+	return nil
+}
+
+func (m *WasmModule) decodeImports(r *wasmReader) error {
+	count, err := r.u32()
+	if err != nil {
+		return err
+	}
+	for i := uint32(0); i < count; i++ {
+		module, err := r.name()
+		if err != nil {
+			return err
+		}
+		name, err := r.name()
+		if err != nil {
+			return err
+		}
+		kind, err := r.byte()
+		if err != nil {
+			return err
+		}
+		if kind != 0 {
+			return fmt.Errorf("only function imports are supported (%s.%s)", module, name)
+		}
+		typeIdx, err := r.u32()
+		if err != nil {
+			return err
+		}
+		if int(typeIdx) >= len(m.Types) {
+			return fmt.Errorf("import %s.%s has unknown type", module, name)
+		}
+		m.Imports = append(m.Imports, WasmImport{Module: module, Name: name, Type: typeIdx})
+	}
+	return nil
+}
+
+func decodeFunctionSection(r *wasmReader) ([]uint32, error) {
+	count, err := r.u32()
+	if err != nil {
+		return nil, err
+	}
+	// Append while reading so a forged count cannot allocate more than the section holds
+	var types []uint32
+	for i := uint32(0); i < count; i++ {
+		typeIdx, err := r.u32()
+		if err != nil {
+			return nil, err
+		}
+		types = append(types, typeIdx)
+	}
+	return types, nil
+}
+
+func (m *WasmModule) decodeMemory(r *wasmReader) error {
+	count, err := r.u32()
+	if err != nil {
+		return err
+	}
+	if count > 1 {
+		return fmt.Errorf("only one memory is supported")
+	}
+	if count == 0 {
+		return nil
+	}
+	flags, err := r.byte()
+	if err != nil {
+		return err
+	}
+	if m.MemoryMin, err = r.u32(); err != nil {
+		return err
+	}
+	m.MemoryMax = wasmMaxPages
+	if flags&1 != 0 {
+		if m.MemoryMax, err = r.u32(); err != nil {
+			return err
+		}
+	}
+	if m.MemoryMin > wasmMaxPages {
+		return fmt.Errorf("memory exceeds sandbox limit of %d pages", wasmMaxPages)
+	}
+	if m.MemoryMax > wasmMaxPages {
+		m.MemoryMax = wasmMaxPages
+	}
+	m.HasMemory = true
+	return nil
+}
+
+// decodeConstExpr reads a constant initializer expression (i32.const or i64.const followed by end)
+func decodeConstExpr(r *wasmReader) (uint64, error) {
+	op, err := r.byte()
+	if err != nil {
+		return 0, err
+	}
+	var value uint64
+	switch op {
+	case 0x41:
+		v, err := r.sleb(32)
+		if err != nil {
+			return 0, err
+		}
+		value = uint64(uint32(v))
+	case 0x42:
+		v, err := r.sleb(64)
+		if err != nil {
+			return 0, err
+		}
+		value = uint64(v)
+	default:
+		return 0, fmt.Errorf("unsupported constant expression opcode 0x%x", op)
+	}
+	end, err := r.byte()
+	if err != nil {
+		return 0, err
+	}
+	if end != 0x0b {
+		return 0, fmt.Errorf("constant expression must end with end")
+	}
+	return value, nil
+}
+
+func (m *WasmModule) decodeGlobals(r *wasmReader) error {
+	count, err := r.u32()
+	if err != nil {
+		return err
+	}
+	for i := uint32(0); i < count; i++ {
+		t, err := decodeValueType(r)
+		if err != nil {
+			return err
+		}
+		mut, err := r.byte()
+		if err != nil {
+			return err
+		}
+		init, err := decodeConstExpr(r)
+		if err != nil {
+			return err
+		}
+		m.Globals = append(m.Globals, WasmGlobal{Type: t, Mutable: mut == 1, Init: init})
+	}
+	return nil
+}
+
+func (m *WasmModule) decodeExports(r *wasmReader) error {
+	count, err := r.u32()
+	if err != nil {
+		return err
+	}
+	for i := uint32(0); i < count; i++ {
+		name, err := r.name()
+		if err != nil {
+			return err
+		}
+		kind, err := r.byte()
+		if err != nil {
+			return err
+		}
+		idx, err := r.u32()
+		if err != nil {
+			return err
+		}
+		if kind == 0 { // Only function exports are callable; memory and global exports are ignored
+			m.Exports[name] = idx
+		}
+	}
+	return nil
+}
+
+func (m *WasmModule) decodeData(r *wasmReader) error {
+	count, err := r.u32()
+	if err != nil {
+		return err
+	}
+	for i := uint32(0); i < count; i++ {
+		flags, err := r.u32()
+		if err != nil {
+			return err
+		}
+		if flags != 0 {
+			return fmt.Errorf("only active data segments for memory 0 are supported")
+		}
+		offset, err := decodeConstExpr(r)
+		if err != nil {
+			return err
+		}
+		n, err := r.u32()
+		if err != nil {
+			return err
+		}
+		data, err := r.bytes(int(n))
+		if err != nil {
+			return err
+		}
+		m.Data = append(m.Data, WasmDataSegment{Offset: uint32(offset), Data: data})
+	}
+	return nil
+}
+
+func (m *WasmModule) decodeCode(r *wasmReader, funcTypes []uint32) error {
+	count, err := r.u32()
+	if err != nil {
+		return err
+	}
+	if int(count) != len(funcTypes) {
+		return fmt.Errorf("function and code section counts do not match")
+	}
+	locals := 0
+	for i := uint32(0); i < count; i++ {
+		size, err := r.u32()
+		if err != nil {
+			return err
+		}
+		body, err := r.bytes(int(size))
+		if err != nil {
+			return err
+		}
+		if int(funcTypes[i]) >= len(m.Types) {
+			return fmt.Errorf("function %d has unknown type", i)
+		}
+		br := &wasmReader{data: body}
+
+		fn := WasmFunction{Type: funcTypes[i]}
+		groups, err := br.u32()
+		if err != nil {
+			return err
+		}
+		for g := uint32(0); g < groups; g++ {
+			n, err := br.u32()
+			if err != nil {
+				return err
+			}
+			t, err := decodeValueType(br)
+			if err != nil {
+				return err
+			}
+			// Declarations are a few bytes each, so the limit is module-wide, not per function
+			if locals += int(n); locals > wasmMaxLocals {
+				return fmt.Errorf("too many locals")
+			}
+			for j := uint32(0); j < n; j++ {
+				fn.Locals = append(fn.Locals, t)
+			}
+		}
+
+		if fn.Body, err = m.decodeBody(br); err != nil {
+			return fmt.Errorf("function %d: %v", i, err)
+		}
+		m.Functions = append(m.Functions, fn)
+	}
+	return nil
+}
+
+// decodeBlockType returns the parameter and result counts of a block type
+func (m *WasmModule) decodeBlockType(r *wasmReader) (int, int, error) {
+	bt, err := r.sleb(33)
+	if err != nil {
+		return 0, 0, err
+	}
+	switch {
+	case bt == -64: // 0x40: empty
+		return 0, 0, nil
+	case bt == -1 || bt == -2: // 0x7f i32, 0x7e i64
+		return 0, 1, nil
+	case bt >= 0 && int(bt) < len(m.Types):
+		return len(m.Types[bt].Params), len(m.Types[bt].Results), nil
+	default:
+		return 0, 0, fmt.Errorf("unsupported block type")
+	}
+}
+
+// decodeBody decodes a function body and resolves block structure
+func (m *WasmModule) decodeBody(r *wasmReader) ([]wasmInstr, error) {
+	var body []wasmInstr
+	var blocks []int // Indexes of open block, loop and if instructions
+
+	for !r.eof() {
+		op, err := r.byte()
+		if err != nil {
+			return nil, err
+		}
+		in := wasmInstr{op: op, els: -1}
+
+		switch {
+		case op == 0x02 || op == 0x03 || op == 0x04: // block, loop, if
+			if in.param, in.arity, err = m.decodeBlockType(r); err != nil {
+				return nil, err
+			}
+			blocks = append(blocks, len(body))
+		case op == 0x05: // else
+			if len(blocks) == 0 || body[blocks[len(blocks)-1]].op != 0x04 {
+				return nil, fmt.Errorf("else without if")
+			}
+			body[blocks[len(blocks)-1]].els = len(body)
+		case op == 0x0b: // end
+			if len(blocks) > 0 {
+				open := blocks[len(blocks)-1]
+				blocks = blocks[:len(blocks)-1]
+				body[open].end = len(body)
+			} else if !r.eof() {
+				return nil, fmt.Errorf("unexpected end")
+			}
+		case op == 0x0c || op == 0x0d || op == 0x10 || (op >= 0x20 && op <= 0x24): // br, br_if, call, locals, globals
+			if in.imm, err = r.uleb(); err != nil {
+				return nil, err
+			}
+		case op == 0x0e: // br_table
+			n, err := r.u32()
+			if err != nil {
+				return nil, err
+			}
+			if n > 10000 {
+				return nil, fmt.Errorf("br_table too large")
+			}
+			for i := uint32(0); i <= n; i++ {
+				target, err := r.u32()
+				if err != nil {
+					return nil, err
+				}
+				in.table = append(in.table, target)
+			}
+		case op >= 0x28 && op <= 0x3e: // loads and stores
+			if op >= 0x2a && op <= 0x2b || op == 0x38 || op == 0x39 {
+				return nil, fmt.Errorf("floating point instructions are not supported")
+			}
+			if _, err := r.uleb(); err != nil { // alignment hint
+				return nil, err
+			}
+			offset, err := r.u32() // Offsets are u32 in a 32-bit memory
+			if err != nil {
+				return nil, fmt.Errorf("invalid memory offset: %v", err)
+			}
+			in.imm = uint64(offset)
+		case op == 0x3f || op == 0x40: // memory.size, memory.grow
+			if _, err := r.byte(); err != nil {
+				return nil, err
+			}
+		case op == 0x41:
+			v, err := r.sleb(32)
+			if err != nil {
+				return nil, err
+			}
+			in.imm = uint64(uint32(v))
+		case op == 0x42:
+			v, err := r.sleb(64)
+			if err != nil {
+				return nil, err
+			}
+			in.imm = uint64(v)
+		case op <= 0x01 || op == 0x0f || op == 0x1a || op == 0x1b:
+		case op >= 0x45 && op <= 0x5a:
+		case op >= 0x67 && op <= 0x8a:
+		case op == 0xa7 || op == 0xac || op == 0xad:
+		case op >= 0xc0 && op <= 0xc4:
+		default:
+			return nil, fmt.Errorf("unsupported opcode 0x%x", op)
+		}
+
+		body = append(body, in)
+	}
+
+	if len(blocks) != 0 || len(body) == 0 || body[len(body)-1].op != 0x0b {
+		return nil, fmt.Errorf("unterminated function body")
+	}
+	return body, nil
+}
+
+// WasmHostFunc implements an imported function; it returns the function's results
+type WasmHostFunc func(vm *WasmVM, args []uint64) ([]uint64, error)
+
+// WasmVM is a sandboxed instance of a module with its own memory, globals and gas meter
+type WasmVM struct {
+	Module   *WasmModule
+	Memory   []byte
+	Globals  []uint64
+	GasLimit uint64
+	GasUsed  uint64
+	host     []WasmHostFunc // Resolved imports, indexed like Module.Imports
+	stack    []uint64
+	depth    int
+}
+
+// wasmTrap is an execution error that aborts the whole VM
+type wasmTrap struct {
+	msg string
+}
+
+func (t *wasmTrap) Error() string {
+	return "wasm trap: " + t.msg
+}
+
+// NewWasmVM instantiates a module, resolving imports against the given host functions
+func NewWasmVM(module *WasmModule, hostFuncs map[string]WasmHostFunc, gasLimit uint64) (*WasmVM, error) {
+	vm := &WasmVM{
+		Module:   module,
+		GasLimit: gasLimit,
+		Globals:  make([]uint64, len(module.Globals)),
+	}
+
+	for _, imp := range module.Imports {
+		fn, exists := hostFuncs[imp.Module+"."+imp.Name]
+		if !exists {
+			return nil, fmt.Errorf("unresolved import %s.%s", imp.Module, imp.Name)
+		}
+		vm.host = append(vm.host, fn)
+	}
+
+	for i, g := range module.Globals {
+		vm.Globals[i] = g.Init
+	}
+
+	if module.HasMemory {
+		vm.Memory = make([]byte, int(module.MemoryMin)*wasmPageSize)
+	}
+	for _, seg := range module.Data {
+		end := uint64(seg.Offset) + uint64(len(seg.Data))
+		if end > uint64(len(vm.Memory)) {
+			return nil, fmt.Errorf("data segment out of bounds")
+		}
+		copy(vm.Memory[seg.Offset:], seg.Data)
+	}
+
+	return vm, nil
+}
+
+// Invoke calls an exported function by name
+func (vm *WasmVM) Invoke(name string, args ...uint64) ([]uint64, error) {
+	idx, exists := vm.Module.Exports[name]
+	if !exists {
+		return nil, fmt.Errorf("function not exported: %s", name)
+	}
+	ft := vm.funcType(idx)
+	if len(args) != len(ft.Params) {
+		return nil, fmt.Errorf("%s expects %d argument(s), got %d", name, len(ft.Params), len(args))
+	}
+	return vm.call(idx, args)
+}
+
+// ReadMemory copies a range of linear memory, trapping on out-of-bounds access
+func (vm *WasmVM) ReadMemory(ptr, length uint32) ([]byte, error) {
+	end := uint64(ptr) + uint64(length)
+	if end > uint64(len(vm.Memory)) {
+		return nil, &wasmTrap{"memory access out of bounds"}
+	}
+	out := make([]byte, length)
+	copy(out, vm.Memory[ptr:end])
+	return out, nil
+}
+
+// WriteMemory copies data into linear memory, trapping on out-of-bounds access
+func (vm *WasmVM) WriteMemory(ptr uint32, data []byte) error {
+	end := uint64(ptr) + uint64(len(data))
+	if end > uint64(len(vm.Memory)) {
+		return &wasmTrap{"memory access out of bounds"}
+	}
+	copy(vm.Memory[ptr:end], data)
+	return nil
+}
+
+// UseGas charges gas and traps when the limit is exceeded
+func (vm *WasmVM) UseGas(amount uint64) error {
+	vm.GasUsed += amount
+	if vm.GasUsed > vm.GasLimit {
+		vm.GasUsed = vm.GasLimit
+		return &wasmTrap{"out of gas"}
+	}
+	return nil
+}
+
+func (vm *WasmVM) funcType(idx uint32) *WasmFuncType {
+	if int(idx) < len(vm.Module.Imports) {
+		return &vm.Module.Types[vm.Module.Imports[idx].Type]
+	}
+	return &vm.Module.Types[vm.Module.Functions[int(idx)-len(vm.Module.Imports)].Type]
+}
+
+func (vm *WasmVM) push(v uint64) {
+	vm.stack = append(vm.stack, v)
+}
+
+func (vm *WasmVM) pop() uint64 {
+	v := vm.stack[len(vm.stack)-1]
+	vm.stack = vm.stack[:len(vm.stack)-1]
+	return v
+}
+
+// call invokes a function (host or module-defined) with arguments and returns its results
+func (vm *WasmVM) call(idx uint32, args []uint64) ([]uint64, error) {
+	if int(idx) >= len(vm.Module.Imports)+len(vm.Module.Functions) {
+		return nil, &wasmTrap{"call to unknown function"}
+	}
+	ft := vm.funcType(idx)
+
+	if int(idx) < len(vm.Module.Imports) {
+		results, err := vm.host[idx](vm, args)
+		if err != nil {
+			return nil, err
+		}
+		if len(results) != len(ft.Results) {
+			return nil, &wasmTrap{"host function returned wrong number of results"}
+		}
+		return results, nil
+	}
+
+	vm.depth++
+	defer func() { vm.depth-- }()
+	if vm.depth > wasmMaxCallDepth {
+		return nil, &wasmTrap{"call stack exhausted"}
+	}
+
+	fn := &vm.Module.Functions[int(idx)-len(vm.Module.Imports)]
+	locals := make([]uint64, len(args)+len(fn.Locals))
+	copy(locals, args)
+
+	base := len(vm.stack)
+	if err := vm.execute(fn, locals); err != nil {
+		return nil, err
+	}
+
+	n := len(ft.Results)
+	if len(vm.stack)-base < n {
+		return nil, &wasmTrap{"stack underflow on return"}
+	}
+	results := make([]uint64, n)
+	copy(results, vm.stack[len(vm.stack)-n:])
+	vm.stack = vm.stack[:base]
+	return results, nil
+}
+
+// wasmLabel is an entry on the control stack
+type wasmLabel struct {
+	height int  // Value stack height when the block was entered (excluding parameters)
+	arity  int  // Values carried by a branch to this label
+	target int  // Instruction index to continue at after a branch
+	loop   bool // Branches to loops jump back to the start
+}
+
+// execute runs a function body until it returns
+func (vm *WasmVM) execute(fn *WasmFunction, locals []uint64) error {
+	body := fn.Body
+	ft := &vm.Module.Types[fn.Type]
+	labels := []wasmLabel{{height: len(vm.stack), arity: len(ft.Results), target: len(body) - 1}}
+
+	// branch unwinds to the label at the given depth and returns the next instruction index
+	branch := func(depth int) (int, error) {
+		if depth >= len(labels) {
+			return 0, &wasmTrap{"invalid branch depth"}
+		}
+		label := labels[len(labels)-1-depth]
+		if len(vm.stack)-label.height < label.arity {
+			return 0, &wasmTrap{"stack underflow on branch"}
+		}
+		copy(vm.stack[label.height:], vm.stack[len(vm.stack)-label.arity:])
+		vm.stack = vm.stack[:label.height+label.arity]
+		if label.loop {
+			labels = labels[:len(labels)-depth]
+			return label.target, nil
+		}
+		labels = labels[:len(labels)-1-depth]
+		return label.target, nil
+	}
+
+	for pc := 0; pc < len(body); pc++ {
+		if err := vm.UseGas(1); err != nil {
+			return err
+		}
+		in := &body[pc]
+
+		// Check that enough operands are available for the instruction (and a block's parameters)
+		need := wasmOperandCount(in.op)
+		if in.op >= 0x02 && in.op <= 0x04 {
+			need += in.param
+		}
+		if len(vm.stack)-labels[len(labels)-1].height < need {
+			return &wasmTrap{fmt.Sprintf("stack underflow at opcode 0x%x", in.op)}
+		}
+
+		switch in.op {
+		case 0x00:
+			return &wasmTrap{"unreachable executed"}
+		case 0x01:
+		case 0x02: // block
+			labels = append(labels, wasmLabel{height: len(vm.stack) - in.param, arity: in.arity, target: in.end})
+		case 0x03: // loop
+			labels = append(labels, wasmLabel{height: len(vm.stack) - in.param, arity: in.param, target: pc, loop: true})
+		case 0x04: // if
+			cond := uint32(vm.pop())
+			labels = append(labels, wasmLabel{height: len(vm.stack) - in.param, arity: in.arity, target: in.end})
+			if cond == 0 {
+				if in.els >= 0 {
+					pc = in.els
+				} else {
+					pc = in.end - 1 // Let end pop the label
+				}
+			}
+		case 0x05: // else reached from the then-branch: skip to end
+			label := labels[len(labels)-1]
+			labels = labels[:len(labels)-1]
+			pc = label.target
+		case 0x0b: // end
+			if len(labels) == 1 {
+				return nil
+			}
+			labels = labels[:len(labels)-1]
+		case 0x0c: // br
+			next, err := branch(int(in.imm))
+			if err != nil {
+				return err
+			}
+			if len(labels) == 0 {
+				return nil
+			}
+			pc = next
+			if body[pc].op == 0x03 {
+				pc-- // Re-enter the loop instruction without pushing a second label
+				labels = labels[:len(labels)-1]
+			}
+		case 0x0d: // br_if
+			if uint32(vm.pop()) != 0 {
+				next, err := branch(int(in.imm))
+				if err != nil {
+					return err
+				}
+				if len(labels) == 0 {
+					return nil
+				}
+				pc = next
+				if body[pc].op == 0x03 {
+					pc--
+					labels = labels[:len(labels)-1]
+				}
+			}
+		case 0x0e: // br_table
+			i := uint32(vm.pop())
+			depth := in.table[len(in.table)-1]
+			if int(i) < len(in.table)-1 {
+				depth = in.table[i]
+			}
+			next, err := branch(int(depth))
+			if err != nil {
+				return err
+			}
+			if len(labels) == 0 {
+				return nil
+			}
+			pc = next
+			if body[pc].op == 0x03 {
+				pc--
+				labels = labels[:len(labels)-1]
+			}
+		case 0x0f: // return
+			n := len(ft.Results)
+			base := labels[0].height
+			if len(vm.stack)-n < base {
+				return &wasmTrap{"stack underflow on return"}
+			}
+			copy(vm.stack[base:], vm.stack[len(vm.stack)-n:])
+			vm.stack = vm.stack[:base+n]
+			return nil
+		case 0x10: // call
+			if in.imm >= uint64(len(vm.Module.Imports)+len(vm.Module.Functions)) {
+				return &wasmTrap{"call to unknown function"}
+			}
+			callee := vm.funcType(uint32(in.imm))
+			n := len(callee.Params)
+			if len(vm.stack) < n {
+				return &wasmTrap{"stack underflow on call"}
+			}
+			args := make([]uint64, n)
+			copy(args, vm.stack[len(vm.stack)-n:])
+			vm.stack = vm.stack[:len(vm.stack)-n]
+			results, err := vm.call(uint32(in.imm), args)
+			if err != nil {
+				return err
+			}
+			vm.stack = append(vm.stack, results...)
+		case 0x1a: // drop
+			vm.pop()
+		case 0x1b: // select
+			cond := uint32(vm.pop())
+			b := vm.pop()
+			a := vm.pop()
+			if cond != 0 {
+				vm.push(a)
+			} else {
+				vm.push(b)
+			}
+		case 0x20:
+			if in.imm >= uint64(len(locals)) {
+				return &wasmTrap{"invalid local index"}
+			}
+			vm.push(locals[in.imm])
+		case 0x21:
+			if in.imm >= uint64(len(locals)) {
+				return &wasmTrap{"invalid local index"}
+			}
+			locals[in.imm] = vm.pop()
+		case 0x22:
+			if in.imm >= uint64(len(locals)) {
+				return &wasmTrap{"invalid local index"}
+			}
+			locals[in.imm] = vm.stack[len(vm.stack)-1]
+		case 0x23:
+			if in.imm >= uint64(len(vm.Globals)) {
+				return &wasmTrap{"invalid global index"}
+			}
+			vm.push(vm.Globals[in.imm])
+		case 0x24:
+			if in.imm >= uint64(len(vm.Globals)) || !vm.Module.Globals[in.imm].Mutable {
+				return &wasmTrap{"invalid global write"}
+			}
+			vm.Globals[in.imm] = vm.pop()
+		case 0x3f: // memory.size
+			vm.push(uint64(len(vm.Memory) / wasmPageSize))
+		case 0x40: // memory.grow
+			delta := uint32(vm.pop())
+			pages := uint32(len(vm.Memory) / wasmPageSize)
+			if uint64(pages)+uint64(delta) > uint64(vm.Module.MemoryMax) || !vm.Module.HasMemory {
+				vm.push(uint64(0xffffffff))
+				break
+			}
+			if err := vm.UseGas(uint64(delta) * wasmMemoryGrowGas); err != nil {
+				return err
+			}
+			vm.Memory = append(vm.Memory, make([]byte, int(delta)*wasmPageSize)...)
+			vm.push(uint64(pages))
+		case 0x41, 0x42:
+			vm.push(in.imm)
+		default:
+			var err error
+			switch {
+			case in.op >= 0x28 && in.op <= 0x35:
+				err = vm.load(in.op, in.imm)
+			case in.op >= 0x36 && in.op <= 0x3e:
+				err = vm.store(in.op, in.imm)
+			default:
+				err = vm.numeric(in.op)
+			}
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// wasmOperandCount returns how many stack operands an instruction consumes
+func wasmOperandCount(op byte) int {
+	switch {
+	case op == 0x04 || op == 0x0d || op == 0x0e || op == 0x1a || op == 0x21 || op == 0x22 || op == 0x24 || op == 0x40:
+		return 1
+	case op == 0x1b:
+		return 3
+	case op >= 0x28 && op <= 0x35:
+		return 1
+	case op >= 0x36 && op <= 0x3e:
+		return 2
+	case op == 0x45 || op == 0x50:
+		return 1
+	case op >= 0x46 && op <= 0x5a:
+		return 2
+	case op >= 0x67 && op <= 0x69 || op >= 0x79 && op <= 0x7b:
+		return 1
+	case op >= 0x6a && op <= 0x78 || op >= 0x7c && op <= 0x8a:
+		return 2
+	case op == 0xa7 || op == 0xac || op == 0xad || op >= 0xc0 && op <= 0xc4:
+		return 1
+	}
+	return 0
+}
+
+// effectiveAddress computes a bounds-checked memory address. The comparison is arranged so
+// that offset and size cannot overflow it.
+func (vm *WasmVM) effectiveAddress(offset uint64, size int) (uint64, error) {
+	addr := uint64(uint32(vm.pop()))
+	memory := uint64(len(vm.Memory))
+	if offset > memory || uint64(size) > memory-offset || addr > memory-offset-uint64(size) {
+		return 0, &wasmTrap{"memory access out of bounds"}
+	}
+	return addr + offset, nil
+}
+
+func (vm *WasmVM) load(op byte, offset uint64) error {
+	sizes := map[byte]int{0x28: 4, 0x29: 8, 0x2c: 1, 0x2d: 1, 0x2e: 2, 0x2f: 2, 0x30: 1, 0x31: 1, 0x32: 2, 0x33: 2, 0x34: 4, 0x35: 4}
+	size := sizes[op]
+	addr, err := vm.effectiveAddress(offset, size)
+	if err != nil {
+		return err
+	}
+	mem := vm.Memory[addr:]
+	var v uint64
+	switch op {
+	case 0x28:
+		v = uint64(binary.LittleEndian.Uint32(mem))
+	case 0x29:
+		v = binary.LittleEndian.Uint64(mem)
+	case 0x2c:
+		v = uint64(uint32(int32(int8(mem[0]))))
+	case 0x2d:
+		v = uint64(mem[0])
+	case 0x2e:
+		v = uint64(uint32(int32(int16(binary.LittleEndian.Uint16(mem)))))
+	case 0x2f:
+		v = uint64(binary.LittleEndian.Uint16(mem))
+	case 0x30:
+		v = uint64(int64(int8(mem[0])))
+	case 0x31:
+		v = uint64(mem[0])
+	case 0x32:
+		v = uint64(int64(int16(binary.LittleEndian.Uint16(mem))))
+	case 0x33:
+		v = uint64(binary.LittleEndian.Uint16(mem))
+	case 0x34:
+		v = uint64(int64(int32(binary.LittleEndian.Uint32(mem))))
+	case 0x35:
+		v = uint64(binary.LittleEndian.Uint32(mem))
+	}
+	vm.push(v)
+	return nil
+}
+
+func (vm *WasmVM) store(op byte, offset uint64) error {
+	value := vm.pop()
+	sizes := map[byte]int{0x36: 4, 0x37: 8, 0x3a: 1, 0x3b: 2, 0x3c: 1, 0x3d: 2, 0x3e: 4}
+	size := sizes[op]
+	addr, err := vm.effectiveAddress(offset, size)
+	if err != nil {
+		return err
+	}
+	mem := vm.Memory[addr:]
+	switch size {
+	case 1:
+		mem[0] = byte(value)
+	case 2:
+		binary.LittleEndian.PutUint16(mem, uint16(value))
+	case 4:
+		binary.LittleEndian.PutUint32(mem, uint32(value))
+	case 8:
+		binary.LittleEndian.PutUint64(mem, value)
+	}
+	return nil
+}
+
+func boolToWasm(b bool) uint64 {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+// numeric executes comparison, arithmetic and conversion instructions
+func (vm *WasmVM) numeric(op byte) error {
+	switch {
+	case op == 0x45:
+		vm.push(boolToWasm(uint32(vm.pop()) == 0))
+	case op >= 0x46 && op <= 0x4f:
+		b, a := uint32(vm.pop()), uint32(vm.pop())
+		var r bool
+		switch op {
+		case 0x46:
+			r = a == b
+		case 0x47:
+			r = a != b
+		case 0x48:
+			r = int32(a) < int32(b)
+		case 0x49:
+			r = a < b
+		case 0x4a:
+			r = int32(a) > int32(b)
+		case 0x4b:
+			r = a > b
+		case 0x4c:
+			r = int32(a) <= int32(b)
+		case 0x4d:
+			r = a <= b
+		case 0x4e:
+			r = int32(a) >= int32(b)
+		case 0x4f:
+			r = a >= b
+		}
+		vm.push(boolToWasm(r))
+	case op == 0x50:
+		vm.push(boolToWasm(vm.pop() == 0))
+	case op >= 0x51 && op <= 0x5a:
+		b, a := vm.pop(), vm.pop()
+		var r bool
+		switch op {
+		case 0x51:
+			r = a == b
+		case 0x52:
+			r = a != b
+		case 0x53:
+			r = int64(a) < int64(b)
+		case 0x54:
+			r = a < b
+		case 0x55:
+			r = int64(a) > int64(b)
+		case 0x56:
+			r = a > b
+		case 0x57:
+			r = int64(a) <= int64(b)
+		case 0x58:
+			r = a <= b
+		case 0x59:
+			r = int64(a) >= int64(b)
+		case 0x5a:
+			r = a >= b
+		}
+		vm.push(boolToWasm(r))
+	case op >= 0x67 && op <= 0x69:
+		a := uint32(vm.pop())
+		switch op {
+		case 0x67:
+			vm.push(uint64(bits.LeadingZeros32(a)))
+		case 0x68:
+			vm.push(uint64(bits.TrailingZeros32(a)))
+		case 0x69:
+			vm.push(uint64(bits.OnesCount32(a)))
+		}
+	case op >= 0x6a && op <= 0x78:
+		b, a := uint32(vm.pop()), uint32(vm.pop())
+		var r uint32
+		switch op {
+		case 0x6a:
+			r = a + b
+		case 0x6b:
+			r = a - b
+		case 0x6c:
+			r = a * b
+		case 0x6d, 0x6e, 0x6f, 0x70:
+			if b == 0 {
+				return &wasmTrap{"integer divide by zero"}
+			}
+			switch op {
+			case 0x6d:
+				if int32(a) == -1<<31 && int32(b) == -1 {
+					return &wasmTrap{"integer overflow"}
+				}
+				r = uint32(int32(a) / int32(b))
+			case 0x6e:
+				r = a / b
+			case 0x6f:
+				if int32(b) == -1 {
+					r = 0
+				} else {
+					r = uint32(int32(a) % int32(b))
+				}
+			case 0x70:
+				r = a % b
+			}
+		case 0x71:
+			r = a & b
+		case 0x72:
+			r = a | b
+		case 0x73:
+			r = a ^ b
+		case 0x74:
+			r = a << (b % 32)
+		case 0x75:
+			r = uint32(int32(a) >> (b % 32))
+		case 0x76:
+			r = a >> (b % 32)
+		case 0x77:
+			r = bits.RotateLeft32(a, int(b%32))
+		case 0x78:
+			r = bits.RotateLeft32(a, -int(b%32))
+		}
+		vm.push(uint64(r))
+	case op >= 0x79 && op <= 0x7b:
+		a := vm.pop()
+		switch op {
+		case 0x79:
+			vm.push(uint64(bits.LeadingZeros64(a)))
+		case 0x7a:
+			vm.push(uint64(bits.TrailingZeros64(a)))
+		case 0x7b:
+			vm.push(uint64(bits.OnesCount64(a)))
+		}
+	case op >= 0x7c && op <= 0x8a:
+		b, a := vm.pop(), vm.pop()
+		var r uint64
+		switch op {
+		case 0x7c:
+			r = a + b
+		case 0x7d:
+			r = a - b
+		case 0x7e:
+			r = a * b
+		case 0x7f, 0x80, 0x81, 0x82:
+			if b == 0 {
+				return &wasmTrap{"integer divide by zero"}
+			}
+			switch op {
+			case 0x7f:
+				if int64(a) == -1<<63 && int64(b) == -1 {
+					return &wasmTrap{"integer overflow"}
+				}
+				r = uint64(int64(a) / int64(b))
+			case 0x80:
+				r = a / b
+			case 0x81:
+				if int64(b) == -1 {
+					r = 0
+				} else {
+					r = uint64(int64(a) % int64(b))
+				}
+			case 0x82:
+				r = a % b
+			}
+		case 0x83:
+			r = a & b
+		case 0x84:
+			r = a | b
+		case 0x85:
+			r = a ^ b
+		case 0x86:
+			r = a << (b % 64)
+		case 0x87:
+			r = uint64(int64(a) >> (b % 64))
+		case 0x88:
+			r = a >> (b % 64)
+		case 0x89:
+			r = bits.RotateLeft64(a, int(b%64))
+		case 0x8a:
+			r = bits.RotateLeft64(a, -int(b%64))
+		}
+		vm.push(r)
+	case op == 0xa7: // i32.wrap_i64
+		vm.push(uint64(uint32(vm.pop())))
+	case op == 0xac: // i64.extend_i32_s
+		vm.push(uint64(int64(int32(uint32(vm.pop())))))
+	case op == 0xad: // i64.extend_i32_u
+		vm.push(uint64(uint32(vm.pop())))
+	case op == 0xc0:
+		vm.push(uint64(uint32(int32(int8(vm.pop())))))
+	case op == 0xc1:
+		vm.push(uint64(uint32(int32(int16(vm.pop())))))
+	case op == 0xc2:
+		vm.push(uint64(int64(int8(vm.pop()))))
+	case op == 0xc3:
+		vm.push(uint64(int64(int16(vm.pop()))))
+	case op == 0xc4:
+		vm.push(uint64(int64(int32(vm.pop()))))
+	default:
+		return &wasmTrap{fmt.Sprintf("unsupported opcode 0x%x", op)}
+	}
+	return nil
+}
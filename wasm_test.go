@@ -0,0 +1,21 @@
+package main
+
+import "testing"
+
+// TestDecodeWasmModuleForgedCounts checks that counts read from a module cannot force
+// allocations beyond what the module's bytes hold
+func TestDecodeWasmModuleForgedCounts(t *testing.T) {
+	header := "\x00asm\x01\x00\x00\x00"
+	modules := map[string]string{
+		// Function section declaring 0xffffffff functions in a 5-byte payload
+		"function count": header + "\x03\x05\xff\xff\xff\xff\x0f",
+		// One type, then 3 functions of that type each declaring 50,000 locals
+		"locals": header + "\x01\x04\x01\x60\x00\x00" + "\x03\x04\x03\x00\x00\x00" +
+			"\x0a\x16\x03" + "\x06\x01\xd0\x86\x03\x7f\x0b" + "\x06\x01\xd0\x86\x03\x7f\x0b" + "\x06\x01\xd0\x86\x03\x7f\x0b",
+	}
+	for name, module := range modules {
+		if _, err := DecodeWasmModule([]byte(module)); err == nil {
+			t.Errorf("%s: forged module decoded without error", name)
+		}
+	}
+}
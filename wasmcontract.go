@@ -0,0 +1,293 @@
+package main
+
+import (
+	"encoding/hex"
+	"fmt"
+	"math"
+	"strings"
+)
+
+// DefaultWasmGasLimit is the gas available to a WASM contract call when the context sets no limit
+const DefaultWasmGasLimit uint64 = 1000000
+
+// WasmAmountScale converts native coin amounts to the integer units used by WASM host functions (1e-8 coins)
+const WasmAmountScale = 1e8
+
+// Gas charged for host functions on top of the per-instruction cost
+const (
+	wasmHostCallGas     = 10  // Base cost of every host call
+	wasmStorageReadGas  = 50  // Reading a storage slot
+	wasmStorageWriteGas = 200 // Writing or deleting a storage slot
+	wasmLogGas          = 100 // Emitting a log
+	wasmTransferGas     = 500 // Native balance transfer
+	wasmByteGas         = 1   // Per byte copied across the host boundary
+)
+
+// wasmStorageKey is the state key holding a WASM contract's key/value storage
+const wasmStorageKey = "storage"
+
+// wasmModule decodes the contract's hex bytecode, caching the result
+func (sc *SmartContract) wasmModule() (*WasmModule, error) {
+	sc.mu.RLock()
+	module := sc.module
+	sc.mu.RUnlock()
+	if module != nil {
+		return module, nil
+	}
+
+	code, err := hex.DecodeString(strings.TrimPrefix(sc.Bytecode, "0x"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid wasm bytecode: %v", err)
+	}
+	module, err = DecodeWasmModule(code)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkWasmImports(module); err != nil {
+		return nil, err
+	}
+
+	sc.mu.Lock()
+	sc.module = module
+	sc.mu.Unlock()
+	return module, nil
+}
+
+// executeWasm runs an exported function of a WASM module in a fresh sandboxed instance against the contract's storage.
+// Only the storage persists between calls; memory and globals are reset every time.
+func (sc *SmartContract) executeWasm(module *WasmModule, function string, ctx *ContractContext) (interface{}, error) {
+	gasLimit := ctx.GasLimit
+	if gasLimit == 0 {
+		gasLimit = DefaultWasmGasLimit
+	}
+
+	var returnData []byte
+	vm, err := NewWasmVM(module, sc.wasmHostFuncs(ctx, &returnData), gasLimit)
+	if err != nil {
+		return nil, err
+	}
+
+	results, err := vm.Invoke(function)
+	ctx.GasUsed += vm.GasUsed
+	if err != nil {
+		return nil, err
+	}
+
+	if returnData != nil {
+		return string(returnData), nil
+	}
+	if len(results) == 1 {
+		if ft := vm.funcType(module.Exports[function]); ft.Results[0] == WasmI32 {
+			return int64(int32(results[0])), nil
+		}
+		return int64(results[0]), nil
+	}
+	return nil, nil
+}
+
+// getStorage returns the key/value storage of a WASM contract
+func (sc *SmartContract) getStorage() map[string]string {
+	return sc.getStringMap(wasmStorageKey)
+}
+
+// wasmHostFuncs builds the "env" imports available to WASM contracts.
+// Buffers passed to the host are (pointer, length) pairs in linear memory; functions
+// that copy data out take a capacity and return the full length (or -1 if missing).
+func (sc *SmartContract) wasmHostFuncs(ctx *ContractContext, returnData *[]byte) map[string]WasmHostFunc {
+	readString := func(vm *WasmVM, ptr, length uint64) (string, error) {
+		if err := vm.UseGas(uint64(uint32(length)) * wasmByteGas); err != nil {
+			return "", err
+		}
+		data, err := vm.ReadMemory(uint32(ptr), uint32(length))
+		return string(data), err
+	}
+	// writeOut copies as much of data as fits in the buffer and returns the full length
+	writeOut := func(vm *WasmVM, data string, ptr, capacity uint64) ([]uint64, error) {
+		n := len(data)
+		if n > int(uint32(capacity)) {
+			n = int(uint32(capacity))
+		}
+		if err := vm.UseGas(uint64(n) * wasmByteGas); err != nil {
+			return nil, err
+		}
+		if err := vm.WriteMemory(uint32(ptr), []byte(data[:n])); err != nil {
+			return nil, err
+		}
+		return []uint64{uint64(uint32(len(data)))}, nil
+	}
+	// host wraps a host function with the base call cost
+	host := func(gas uint64, fn WasmHostFunc) WasmHostFunc {
+		return func(vm *WasmVM, args []uint64) ([]uint64, error) {
+			if err := vm.UseGas(wasmHostCallGas + gas); err != nil {
+				return nil, err
+			}
+			return fn(vm, args)
+		}
+	}
+	missing := []uint64{uint64(math.MaxUint32)} // -1 as i32
+
+	return map[string]WasmHostFunc{
+		// storage_read(keyPtr, keyLen, valPtr, valCap) -> valLen or -1
+		"env.storage_read": host(wasmStorageReadGas, func(vm *WasmVM, args []uint64) ([]uint64, error) {
+			key, err := readString(vm, args[0], args[1])
+			if err != nil {
+				return nil, err
+			}
+			value, exists := sc.getStorage()[key]
+			if !exists {
+				sc.traceRead(wasmStorageKey+"["+key+"]", nil)
+				return missing, nil
+			}
+			sc.traceRead(wasmStorageKey+"["+key+"]", value)
+			return writeOut(vm, value, args[2], args[3])
+		}),
+		// storage_write(keyPtr, keyLen, valPtr, valLen)
+		"env.storage_write": host(wasmStorageWriteGas, func(vm *WasmVM, args []uint64) ([]uint64, error) {
+			key, err := readString(vm, args[0], args[1])
+			if err != nil {
+				return nil, err
+			}
+			value, err := readString(vm, args[2], args[3])
+			if err != nil {
+				return nil, err
+			}
+			storage := sc.getStorage()
+			sc.mu.Lock()
+			storage[key] = value
+			sc.mu.Unlock()
+			return nil, nil
+		}),
+		// storage_delete(keyPtr, keyLen)
+		"env.storage_delete": host(wasmStorageWriteGas, func(vm *WasmVM, args []uint64) ([]uint64, error) {
+			key, err := readString(vm, args[0], args[1])
+			if err != nil {
+				return nil, err
+			}
+			storage := sc.getStorage()
+			sc.mu.Lock()
+			delete(storage, key)
+			sc.mu.Unlock()
+			return nil, nil
+		}),
+		// caller(ptr, cap) -> length of the caller address
+		"env.caller": host(0, func(vm *WasmVM, args []uint64) ([]uint64, error) {
+			return writeOut(vm, ctx.Caller, args[0], args[1])
+		}),
+		// self(ptr, cap) -> length of the contract's own address
+		"env.self": host(0, func(vm *WasmVM, args []uint64) ([]uint64, error) {
+			return writeOut(vm, sc.Address, args[0], args[1])
+		}),
+		// value() -> native value sent with the call, in 1e-8 coin units
+		"env.value": host(0, func(vm *WasmVM, args []uint64) ([]uint64, error) {
+			return []uint64{uint64(int64(math.Round(ctx.Value * WasmAmountScale)))}, nil
+		}),
+		// arg_count() -> number of call arguments
+		"env.arg_count": host(0, func(vm *WasmVM, args []uint64) ([]uint64, error) {
+			return []uint64{uint64(len(ctx.Args))}, nil
+		}),
+		// arg(index, ptr, cap) -> argument length or -1
+		"env.arg": host(0, func(vm *WasmVM, args []uint64) ([]uint64, error) {
+			index := int(uint32(args[0]))
+			if index >= len(ctx.Args) {
+				return missing, nil
+			}
+			return writeOut(vm, ctx.Args[index], args[1], args[2])
+		}),
+		// log(topicPtr, topicLen, dataPtr, dataLen) emits an event; the topic is its signature
+		"env.log": host(wasmLogGas, func(vm *WasmVM, args []uint64) ([]uint64, error) {
+			signature, err := readString(vm, args[0], args[1])
+			if err != nil {
+				return nil, err
+			}
+			data, err := readString(vm, args[2], args[3])
+			if err != nil {
+				return nil, err
+			}
+			sc.emitLog(ctx, signature, nil, data)
+			return nil, nil
+		}),
+		// transfer(toPtr, toLen, amount) -> 0 on success, 1 on failure
+		"env.transfer": host(wasmTransferGas, func(vm *WasmVM, args []uint64) ([]uint64, error) {
+			to, err := readString(vm, args[0], args[1])
+			if err != nil {
+				return nil, err
+			}
+			amount := float64(int64(args[2])) / WasmAmountScale
+			if err := ctx.Transfer(to, amount); err != nil {
+				return []uint64{1}, nil
+			}
+			return []uint64{0}, nil
+		}),
+		// balance(addrPtr, addrLen) -> native balance in 1e-8 coin units
+		"env.balance": host(0, func(vm *WasmVM, args []uint64) ([]uint64, error) {
+			address, err := readString(vm, args[0], args[1])
+			if err != nil {
+				return nil, err
+			}
+			return []uint64{uint64(int64(math.Round(ctx.BalanceOf(address) * WasmAmountScale)))}, nil
+		}),
+		// set_return(ptr, len) sets the call's result
+		"env.set_return": host(0, func(vm *WasmVM, args []uint64) ([]uint64, error) {
+			data, err := readString(vm, args[0], args[1])
+			if err != nil {
+				return nil, err
+			}
+			*returnData = []byte(data)
+			return nil, nil
+		}),
+		// revert(msgPtr, msgLen) aborts the call and undoes its changes
+		"env.revert": host(0, func(vm *WasmVM, args []uint64) ([]uint64, error) {
+			msg, err := readString(vm, args[0], args[1])
+			if err != nil {
+				return nil, err
+			}
+			return nil, fmt.Errorf("execution reverted: %s", msg)
+		}),
+	}
+}
+
+// wasmHostSignatures lists the type of every host function contracts may import
+var wasmHostSignatures = map[string]WasmFuncType{
+	"env.storage_read":   {Params: []byte{WasmI32, WasmI32, WasmI32, WasmI32}, Results: []byte{WasmI32}},
+	"env.storage_write":  {Params: []byte{WasmI32, WasmI32, WasmI32, WasmI32}},
+	"env.storage_delete": {Params: []byte{WasmI32, WasmI32}},
+	"env.caller":         {Params: []byte{WasmI32, WasmI32}, Results: []byte{WasmI32}},
+	"env.self":           {Params: []byte{WasmI32, WasmI32}, Results: []byte{WasmI32}},
+	"env.value":          {Results: []byte{WasmI64}},
+	"env.arg_count":      {Results: []byte{WasmI32}},
+	"env.arg":            {Params: []byte{WasmI32, WasmI32, WasmI32}, Results: []byte{WasmI32}},
+	"env.log":            {Params: []byte{WasmI32, WasmI32, WasmI32, WasmI32}},
+	"env.transfer":       {Params: []byte{WasmI32, WasmI32, WasmI64}, Results: []byte{WasmI32}},
+	"env.balance":        {Params: []byte{WasmI32, WasmI32}, Results: []byte{WasmI64}},
+	"env.set_return":     {Params: []byte{WasmI32, WasmI32}},
+	"env.revert":         {Params: []byte{WasmI32, WasmI32}},
+}
+
+// checkWasmImports verifies that a module only imports known host functions with matching types
+func checkWasmImports(module *WasmModule) error {
+	for _, imp := range module.Imports {
+		name := imp.Module + "." + imp.Name
+		expected, exists := wasmHostSignatures[name]
+		if !exists {
+			return fmt.Errorf("unknown host function %s", name)
+		}
+		actual := module.Types[imp.Type]
+		if string(actual.Params) != string(expected.Params) || string(actual.Results) != string(expected.Results) {
+			return fmt.Errorf("host function %s imported with wrong signature", name)
+		}
+	}
+	return nil
+}
+
+// validateWasmBytecode checks that bytecode decodes and only imports known host functions
+func validateWasmBytecode(bytecode string) error {
+	code, err := hex.DecodeString(strings.TrimPrefix(bytecode, "0x"))
+	if err != nil {
+		return fmt.Errorf("invalid wasm bytecode: %v", err)
+	}
+	module, err := DecodeWasmModule(code)
+	if err != nil {
+		return err
+	}
+	return checkWasmImports(module)
+}
@@ -4,9 +4,13 @@ import (
 	"encoding/hex"
 	"encoding/json"
 	"fmt"
+	"math"
+	"math/big"
 	"net/http"
 	"strconv"
+	"strings"
 	"sync"
+	"time"
 )
 
 // Web3Server represents a Web3 JSON-RPC server
@@ -17,6 +21,15 @@ type Web3Server struct {
 	server     *http.Server
 	mu         sync.RWMutex
 	running    bool
+	filters    map[string]*logSubscription // Installed log filters by ID
+	nextFilter int
+	keystore   *Keystore // Accounts served by eth_accounts and personal_* (nil if none)
+}
+
+// logSubscription tracks a filter installed with eth_newFilter and how far it has been polled
+type logSubscription struct {
+	filter    *LogFilter
+	lastBlock int // Last block already returned by eth_getFilterChanges
 }
 
 // JSONRPCRequest represents a JSON-RPC request
@@ -48,9 +61,17 @@ func NewWeb3Server(blockchain *Blockchain, address string, port int) *Web3Server
 		address:    address,
 		port:       port,
 		running:    false,
+		filters:    make(map[string]*logSubscription),
 	}
 }
 
+// SetKeystore serves the accounts of a keystore and signs eth_sendTransaction with its unlocked accounts
+func (w *Web3Server) SetKeystore(keystore *Keystore) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.keystore = keystore
+}
+
 // Start starts the Web3 server
 func (w *Web3Server) Start() error {
 	w.mu.Lock()
@@ -129,8 +150,32 @@ func (w *Web3Server) handleRequest(rw http.ResponseWriter, r *http.Request) {
 		result, err = w.sendTransaction(req.Params)
 	case "eth_call":
 		result, err = w.call(req.Params)
+	case "contract_getABI":
+		result, err = w.getContractABI(req.Params)
 	case "eth_getCode":
 		result, err = w.getCode(req.Params)
+	case "eth_getTransactionReceipt":
+		result, err = w.getTransactionReceipt(req.Params)
+	case "eth_getLogs":
+		result, err = w.getLogs(req.Params)
+	case "eth_newFilter":
+		result, err = w.newFilter(req.Params)
+	case "eth_getFilterChanges":
+		result, err = w.getFilterChanges(req.Params)
+	case "eth_uninstallFilter":
+		result, err = w.uninstallFilter(req.Params)
+	case "debug_traceTransaction":
+		result, err = w.traceTransaction(req.Params)
+	case "debug_verificationStats":
+		result = verificationStats()
+	case "eth_accounts":
+		result, err = w.accounts()
+	case "personal_newAccount":
+		result, err = w.newAccount(req.Params)
+	case "personal_unlockAccount":
+		result, err = w.unlockAccount(req.Params)
+	case "personal_lockAccount":
+		result, err = w.lockAccount(req.Params)
 	default:
 		w.sendError(rw, -32601, "Method not found", req.ID)
 		return
@@ -187,19 +232,13 @@ func (w *Web3Server) getBalance(params []interface{}) (string, error) {
 		return "", fmt.Errorf("missing address parameter")
 	}
 
-	address, ok := params[0].(string)
-	if !ok {
-		return "", fmt.Errorf("invalid address parameter")
-	}
-
-	// Remove 0x prefix if present
-	if len(address) > 2 && address[:2] == "0x" {
-		address = address[2:]
-	}
-
 	w.mu.RLock()
 	defer w.mu.RUnlock()
 
+	address, err := w.parseAddressParam(params[0])
+	if err != nil {
+		return "", err
+	}
 	balance := w.blockchain.GetBalance(address)
 
 	// Convert to Wei (1 coin = 1e18 Wei for compatibility)
@@ -245,14 +284,19 @@ func (w *Web3Server) getBlockByNumber(params []interface{}) (interface{}, error)
 	block := w.blockchain.Blocks[blockNum]
 
 	// Format block for Web3 response
-	return map[string]interface{}{
+	result := map[string]interface{}{
 		"number":           fmt.Sprintf("0x%x", block.Index),
 		"hash":             "0x" + block.Hash,
 		"parentHash":       "0x" + block.PreviousHash,
 		"timestamp":        fmt.Sprintf("0x%x", block.Timestamp.Unix()),
 		"transactions":     formatTransactions(block.Transactions),
 		"transactionsRoot": "0x" + block.MerkleRoot,
-	}, nil
+		"logsBloom":        "0x" + block.LogsBloom,
+	}
+	if block.QuorumCert != nil {
+		result["quorumCertificate"] = block.QuorumCert
+	}
+	return result, nil
 }
 
 // getTransactionCount returns the number of transactions sent from an address
@@ -261,19 +305,13 @@ func (w *Web3Server) getTransactionCount(params []interface{}) (string, error) {
 		return "", fmt.Errorf("missing address parameter")
 	}
 
-	address, ok := params[0].(string)
-	if !ok {
-		return "", fmt.Errorf("invalid address parameter")
-	}
-
-	// Remove 0x prefix if present
-	if len(address) > 2 && address[:2] == "0x" {
-		address = address[2:]
-	}
-
 	w.mu.RLock()
 	defer w.mu.RUnlock()
 
+	address, err := w.parseAddressParam(params[0])
+	if err != nil {
+		return "", err
+	}
 	count := 0
 	for _, block := range w.blockchain.Blocks {
 		for _, tx := range block.Transactions {
@@ -298,25 +336,38 @@ func (w *Web3Server) sendTransaction(params []interface{}) (string, error) {
 	}
 
 	// Extract transaction fields
-	from, _ := txData["from"].(string)
-	to, _ := txData["to"].(string)
-	valueStr, _ := txData["value"].(string)
-
-	// Parse value (hex)
-	if len(valueStr) > 2 && valueStr[:2] == "0x" {
-		valueStr = valueStr[2:]
+	w.mu.RLock()
+	from, err := w.parseAddressParam(txData["from"])
+	if err != nil {
+		w.mu.RUnlock()
+		return "", fmt.Errorf("invalid from address: %v", err)
 	}
-	value, err := strconv.ParseInt(valueStr, 16, 64)
+	to, err := w.parseAddressParam(txData["to"])
+	w.mu.RUnlock()
 	if err != nil {
-		return "", fmt.Errorf("invalid value format")
+		return "", fmt.Errorf("invalid to address: %v", err)
 	}
+	valueStr, _ := txData["value"].(string)
 
-	// Convert from Wei to coins (1e18 Wei = 1 coin)
-	amount := float64(value) / 1e18
+	// Parse value (hex Wei)
+	amount, err := weiToCoins(valueStr)
+	if err != nil {
+		return "", err
+	}
 
 	// Create transaction
 	tx := NewTransaction(from, to, amount)
 
+	// Sign with the sender's account if it is unlocked in the keystore
+	w.mu.RLock()
+	keystore := w.keystore
+	w.mu.RUnlock()
+	if keystore != nil && keystore.HasAccount(from) {
+		if err := keystore.SignTransaction(tx); err != nil {
+			return "", err
+		}
+	}
+
 	w.mu.Lock()
 	err = w.blockchain.AddTransactionToMempool(tx)
 	w.mu.Unlock()
@@ -330,7 +381,119 @@ func (w *Web3Server) sendTransaction(params []interface{}) (string, error) {
 	return "0x" + txHash, nil
 }
 
-// call executes a contract call (read-only)
+// accounts returns the addresses in the server's keystore
+func (w *Web3Server) accounts() ([]string, error) {
+	keystore, err := w.getKeystore()
+	if err != nil {
+		return []string{}, nil
+	}
+	return keystore.Accounts()
+}
+
+// newAccount creates an account in the keystore encrypted with the given passphrase
+func (w *Web3Server) newAccount(params []interface{}) (string, error) {
+	keystore, err := w.getKeystore()
+	if err != nil {
+		return "", err
+	}
+	if len(params) < 1 {
+		return "", fmt.Errorf("missing passphrase parameter")
+	}
+	passphrase, ok := params[0].(string)
+	if !ok {
+		return "", fmt.Errorf("invalid passphrase parameter")
+	}
+	wallet, err := keystore.NewAccount(passphrase)
+	if err != nil {
+		return "", err
+	}
+	return wallet.Address, nil
+}
+
+// unlockAccount decrypts a keystore account so eth_sendTransaction can sign with it for the
+// given number of seconds (default 300, 0 for until it is locked)
+func (w *Web3Server) unlockAccount(params []interface{}) (bool, error) {
+	keystore, err := w.getKeystore()
+	if err != nil {
+		return false, err
+	}
+	if len(params) < 2 {
+		return false, fmt.Errorf("missing address or passphrase parameter")
+	}
+	passphrase, ok := params[1].(string)
+	if !ok {
+		return false, fmt.Errorf("invalid passphrase parameter")
+	}
+	duration := DefaultUnlockDuration
+	if len(params) > 2 && params[2] != nil {
+		seconds, ok := params[2].(float64)
+		if !ok || seconds < 0 || seconds != math.Trunc(seconds) || seconds > math.MaxInt64/float64(time.Second) {
+			return false, fmt.Errorf("invalid duration parameter")
+		}
+		duration = time.Duration(seconds) * time.Second
+	}
+	w.mu.RLock()
+	address, err := w.parseAddressParam(params[0])
+	w.mu.RUnlock()
+	if err != nil {
+		return false, err
+	}
+	if _, err := keystore.TimedUnlock(address, passphrase, duration); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// lockAccount removes an unlocked keystore account from memory
+func (w *Web3Server) lockAccount(params []interface{}) (bool, error) {
+	keystore, err := w.getKeystore()
+	if err != nil {
+		return false, err
+	}
+	if len(params) < 1 {
+		return false, fmt.Errorf("missing address parameter")
+	}
+	w.mu.RLock()
+	address, err := w.parseAddressParam(params[0])
+	w.mu.RUnlock()
+	if err != nil {
+		return false, err
+	}
+	keystore.Lock(address)
+	return true, nil
+}
+
+// parseAddressParam reads an address parameter, encoded (with its checksum checked) or in hex.
+// A "0x" prefix that does not belong to a deployed contract is taken as the Ethereum-style hex
+// prefix of an account address. Callers hold w.mu.
+func (w *Web3Server) parseAddressParam(param interface{}) (string, error) {
+	input, ok := param.(string)
+	if !ok {
+		return "", fmt.Errorf("invalid address parameter")
+	}
+	address, err := ParseAddress(input)
+	if err != nil {
+		return "", err
+	}
+	if IsContractAddress(address) && !IsEncodedAddress(input) {
+		if _, err := w.blockchain.GetContract(address); err != nil {
+			return address[2:], nil
+		}
+	}
+	return address, nil
+}
+
+// getKeystore returns the server's keystore
+func (w *Web3Server) getKeystore() (*Keystore, error) {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	if w.keystore == nil {
+		return nil, fmt.Errorf("no keystore configured")
+	}
+	return w.keystore, nil
+}
+
+// call simulates a contract call against the state at a block without modifying it
 func (w *Web3Server) call(params []interface{}) (string, error) {
 	if len(params) < 1 {
 		return "", fmt.Errorf("missing call parameter")
@@ -341,38 +504,106 @@ func (w *Web3Server) call(params []interface{}) (string, error) {
 		return "", fmt.Errorf("invalid call parameter")
 	}
 
-	to, _ := callData["to"].(string)
-	_ = callData["data"] // Contract data (not used in simplified implementation)
+	if callData["to"] == nil {
+		return "", fmt.Errorf("missing call target")
+	}
+	data, _ := callData["data"].(string)
 
-	// Remove 0x prefix
-	if len(to) > 2 && to[:2] == "0x" {
-		to = to[2:]
+	// Data is the hex-encoded contract call (JSON or "function:args")
+	raw, err := hex.DecodeString(strings.TrimPrefix(data, "0x"))
+	if err != nil {
+		return "", fmt.Errorf("invalid call data: %v", err)
 	}
+	call, err := ParseContractCall(string(raw))
+	if err != nil {
+		return "", err
+	}
+
+	// Optional value sent with the call, in Wei (1e18 Wei = 1 coin)
+	if valueStr, ok := callData["value"].(string); ok && valueStr != "" {
+		if call.Value, err = weiToCoins(valueStr); err != nil {
+			return "", err
+		}
+	}
+
+	w.mu.RLock()
+	defer w.mu.RUnlock()
 
-	// This is a simplified implementation
-	// In a real implementation, you would decode the data and execute the contract
-	return "0x", nil
+	to, err := w.parseAddressParam(callData["to"])
+	if err != nil {
+		return "", fmt.Errorf("invalid call target: %v", err)
+	}
+	call.ContractAddress = to
+	from := ""
+	if callData["from"] != nil {
+		if from, err = w.parseAddressParam(callData["from"]); err != nil {
+			return "", fmt.Errorf("invalid from address: %v", err)
+		}
+	}
+
+	// Optional block tag selects the state to execute against (default: latest)
+	blockNumber := -1
+	if len(params) > 1 {
+		tag, ok := params[1].(string)
+		if !ok {
+			return "", fmt.Errorf("invalid block parameter")
+		}
+		if blockNumber, err = w.parseBlockTag(tag); err != nil {
+			return "", err
+		}
+	}
+
+	simulation, err := w.blockchain.SimulateCall(call, from, blockNumber)
+	if err != nil {
+		return "", err
+	}
+
+	// Results are returned as hex-encoded JSON, typed by the contract's ABI when it has one
+	var encoded []byte
+	if abi, err := w.blockchain.ContractRegistry.GetABI(to); err == nil {
+		fn, err := abi.Function(call.Function)
+		if err != nil {
+			return "", err
+		}
+		if encoded, err = fn.EncodeResult(simulation.Result); err != nil {
+			return "", err
+		}
+	} else if encoded, err = json.Marshal(simulation.Result); err != nil {
+		return "", fmt.Errorf("failed to encode result: %v", err)
+	}
+	return "0x" + hex.EncodeToString(encoded), nil
 }
 
-// getCode returns the code at a given address (for contracts)
-func (w *Web3Server) getCode(params []interface{}) (string, error) {
+// getContractABI returns the ABI of a deployed contract
+func (w *Web3Server) getContractABI(params []interface{}) (interface{}, error) {
 	if len(params) < 1 {
-		return "", fmt.Errorf("missing address parameter")
+		return nil, fmt.Errorf("missing address parameter")
 	}
 
-	address, ok := params[0].(string)
-	if !ok {
-		return "", fmt.Errorf("invalid address parameter")
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+
+	address, err := w.parseAddressParam(params[0])
+	if err != nil {
+		return nil, err
 	}
+	return w.blockchain.ContractRegistry.GetABI(address)
+}
 
-	// Remove 0x prefix
-	if len(address) > 2 && address[:2] == "0x" {
-		address = address[2:]
+// getCode returns the code at a given address (for contracts)
+func (w *Web3Server) getCode(params []interface{}) (string, error) {
+	if len(params) < 1 {
+		return "", fmt.Errorf("missing address parameter")
 	}
 
 	w.mu.RLock()
 	defer w.mu.RUnlock()
 
+	address, err := w.parseAddressParam(params[0])
+	if err != nil {
+		return "", err
+	}
+
 	// Check if address is a contract
 	if IsContractAddress(address) {
 		contract, err := w.blockchain.GetContract(address)
@@ -385,16 +616,267 @@ func (w *Web3Server) getCode(params []interface{}) (string, error) {
 	return "0x", nil // No code (regular address)
 }
 
+// getTransactionReceipt returns the receipt of a contract transaction
+func (w *Web3Server) getTransactionReceipt(params []interface{}) (interface{}, error) {
+	if len(params) < 1 {
+		return nil, fmt.Errorf("missing transaction hash parameter")
+	}
+
+	txHash, ok := params[0].(string)
+	if !ok {
+		return nil, fmt.Errorf("invalid transaction hash parameter")
+	}
+
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+
+	receipt, err := w.blockchain.GetReceipt(txHash)
+	if err != nil {
+		return nil, nil // Unknown transaction, return null
+	}
+	return receipt, nil
+}
+
+// traceTransaction replays a contract transaction and returns its execution trace
+func (w *Web3Server) traceTransaction(params []interface{}) (interface{}, error) {
+	if len(params) < 1 {
+		return nil, fmt.Errorf("missing transaction hash parameter")
+	}
+
+	txHash, ok := params[0].(string)
+	if !ok {
+		return nil, fmt.Errorf("invalid transaction hash parameter")
+	}
+
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+
+	return w.blockchain.TraceTransaction(txHash)
+}
+
+// verificationStats returns the signature verifier's totals and throughput
+func verificationStats() map[string]interface{} {
+	stats := SignatureVerificationStats()
+	return map[string]interface{}{
+		"transactions":       stats.Transactions,
+		"verified":           stats.Verified,
+		"cacheHits":          stats.CacheHits,
+		"failures":           stats.Failures,
+		"elapsedMs":          stats.Elapsed.Milliseconds(),
+		"transactionsPerSec": stats.Throughput(),
+		"workers":            stats.Workers,
+		"cacheSize":          stats.CacheSize,
+	}
+}
+
+// getLogs returns all logs matching a filter object
+func (w *Web3Server) getLogs(params []interface{}) (interface{}, error) {
+	if len(params) < 1 {
+		return nil, fmt.Errorf("missing filter parameter")
+	}
+
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+
+	filter, err := w.parseLogFilter(params[0])
+	if err != nil {
+		return nil, err
+	}
+	return w.blockchain.GetLogs(filter), nil
+}
+
+// newFilter installs a log filter and returns its ID for polling
+func (w *Web3Server) newFilter(params []interface{}) (string, error) {
+	if len(params) < 1 {
+		return "", fmt.Errorf("missing filter parameter")
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	filter, err := w.parseLogFilter(params[0])
+	if err != nil {
+		return "", err
+	}
+
+	w.nextFilter++
+	id := fmt.Sprintf("0x%x", w.nextFilter)
+	w.filters[id] = &logSubscription{
+		filter:    filter,
+		lastBlock: filter.FromBlock - 1,
+	}
+	return id, nil
+}
+
+// getFilterChanges returns logs matching an installed filter since the last poll
+func (w *Web3Server) getFilterChanges(params []interface{}) (interface{}, error) {
+	if len(params) < 1 {
+		return nil, fmt.Errorf("missing filter ID parameter")
+	}
+
+	id, ok := params[0].(string)
+	if !ok {
+		return nil, fmt.Errorf("invalid filter ID parameter")
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	sub, exists := w.filters[id]
+	if !exists {
+		return nil, fmt.Errorf("filter not found")
+	}
+
+	latest := len(w.blockchain.Blocks) - 1
+	toBlock := latest
+	if sub.filter.ToBlock >= 0 && sub.filter.ToBlock < latest {
+		toBlock = sub.filter.ToBlock
+	}
+	if sub.lastBlock >= toBlock {
+		return []*Log{}, nil
+	}
+
+	window := *sub.filter
+	window.FromBlock = sub.lastBlock + 1
+	window.ToBlock = toBlock
+	sub.lastBlock = toBlock
+
+	return w.blockchain.GetLogs(&window), nil
+}
+
+// uninstallFilter removes an installed filter
+func (w *Web3Server) uninstallFilter(params []interface{}) (bool, error) {
+	if len(params) < 1 {
+		return false, fmt.Errorf("missing filter ID parameter")
+	}
+
+	id, ok := params[0].(string)
+	if !ok {
+		return false, fmt.Errorf("invalid filter ID parameter")
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if _, exists := w.filters[id]; !exists {
+		return false, nil
+	}
+	delete(w.filters, id)
+	return true, nil
+}
+
+// parseLogFilter converts a JSON-RPC filter object into a LogFilter
+func (w *Web3Server) parseLogFilter(param interface{}) (*LogFilter, error) {
+	filterData, ok := param.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("invalid filter parameter")
+	}
+
+	filter := &LogFilter{FromBlock: len(w.blockchain.Blocks) - 1, ToBlock: -1}
+
+	if fromBlock, ok := filterData["fromBlock"].(string); ok {
+		num, err := w.parseBlockTag(fromBlock)
+		if err != nil {
+			return nil, err
+		}
+		filter.FromBlock = num
+	}
+	if toBlock, ok := filterData["toBlock"].(string); ok && toBlock != "latest" {
+		num, err := w.parseBlockTag(toBlock)
+		if err != nil {
+			return nil, err
+		}
+		filter.ToBlock = num
+	}
+
+	switch address := filterData["address"].(type) {
+	case string:
+		parsed, err := w.parseAddressParam(address)
+		if err != nil {
+			return nil, err
+		}
+		filter.Addresses = []string{parsed}
+	case []interface{}:
+		for _, a := range address {
+			parsed, err := w.parseAddressParam(a)
+			if err != nil {
+				return nil, err
+			}
+			filter.Addresses = append(filter.Addresses, parsed)
+		}
+	}
+
+	if topics, ok := filterData["topics"].([]interface{}); ok {
+		for _, topic := range topics {
+			switch t := topic.(type) {
+			case string:
+				filter.Topics = append(filter.Topics, []string{t})
+			case []interface{}:
+				alternatives := make([]string, 0, len(t))
+				for _, alt := range t {
+					if str, ok := alt.(string); ok {
+						alternatives = append(alternatives, str)
+					}
+				}
+				filter.Topics = append(filter.Topics, alternatives)
+			default:
+				filter.Topics = append(filter.Topics, nil) // null matches any topic
+			}
+		}
+	}
+
+	return filter, nil
+}
+
+// parseBlockTag parses "latest", "earliest" or a hex block number
+func (w *Web3Server) parseBlockTag(tag string) (int, error) {
+	switch tag {
+	case "latest", "pending":
+		return len(w.blockchain.Blocks) - 1, nil
+	case "earliest":
+		return 0, nil
+	}
+	if len(tag) > 2 && tag[:2] == "0x" {
+		tag = tag[2:]
+	}
+	num, err := strconv.ParseInt(tag, 16, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid block number format")
+	}
+	return int(num), nil
+}
+
 // formatTransactions formats transactions for Web3 response
 func formatTransactions(transactions []*Transaction) []map[string]interface{} {
 	result := make([]map[string]interface{}, len(transactions))
 	for i, tx := range transactions {
 		result[i] = map[string]interface{}{
-			"from":  tx.From,
-			"to":    tx.To,
-			"value": fmt.Sprintf("0x%x", int64(tx.Amount*1e18)),
-			"hash":  "0x" + hex.EncodeToString(tx.Hash()),
+			"from":   tx.From,
+			"to":     tx.To,
+			"value":  fmt.Sprintf("0x%x", int64(tx.Amount*1e18)),
+			"hash":   "0x" + hex.EncodeToString(tx.Hash()),
+			"scheme": tx.Scheme.String(),
+			"type":   tx.Type.String(),
+		}
+		if tx.Type != TxTypeLegacy {
+			result[i]["version"] = tx.Version
+			result[i]["payload"] = "0x" + hex.EncodeToString(tx.Payload)
+		}
+		if tx.Multisig != nil {
+			result[i]["scheme"] = "multisig"
+			result[i]["threshold"] = tx.Multisig.Threshold
+			result[i]["signatures"] = tx.SignatureCount()
 		}
 	}
 	return result
 }
+
+// weiToCoins parses a hex Wei amount of any size and converts it to coins (1e18 Wei = 1 coin)
+func weiToCoins(valueStr string) (float64, error) {
+	wei, ok := new(big.Int).SetString(strings.TrimPrefix(valueStr, "0x"), 16)
+	if !ok || wei.Sign() < 0 {
+		return 0, fmt.Errorf("invalid value format")
+	}
+	coins, _ := new(big.Float).Quo(new(big.Float).SetInt(wei), big.NewFloat(1e18)).Float64()
+	return coins, nil
+}